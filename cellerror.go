@@ -0,0 +1,65 @@
+package grate
+
+// CellError is the value of a cell containing an error, such as the
+// result of a formula which divides by zero.
+type CellError struct {
+	Code byte   // the BIFF8 error code, e.g. 0x07
+	Text string // the displayed text, e.g. "#DIV/0!"
+}
+
+// Error codes used by CellError.
+const (
+	ErrCodeNull        byte = 0x00
+	ErrCodeDiv0        byte = 0x07
+	ErrCodeValue       byte = 0x0F
+	ErrCodeRef         byte = 0x17
+	ErrCodeName        byte = 0x1D
+	ErrCodeNum         byte = 0x24
+	ErrCodeNA          byte = 0x2A
+	ErrCodeGettingData byte = 0x2B
+
+	// ErrCodeUnknown is used for unrecognized error values.
+	ErrCodeUnknown byte = 0xFF
+)
+
+var cellErrorText = map[byte]string{
+	ErrCodeNull:        "#NULL!",
+	ErrCodeDiv0:        "#DIV/0!",
+	ErrCodeValue:       "#VALUE!",
+	ErrCodeRef:         "#REF!",
+	ErrCodeName:        "#NAME?",
+	ErrCodeNum:         "#NUM!",
+	ErrCodeNA:          "#N/A",
+	ErrCodeGettingData: "#GETTING_DATA",
+}
+
+// NewCellError returns the CellError for a BIFF8 error code.
+func NewCellError(code byte) CellError {
+	text, ok := cellErrorText[code]
+	if !ok {
+		return CellError{Code: ErrCodeUnknown, Text: "<unknown error>"}
+	}
+	return CellError{Code: code, Text: text}
+}
+
+// ParseCellError returns the CellError for its displayed text, e.g. "#N/A".
+// Unrecognized text is kept with the ErrCodeUnknown code.
+func ParseCellError(text string) CellError {
+	for code, t := range cellErrorText {
+		if t == text {
+			return CellError{Code: code, Text: text}
+		}
+	}
+	return CellError{Code: ErrCodeUnknown, Text: text}
+}
+
+// String returns the displayed text of the error.
+func (e CellError) String() string {
+	return e.Text
+}
+
+// Error implements the error interface, so that the value can be
+// returned by Scan.
+func (e CellError) Error() string {
+	return "grate: cell contains error " + e.Text
+}
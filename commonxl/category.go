@@ -0,0 +1,150 @@
+package commonxl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NumberCategory is the semantic category of a numeric format, beyond the
+// integer or float type of its values.
+type NumberCategory uint8
+
+// NumberCategory values for numeric formats.
+const (
+	NoCategory NumberCategory = iota // not a numeric format
+	GeneralNumber
+	PlainNumber
+	PercentNumber
+	CurrencyNumber
+	AccountingNumber
+	ScientificNumber
+	FractionNumber
+)
+
+// String returns a description of the number category.
+func (c NumberCategory) String() string {
+	switch c {
+	case GeneralNumber:
+		return "general"
+	case PlainNumber:
+		return "number"
+	case PercentNumber:
+		return "percent"
+	case CurrencyNumber:
+		return "currency"
+	case AccountingNumber:
+		return "accounting"
+	case ScientificNumber:
+		return "scientific"
+	case FractionNumber:
+		return "fraction"
+	}
+	return ""
+}
+
+// NumberClass describes what a number format says about its values.
+type NumberClass struct {
+	Category NumberCategory
+
+	// Currency is the currency symbol or ISO code shown by currency
+	// and accounting formats (e.g. "$", "€" or "EUR"), if any.
+	Currency string
+}
+
+// String returns the category, followed by the currency if there is
+// one, e.g. "percent" or "currency:€".
+func (c NumberClass) String() string {
+	if c.Currency == "" {
+		return c.Category.String()
+	}
+	return c.Category.String() + ":" + c.Currency
+}
+
+// isoCurrencies are the common ISO 4217 codes recognized as literal
+// text in format codes, e.g. "EUR "#,##0.00.
+var isoCurrencies = map[string]bool{
+	"AUD": true, "BRL": true, "CAD": true, "CHF": true, "CNY": true,
+	"CZK": true, "DKK": true, "EUR": true, "GBP": true, "HKD": true,
+	"HUF": true, "IDR": true, "ILS": true, "INR": true, "JPY": true,
+	"KRW": true, "MXN": true, "NOK": true, "NZD": true, "PLN": true,
+	"RUB": true, "SEK": true, "SGD": true, "THB": true, "TRY": true,
+	"TWD": true, "USD": true, "ZAR": true,
+}
+
+// classify returns the semantic category of the format, from its
+// first numeric section.
+func (nf *numFmt) classify() NumberClass {
+	var sec *fmtSection
+	for _, s := range nf.sections {
+		if s.kind == sectionNumber {
+			sec = s
+			break
+		}
+	}
+	if nf.textOnly || sec == nil {
+		return NumberClass{}
+	}
+
+	cur := nf.currency
+	if cur == "" {
+		cur = sec.literalCurrency()
+	}
+	switch {
+	case sec.hasExp:
+		return NumberClass{Category: ScientificNumber}
+	case sec.hasFraction:
+		return NumberClass{Category: FractionNumber}
+	case sec.percent:
+		return NumberClass{Category: PercentNumber}
+	case sec.fill:
+		return NumberClass{Category: AccountingNumber, Currency: cur}
+	case cur != "":
+		return NumberClass{Category: CurrencyNumber, Currency: cur}
+	case sec.general && len(sec.toks) == 1:
+		return NumberClass{Category: GeneralNumber}
+	}
+	return NumberClass{Category: PlainNumber}
+}
+
+// literalCurrency returns the first currency symbol or ISO code in the
+// literal text of the section.
+func (sec *fmtSection) literalCurrency() string {
+	for _, t := range sec.toks {
+		if t.kind != tokLiteral {
+			continue
+		}
+		for _, r := range t.lit {
+			if unicode.Is(unicode.Sc, r) {
+				return string(r)
+			}
+		}
+		for _, w := range strings.FieldsFunc(t.lit, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			if isoCurrencies[w] {
+				return w
+			}
+		}
+	}
+	return ""
+}
+
+// builtInClasses are the number categories of the built-in formats.
+var builtInClasses = func() map[uint16]NumberClass {
+	res := make(map[uint16]NumberClass, len(builtInFormats))
+	for fmtID, code := range builtInFormats {
+		res[fmtID] = parseNumFmt(code).classify()
+	}
+	return res
+}()
+
+// Class returns the semantic category of the number format.
+func (x *Formatter) Class(fmtID uint16) NumberClass {
+	if c, ok := builtInClasses[fmtID]; ok {
+		return c
+	}
+	if x != nil {
+		if c, ok := x.customCodeClasses[fmtID]; ok {
+			return c
+		}
+	}
+	return NumberClass{Category: GeneralNumber}
+}
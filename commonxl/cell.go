@@ -4,9 +4,6 @@ import (
 	"fmt"
 	"math"
 	"net/url"
-	"strconv"
-	"time"
-	"unicode/utf16"
 )
 
 // CellType annotates the type of data extracted in the cell.
@@ -23,6 +20,9 @@ const (
 
 	HyperlinkStringCell // internal type to separate URLs
 	StaticCell          // placeholder, internal use only
+
+	ErrorCell    // a grate.CellError value
+	DurationCell // a time.Duration, for elapsed time formats such as [h]:mm
 )
 
 // String returns a string description of the cell data type.
@@ -38,6 +38,10 @@ func (c CellType) String() string {
 		return "boolean"
 	case DateCell:
 		return "date"
+	case ErrorCell:
+		return "error"
+	case DurationCell:
+		return "duration"
 	case HyperlinkStringCell:
 		return "hyperlink"
 	case StaticCell:
@@ -50,9 +54,10 @@ func (c CellType) String() string {
 // Cell represents a single cell value.
 type Cell []interface{}
 
-// internally, it is a slice sized 2 or 3
+// internally, it is a slice sized 2 to 5
 //   [Value, CellType] or [Value, CellType, FormatNumber]
-// where FormatNumber is a uint16 if not 0
+//   or [Value, CellType, FormatNumber, Hyperlink, XF]
+// where FormatNumber and XF are uint16 and Hyperlink is a *Hyperlink
 
 // Value returns the contents as a generic interface{}.
 func (c Cell) Value() interface{} {
@@ -62,25 +67,81 @@ func (c Cell) Value() interface{} {
 	return c[0]
 }
 
+// grow extends the cell to hold at least n slots, filling
+// the optional slots with their zero values.
+func (c *Cell) grow(n int) {
+	if len(*c) < 2 {
+		*c = Cell{nil, BlankCell}
+	}
+	for len(*c) < n {
+		switch len(*c) {
+		case 2:
+			*c = append(*c, uint16(0))
+		case 3:
+			*c = append(*c, (*Hyperlink)(nil))
+		default:
+			*c = append(*c, uint16(0))
+		}
+	}
+}
+
 // SetURL adds a URL hyperlink to the cell.
 func (c *Cell) SetURL(link string) {
-	(*c)[1] = HyperlinkStringCell
-	if len(*c) == 2 {
-		*c = append(*c, uint16(0), link)
-	} else { // len = 3 already
-		*c = append(*c, link)
+	c.SetHyperlink(&Hyperlink{URL: link})
+}
+
+// SetHyperlink adds a hyperlink to the cell. Blank and string cells
+// become hyperlink cells, other values keep their type.
+func (c *Cell) SetHyperlink(h *Hyperlink) {
+	c.grow(4)
+	switch c.Type() {
+	case BlankCell:
+		if h.Display != "" {
+			(*c)[0] = h.Display
+		} else {
+			(*c)[0] = h.Target()
+		}
+		(*c)[1] = HyperlinkStringCell
+	case StringCell:
+		(*c)[1] = HyperlinkStringCell
+	}
+	(*c)[3] = h
+}
+
+// Hyperlink returns the hyperlink of the cell, or nil if there is none.
+func (c Cell) Hyperlink() *Hyperlink {
+	if len(c) >= 4 {
+		return c[3].(*Hyperlink)
 	}
+	return nil
 }
 
 // URL returns the parsed URL when a cell contains a hyperlink.
 func (c Cell) URL() (*url.URL, bool) {
-	if c.Type() == HyperlinkStringCell && len(c) >= 4 {
-		u, err := url.Parse(c[3].(string))
+	if h := c.Hyperlink(); h != nil && h.URL != "" {
+		u, err := url.Parse(h.URL)
 		return u, err == nil
 	}
 	return nil, false
 }
 
+// SetXF records the index of the XF (extended format) record styling the cell.
+func (c *Cell) SetXF(xf uint16) {
+	if xf == 0 && len(*c) < 5 {
+		return
+	}
+	c.grow(5)
+	(*c)[4] = xf
+}
+
+// XF returns the index of the XF record styling the cell.
+func (c Cell) XF() uint16 {
+	if len(c) == 5 {
+		return c[4].(uint16)
+	}
+	return 0
+}
+
 // Type returns the CellType of the value.
 func (c Cell) Type() CellType {
 	if len(c) < 2 {
@@ -91,7 +152,7 @@ func (c Cell) Type() CellType {
 
 // FormatNo returns the NumberFormat used for display.
 func (c Cell) FormatNo() uint16 {
-	if len(c) == 3 {
+	if len(c) >= 3 {
 		return c[2].(uint16)
 	}
 	return 0
@@ -117,203 +178,25 @@ var boolStrings = map[string]bool{
 
 // NewCellWithType creates a new cell value with the given type, coercing as necessary.
 func NewCellWithType(value interface{}, t CellType, f *Formatter) Cell {
-	c := NewCell(value)
-	if c[1] == t {
-		// fast path if it was already typed correctly
-		return c
-	}
-
-	if c[1] == BooleanCell {
-		if t == IntegerCell {
-			if c[0].(bool) {
-				c[0] = int64(1)
-			} else {
-				c[0] = int64(0)
-			}
-			c[1] = IntegerCell
-		} else if t == FloatCell {
-			if c[0].(bool) {
-				c[0] = float64(1.0)
-			} else {
-				c[0] = float64(0.0)
-			}
-			c[1] = FloatCell
-		} else if t == StringCell {
-			if c[0].(bool) {
-				c[0] = "TRUE"
-			} else {
-				c[0] = "FALSE"
-			}
-			c[1] = FloatCell
-		}
-	}
-
-	if c[1] == FloatCell {
-		if t == IntegerCell {
-			c[0] = int64(c[0].(float64))
-			c[1] = IntegerCell
-		} else if t == BooleanCell {
-			c[0] = c[0].(float64) != 0.0
-			c[1] = BooleanCell
-		}
-	}
-	if c[1] == IntegerCell {
-		if t == FloatCell {
-			c[0] = float64(c[0].(int64))
-			c[1] = FloatCell
-		} else if t == BooleanCell {
-			c[0] = c[0].(int64) != 0
-			c[1] = BooleanCell
-		}
-	}
-	if c[1] == StringCell {
-		if t == IntegerCell {
-			x, _ := strconv.ParseInt(c[0].(string), 10, 64)
-			c[0] = x
-			c[1] = IntegerCell
-		} else if t == FloatCell {
-			x, _ := strconv.ParseFloat(c[0].(string), 64)
-			c[0] = x
-			c[1] = FloatCell
-		} else if t == BooleanCell {
-			c[0] = boolStrings[c[0].(string)]
-			c[1] = BooleanCell
-		}
-	}
-	if t == StringCell {
-		c[0] = fmt.Sprint(c[0])
-		c[1] = StringCell
-	}
-	if t == DateCell {
-		if c[1] == FloatCell {
-			c[0] = f.ConvertToDate(c[0].(float64))
-		} else if c[1] == IntegerCell {
-			c[0] = f.ConvertToDate(float64(c[0].(int64)))
-		}
-		c[1] = DateCell
-	}
-	return c
+	var tab strTable
+	return packValue(&tab, value).coerce(&tab, t, f).cell(tab)
 }
 
 // NewCell creates a new cell value from any builtin type.
 func NewCell(value interface{}) Cell {
-	c := make([]interface{}, 2)
-	switch v := value.(type) {
-	case bool:
-		c[0] = v
-		c[1] = BooleanCell
-	case int:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case int8:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case int16:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case int32:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case int64:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case uint8:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case uint16:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-	case uint32:
-		c[0] = int64(v)
-		c[1] = IntegerCell
-
-	case uint:
-		if int64(v) > int64(math.MaxInt64) {
-			c[0] = float64(v)
-			c[1] = FloatCell
-		} else {
-			c[0] = int64(v)
-			c[1] = IntegerCell
-		}
-	case uint64:
-		if v > math.MaxInt64 {
-			c[0] = float64(v)
-			c[1] = FloatCell
-		} else {
-			c[0] = int64(v)
-			c[1] = IntegerCell
-		}
-
-	case float32:
-		c[0] = float64(v)
-		c[1] = FloatCell
-	case float64:
-		c[0] = float64(v)
-		c[1] = FloatCell
-
-	case string:
-		if len(v) == 0 {
-			c[0] = nil
-			c[1] = BlankCell
-		} else {
-			c[0] = v
-			c[1] = StringCell
-		}
-	case []byte:
-		if len(v) == 0 {
-			c[0] = nil
-			c[1] = BlankCell
-		} else {
-			c[0] = string(v)
-			c[1] = StringCell
-		}
-	case []uint16:
-		if len(v) == 0 {
-			c[0] = nil
-			c[1] = BlankCell
-		} else {
-			c[0] = string(utf16.Decode(v))
-			c[1] = StringCell
-		}
-	case []rune:
-		if len(v) == 0 {
-			c[0] = nil
-			c[1] = BlankCell
-		} else {
-			c[0] = string(v)
-			c[1] = StringCell
-		}
-	case time.Time:
-		c[0] = v
-		c[1] = DateCell
-
-	case fmt.Stringer:
-		s := v.String()
-		if len(s) == 0 {
-			c[0] = nil
-			c[1] = BlankCell
-		} else {
-			c[0] = s
-			c[1] = StringCell
-		}
-	default:
-		panic("grate: data type not handled")
-	}
-	return Cell(c)
+	var tab strTable
+	return packValue(&tab, value).cell(tab)
 }
 
 // SetFormatNumber changes the number format stored with the cell.
 func (c *Cell) SetFormatNumber(f uint16) {
-	if f == 0 {
+	if f == 0 && len(*c) <= 3 {
 		*c = (*c)[:2]
 		return
 	}
 
-	if len(*c) == 2 {
-		*c = append(*c, f)
-	} else {
-		(*c)[2] = f
-	}
+	c.grow(3)
+	(*c)[2] = f
 }
 
 func (c Cell) Equal(other Cell) bool {
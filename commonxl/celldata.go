@@ -0,0 +1,267 @@
+package commonxl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
+)
+
+// cellData is the compact in-memory form of a Cell, using 16 bytes
+// per cell. Depending on the kind, bits holds:
+//
+//	IntegerCell, BooleanCell: an int64 (0 or 1 for booleans)
+//	FloatCell:                the bits of a float64
+//	DateCell:                 microseconds since the Unix epoch (UTC)
+//	DurationCell:             nanoseconds, as a time.Duration
+//	String, Hyperlink, Static cells: an index into the string table
+//	ErrorCell:                the error code << 32 | index of its text
+//
+// Hyperlinks and other rare data are held in sparse maps by the Sheet.
+type cellData struct {
+	bits  uint64
+	kind  CellType
+	fmtNo uint16
+	xf    uint16
+}
+
+// strTable holds the string values of the cells in a sheet.
+type strTable []string
+
+func (t *strTable) add(s string) uint64 {
+	*t = append(*t, s)
+	return uint64(len(*t) - 1)
+}
+
+func (t *strTable) str(s string) cellData {
+	return cellData{bits: t.add(s), kind: StringCell}
+}
+
+func intData(v int64) cellData {
+	return cellData{bits: uint64(v), kind: IntegerCell}
+}
+
+func floatData(v float64) cellData {
+	return cellData{bits: math.Float64bits(v), kind: FloatCell}
+}
+
+func boolData(v bool) cellData {
+	if v {
+		return cellData{bits: 1, kind: BooleanCell}
+	}
+	return cellData{kind: BooleanCell}
+}
+
+func dateData(v time.Time) cellData {
+	v = v.Round(time.Microsecond)
+	us := v.Unix()*1e6 + int64(v.Nanosecond()/1e3)
+	return cellData{bits: uint64(us), kind: DateCell}
+}
+
+func durationData(v time.Duration) cellData {
+	return cellData{bits: uint64(v), kind: DurationCell}
+}
+
+func (c cellData) int() int64 {
+	return int64(c.bits)
+}
+
+func (c cellData) float() float64 {
+	return math.Float64frombits(c.bits)
+}
+
+func (c cellData) date() time.Time {
+	us := int64(c.bits)
+	sec, frac := us/1e6, us%1e6
+	if frac < 0 {
+		sec, frac = sec-1, frac+1e6
+	}
+	return time.Unix(sec, frac*1e3).UTC()
+}
+
+// value returns the contents as a generic interface{}, as for Cell.Value.
+func (c cellData) value(t strTable) interface{} {
+	switch c.kind {
+	case IntegerCell:
+		return c.int()
+	case FloatCell:
+		return c.float()
+	case BooleanCell:
+		return c.bits != 0
+	case DateCell:
+		return c.date()
+	case DurationCell:
+		return time.Duration(c.bits)
+	case StringCell, HyperlinkStringCell, StaticCell:
+		return t[c.bits]
+	case ErrorCell:
+		return grate.CellError{Code: byte(c.bits >> 32), Text: t[uint32(c.bits)]}
+	}
+	return nil
+}
+
+// packValue converts any builtin type into a cell value, as for NewCell.
+func packValue(t *strTable, value interface{}) cellData {
+	switch v := value.(type) {
+	case bool:
+		return boolData(v)
+	case int:
+		return intData(int64(v))
+	case int8:
+		return intData(int64(v))
+	case int16:
+		return intData(int64(v))
+	case int32:
+		return intData(int64(v))
+	case int64:
+		return intData(v)
+	case uint8:
+		return intData(int64(v))
+	case uint16:
+		return intData(int64(v))
+	case uint32:
+		return intData(int64(v))
+
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return floatData(float64(v))
+		}
+		return intData(int64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			return floatData(float64(v))
+		}
+		return intData(int64(v))
+
+	case float32:
+		return floatData(float64(v))
+	case float64:
+		return floatData(v)
+
+	case string:
+		if len(v) == 0 {
+			return cellData{}
+		}
+		return t.str(v)
+	case []byte:
+		if len(v) == 0 {
+			return cellData{}
+		}
+		return t.str(string(v))
+	case []uint16:
+		if len(v) == 0 {
+			return cellData{}
+		}
+		return t.str(string(utf16.Decode(v)))
+	case []rune:
+		if len(v) == 0 {
+			return cellData{}
+		}
+		return t.str(string(v))
+	case time.Time:
+		return dateData(v)
+	case time.Duration:
+		return durationData(v)
+	case grate.CellError:
+		return cellData{bits: uint64(v.Code)<<32 | t.add(v.Text), kind: ErrorCell}
+
+	case fmt.Stringer:
+		s := v.String()
+		if len(s) == 0 {
+			return cellData{}
+		}
+		return t.str(s)
+	}
+	panic("grate: data type not handled")
+}
+
+// coerce converts the value to the given type, as for NewCellWithType.
+// Values which cannot be converted to dates or durations are unchanged.
+func (c cellData) coerce(t *strTable, ct CellType, f *Formatter) cellData {
+	if c.kind == ct || c.kind == ErrorCell {
+		// fast path if it was already typed correctly
+		// (errors are never coerced)
+		return c
+	}
+
+	if c.kind == BooleanCell {
+		switch ct {
+		case IntegerCell:
+			c.kind = IntegerCell
+		case FloatCell:
+			c = floatData(float64(c.bits))
+		}
+	}
+
+	switch c.kind {
+	case FloatCell:
+		if ct == IntegerCell {
+			// integer formats display the rounded value
+			c = intData(int64(math.Round(c.float())))
+		} else if ct == BooleanCell {
+			c = boolData(c.float() != 0.0)
+		}
+	case IntegerCell:
+		if ct == FloatCell {
+			c = floatData(float64(c.int()))
+		} else if ct == BooleanCell {
+			c = boolData(c.int() != 0)
+		}
+	case StringCell:
+		s := (*t)[c.bits]
+		if ct == IntegerCell {
+			x, _ := strconv.ParseInt(s, 10, 64)
+			c = intData(x)
+		} else if ct == FloatCell {
+			x, _ := strconv.ParseFloat(s, 64)
+			c = floatData(x)
+		} else if ct == BooleanCell {
+			c = boolData(boolStrings[s])
+		}
+	}
+
+	switch ct {
+	case StringCell:
+		if c.kind == BooleanCell {
+			if c.bits != 0 {
+				return t.str("TRUE")
+			}
+			return t.str("FALSE")
+		}
+		if c.kind != StringCell {
+			return t.str(fmt.Sprint(c.value(*t)))
+		}
+	case DurationCell:
+		switch c.kind {
+		case FloatCell:
+			if math.Abs(c.float()) >= maxDurationDays {
+				// too long for a Duration, keep the serial
+				return c
+			}
+			return durationData(f.ConvertToDuration(c.float()))
+		case IntegerCell:
+			if math.Abs(float64(c.int())) >= maxDurationDays {
+				return c
+			}
+			return durationData(f.ConvertToDuration(float64(c.int())))
+		case DateCell:
+			return durationData(c.date().Sub(f.ConvertToDate(0)))
+		}
+	case DateCell:
+		switch c.kind {
+		case FloatCell:
+			return dateData(f.ConvertToDate(c.float()))
+		case IntegerCell:
+			return dateData(f.ConvertToDate(float64(c.int())))
+		}
+	}
+	return c
+}
+
+// cell converts the value to a Cell without its format and styling.
+func (c cellData) cell(t strTable) Cell {
+	return Cell{c.value(t), c.kind}
+}
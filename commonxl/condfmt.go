@@ -0,0 +1,49 @@
+package commonxl
+
+// ConditionalFormat describes a conditional formatting rule applied to
+// ranges of cells. Type and Operator use the SpreadsheetML names.
+type ConditionalFormat struct {
+	Ranges []Range
+
+	Type       string // e.g. "cellIs", "expression", "colorScale", "dataBar", "top10"
+	Operator   string // for "cellIs", e.g. "between", "greaterThan"
+	Priority   int    // lower values are evaluated first
+	StopIfTrue bool
+
+	// Formulas hold the comparison values or expression, e.g.
+	// ["0.8"] for "greater than 0.8" or ["$B1>$C1"].
+	Formulas []string
+
+	// Text is the value searched for by text rules, e.g. "containsText".
+	Text string
+
+	// Rank, Percent and Bottom describe "top10" rules.
+	Rank    int
+	Percent bool
+	Bottom  bool
+
+	// Thresholds and Colors describe "colorScale" and "dataBar" rules,
+	// e.g. min/max thresholds with a color for each one.
+	Thresholds []Threshold
+	Colors     []Color
+
+	// Format is the formatting applied when the rule matches. Only the
+	// properties set by the rule are filled in, and it may be nil.
+	Format *Style
+}
+
+// Threshold is a value used by color scale and data bar rules.
+type Threshold struct {
+	Type  string // "min", "max", "num", "percent", "percentile", "formula"
+	Value string
+}
+
+// AddConditionalFormat records a conditional formatting rule for the sheet.
+func (s *Sheet) AddConditionalFormat(cf ConditionalFormat) {
+	s.condFormats = append(s.condFormats, cf)
+}
+
+// ConditionalFormats returns the conditional formatting rules defined on the sheet.
+func (s *Sheet) ConditionalFormats() []ConditionalFormat {
+	return s.condFormats
+}
@@ -1,8 +1,11 @@
 package commonxl
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // ConvertToDate converts a floating-point value using the
@@ -44,35 +47,383 @@ func (x *Formatter) ConvertToDate(val float64) time.Time {
 	return date.AddDate(0, 0, v).Add(t)
 }
 
-func timeFmtFunc(f string) FmtFunc {
+// maxDurationDays is the largest number of days held by a time.Duration,
+// about 106751.
+const maxDurationDays = float64(math.MaxInt64) / float64(24*time.Hour)
+
+// ConvertToDuration converts a floating-point number of days
+// to a Duration, rounded to the nearest millisecond. Values out
+// of range of a Duration are clamped to the largest Duration.
+func (x *Formatter) ConvertToDuration(val float64) time.Duration {
+	switch {
+	case val >= maxDurationDays:
+		return math.MaxInt64
+	case val <= -maxDurationDays:
+		return math.MinInt64
+	}
+	return time.Duration(math.Round(val*24*60*60*1000)) * time.Millisecond
+}
+
+// toTime converts a date, duration or numeric value to a time.
+func (x *Formatter) toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case time.Duration:
+		// durations are relative to the zero date
+		return x.ConvertToDate(0).Add(t), true
+	}
+	fval, ok := convertToFloat64(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	return x.ConvertToDate(fval), true
+}
+
+// dateToken is a single element of a date format code. Kinds are the
+// lowercase code letter (with 'M' for minutes), 'f' for fractional
+// seconds, 'a' for AM/PM, 'p' for A/P, 'w' for the CJK weekday codes
+// aaa and aaaa, or 0 for literal text.
+type dateToken struct {
+	kind byte
+	n    int
+	lit  string
+}
+
+// parseDateFormat splits an Excel date format code into tokens.
+func parseDateFormat(f string) []dateToken {
+	var toks []dateToken
+	lit := func(s string) {
+		if n := len(toks); n > 0 && toks[n-1].kind == 0 {
+			toks[n-1].lit += s
+			return
+		}
+		toks = append(toks, dateToken{lit: s})
+	}
+	last := func() byte {
+		if len(toks) == 0 {
+			return 0
+		}
+		return toks[len(toks)-1].kind
+	}
+
+	for i := 0; i < len(f); {
+		c := f[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(f[i+1:], '"')
+			if end < 0 {
+				end = len(f) - i - 1
+			}
+			lit(f[i+1 : i+1+end])
+			i += end + 2
+		case (c == '\\' || c == '_' || c == '*') && i+1 < len(f):
+			_, sz := utf8.DecodeRuneInString(f[i+1:])
+			if c == '\\' {
+				lit(f[i+1 : i+1+sz])
+			} else if c == '_' {
+				lit(" ")
+			}
+			i += 1 + sz
+		case c == '[':
+			end := strings.IndexByte(f[i:], ']')
+			if end < 0 {
+				lit(f[i:])
+				end = len(f) - i - 1
+			}
+			i += end + 1
+		case strings.HasPrefix(strings.ToUpper(f[i:]), "AM/PM"):
+			toks = append(toks, dateToken{kind: 'a'})
+			i += 5
+		case strings.HasPrefix(f[i:], "上午/下午"):
+			toks = append(toks, dateToken{kind: 'a', lit: "上午/下午"})
+			i += len("上午/下午")
+		case strings.HasPrefix(strings.ToUpper(f[i:]), "A/P"):
+			toks = append(toks, dateToken{kind: 'p', lit: f[i : i+1]})
+			i += 3
+		case (c | 0x20) == 'a':
+			j := i
+			for j < len(f) && (f[j]|0x20) == 'a' {
+				j++
+			}
+			if j-i >= 3 {
+				toks = append(toks, dateToken{kind: 'w', n: j - i})
+			} else {
+				lit(f[i:j])
+			}
+			i = j
+		case c == '.' && i+1 < len(f) && f[i+1] == '0' && last() == 's':
+			j := i + 1
+			for j < len(f) && f[j] == '0' {
+				j++
+			}
+			toks = append(toks, dateToken{kind: 'f', n: j - i - 1})
+			i = j
+		case strings.IndexByte("ymdhsgeb", c|0x20) >= 0 && (c|0x20) >= 'a':
+			j := i
+			for j < len(f) && (f[j]|0x20) == (c|0x20) {
+				j++
+			}
+			toks = append(toks, dateToken{kind: c | 0x20, n: j - i})
+			i = j
+		default:
+			_, sz := utf8.DecodeRuneInString(f[i:])
+			lit(f[i : i+sz])
+			i += sz
+		}
+	}
+
+	// m and mm are minutes when they follow hours or precede seconds
+	for i, t := range toks {
+		if t.kind != 'm' || t.n > 2 {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if toks[j].kind != 0 {
+				if toks[j].kind == 'h' {
+					toks[i].kind = 'M'
+				}
+				break
+			}
+		}
+		for j := i + 1; j < len(toks); j++ {
+			if toks[j].kind != 0 {
+				if toks[j].kind == 's' {
+					toks[i].kind = 'M'
+				}
+				break
+			}
+		}
+	}
+	return toks
+}
+
+// dateFmtFunc formats a date or time using an Excel format code such as
+// "d-mmm-yy h:mm AM/PM", with names from the Formatter's locale.
+func dateFmtFunc(f string) FmtFunc {
+	toks := parseDateFormat(f)
 	return func(x *Formatter, v interface{}) string {
-		t, ok := v.(time.Time)
+		t, ok := x.toTime(v)
 		if !ok {
-			fval, ok := convertToFloat64(v)
-			if !ok {
-				return "MUST BE time.Time OR numeric TO FORMAT CORRECTLY"
+			return "MUST BE time.Time OR numeric TO FORMAT CORRECTLY"
+		}
+		return formatDate(x.Locale(), toks, t)
+	}
+}
+
+func formatDate(loc *Locale, toks []dateToken, t time.Time) string {
+	ampm := false
+	prec := time.Second
+	for _, tok := range toks {
+		switch tok.kind {
+		case 'a', 'p':
+			ampm = true
+		case 'f':
+			prec = time.Second
+			for i := 0; i < tok.n && i < 9; i++ {
+				prec /= 10
+			}
+		}
+	}
+	t = t.Round(prec)
+
+	var sb strings.Builder
+	for _, tok := range toks {
+		switch tok.kind {
+		case 0:
+			sb.WriteString(tok.lit)
+		case 'y', 'e', 'b':
+			y := t.Year()
+			if tok.kind == 'b' {
+				y += 543
+			} else if tok.kind == 'e' || loc.eraYears {
+				_, y = loc.era(t)
+			}
+			if tok.kind == 'y' && !loc.eraYears && tok.n > 2 {
+				fmt.Fprintf(&sb, "%04d", y)
+			} else if tok.n <= 2 && (tok.kind != 'e' || tok.n == 2) {
+				fmt.Fprintf(&sb, "%02d", y%100)
+			} else {
+				fmt.Fprintf(&sb, "%d", y)
+			}
+		case 'g':
+			if era, _ := loc.era(t); era != nil {
+				switch tok.n {
+				case 1:
+					sb.WriteString(era.Initial)
+				case 2:
+					sb.WriteString(era.Short)
+				default:
+					sb.WriteString(era.Name)
+				}
+			}
+		case 'm':
+			switch tok.n {
+			case 1:
+				fmt.Fprintf(&sb, "%d", t.Month())
+			case 2:
+				fmt.Fprintf(&sb, "%02d", t.Month())
+			case 3:
+				sb.WriteString(loc.ShortMonths[t.Month()-1])
+			case 5:
+				r, _ := utf8.DecodeRuneInString(loc.Months[t.Month()-1])
+				sb.WriteRune(r)
+			default:
+				sb.WriteString(loc.Months[t.Month()-1])
+			}
+		case 'd':
+			switch tok.n {
+			case 1:
+				fmt.Fprintf(&sb, "%d", t.Day())
+			case 2:
+				fmt.Fprintf(&sb, "%02d", t.Day())
+			case 3:
+				sb.WriteString(loc.ShortDays[t.Weekday()])
+			default:
+				sb.WriteString(loc.Days[t.Weekday()])
+			}
+		case 'w':
+			if tok.n == 3 {
+				sb.WriteString(loc.ShortDays[t.Weekday()])
+			} else {
+				sb.WriteString(loc.Days[t.Weekday()])
+			}
+		case 'h', 'M', 's':
+			n := t.Second()
+			if tok.kind == 'M' {
+				n = t.Minute()
+			} else if tok.kind == 'h' {
+				n = t.Hour()
+				if ampm {
+					n %= 12
+					if n == 0 {
+						n = 12
+					}
+				}
+			}
+			if tok.n == 1 {
+				fmt.Fprintf(&sb, "%d", n)
+			} else {
+				fmt.Fprintf(&sb, "%02d", n)
+			}
+		case 'f':
+			ns := fmt.Sprintf("%09d", t.Nanosecond())
+			n := tok.n
+			if n > 9 {
+				n = 9
 			}
-			t = x.ConvertToDate(fval)
+			sb.WriteString(loc.Decimal + ns[:n])
+		case 'a':
+			am, pm := loc.AM, loc.PM
+			if tok.lit != "" {
+				parts := strings.SplitN(tok.lit, "/", 2)
+				am, pm = parts[0], parts[1]
+			}
+			if t.Hour() < 12 {
+				sb.WriteString(am)
+			} else {
+				sb.WriteString(pm)
+			}
+		case 'p':
+			s := "A"
+			if t.Hour() >= 12 {
+				s = "P"
+			}
+			if tok.lit == "a" {
+				s = strings.ToLower(s)
+			}
+			sb.WriteString(s)
 		}
-		//log.Println("formatting date", t, "with", f, "=", t.Format(f))
-		return t.Format(f)
 	}
+	return sb.String()
 }
 
-// same as above but replaces "AM" and "PM" with chinese translations.
-// TODO: implement others
-func cnTimeFmtFunc(f string) FmtFunc {
+// elapsedFmtFunc formats a duration using an Excel format code such as
+// "[h]:mm:ss", where the bracketed unit is not wrapped at the next
+// larger unit (e.g. 26 hours is "26:00:00" instead of "2:00:00").
+func elapsedFmtFunc(f string) FmtFunc {
 	return func(x *Formatter, v interface{}) string {
-		t, ok := v.(time.Time)
+		if d, ok := v.(time.Duration); ok {
+			return formatElapsed(f, int64(d/time.Millisecond))
+		}
+		fval, ok := convertToFloat64(v)
 		if !ok {
-			fval, ok := convertToFloat64(v)
-			if !ok {
-				return "MUST BE time.Time OR numeric TO FORMAT CORRECTLY"
+			return "MUST BE time.Duration OR numeric TO FORMAT CORRECTLY"
+		}
+		// in milliseconds, as days beyond a Duration still fit
+		return formatElapsed(f, int64(math.Round(fval*24*60*60*1000)))
+	}
+}
+
+// formatElapsed formats a number of milliseconds.
+func formatElapsed(f string, ms int64) string {
+	var sb strings.Builder
+	if ms < 0 {
+		sb.WriteByte('-')
+		ms = -ms
+	}
+	for i := 0; i < len(f); {
+		c := f[i]
+		switch {
+		case c == '[':
+			end := strings.IndexByte(f[i:], ']')
+			if end < 0 {
+				sb.WriteString(f[i:])
+				return sb.String()
+			}
+			unit := f[i+1 : i+end]
+			var n int64
+			switch strings.ToLower(unit[:1]) {
+			case "h":
+				n = ms / 3600000
+			case "m":
+				n = ms / 60000
+			case "s":
+				n = ms / 1000
+			}
+			fmt.Fprintf(&sb, "%0*d", len(unit), n)
+			i += end + 1
+		case c == 'h' || c == 'H' || c == 'm' || c == 'M' || c == 's' || c == 'S':
+			j := i
+			for j < len(f) && (f[j]|0x20) == (c|0x20) {
+				j++
+			}
+			var n int64
+			switch c | 0x20 {
+			case 'h':
+				n = (ms / 3600000) % 24
+			case 'm':
+				n = (ms / 60000) % 60
+			case 's':
+				n = (ms / 1000) % 60
+			}
+			fmt.Fprintf(&sb, "%0*d", j-i, n)
+			i = j
+		case c == '.' && i+1 < len(f) && f[i+1] == '0':
+			j := i + 1
+			for j < len(f) && f[j] == '0' {
+				j++
+			}
+			frac := fmt.Sprintf("%03d", ms%1000)
+			if j-i-1 < 3 {
+				frac = frac[:j-i-1]
+			}
+			sb.WriteString("." + frac)
+			i = j
+		case c == '"':
+			end := strings.IndexByte(f[i+1:], '"')
+			if end < 0 {
+				end = len(f) - i - 1
 			}
-			t = x.ConvertToDate(fval)
+			sb.WriteString(f[i+1 : i+1+end])
+			i += end + 2
+		case c == '\\' && i+1 < len(f):
+			sb.WriteByte(f[i+1])
+			i += 2
+		default:
+			sb.WriteByte(c)
+			i++
 		}
-		s := t.Format(f)
-		s = strings.Replace(s, `AM`, `上午`, 1)
-		return strings.Replace(s, `PM`, `下午`, 1)
 	}
+	return sb.String()
 }
@@ -0,0 +1,68 @@
+package commonxl
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestElapsedFormats(t *testing.T) {
+	d := 26*time.Hour + 3*time.Minute + 4*time.Second + 500*time.Millisecond
+	for code, expect := range map[string]string{
+		`[h]:mm:ss`:   "26:03:04",
+		`[hh]:mm`:     "26:03",
+		`[mm]:ss`:     "1563:04",
+		`[s]`:         "93784",
+		`[h]:mm:ss.0`: "26:03:04.5",
+		`[h]"h"`:      "26h",
+	} {
+		if s := formatElapsed(code, int64(d/time.Millisecond)); s != expect {
+			t.Errorf("%s: expected %s, got %s", code, expect, s)
+		}
+	}
+	if s := formatElapsed(`[h]:mm`, -90*60*1000); s != "-1:30" {
+		t.Errorf("expected -1:30, got %s", s)
+	}
+}
+
+func TestDurationCells(t *testing.T) {
+	for code, expect := range map[string]CellType{
+		`[h]:mm:ss`:     DurationCell,
+		`[Red][mm]:ss`:  DurationCell,
+		`h:mm AM/PM`:    DateCell,
+		`mm:ss.0`:       DateCell,
+		`yyyy-mm-dd`:    DateCell,
+		`d/m/yyyy h:mm`: DateCell,
+	} {
+		if _, ct := makeFormatter(code); ct != expect {
+			t.Errorf("%s: expected %s, got %s", code, expect, ct)
+		}
+	}
+
+	fx := &Formatter{}
+	fx.Add(200, `[h]:mm`)
+	s := &Sheet{Formatter: fx}
+	s.Resize(1, 3)
+	s.Put(0, 0, 1.25, 200)
+	s.Put(0, 1, 0.75, 20)
+	s.Put(0, 2, 200000.5, 200) // beyond the range of a Duration
+	s.Next()
+	if types := s.Types(); types[0] != "duration" || types[1] != "date" || types[2] != "float" {
+		t.Errorf("unexpected types %v", types)
+	}
+	if strs := s.Strings(); strs[0] != "30:00" || strs[1] != "18:00" || strs[2] != "4800012:00" {
+		t.Errorf("unexpected strings %v", strs)
+	}
+	var d1 time.Duration
+	var t2 time.Time
+	if err := s.Scan(&d1, &t2); err != nil {
+		t.Fatal(err)
+	}
+	if d1 != 30*time.Hour || t2.Hour() != 18 {
+		t.Errorf("unexpected values %s %s", d1, t2)
+	}
+
+	if d := fx.ConvertToDuration(200000.5); d != math.MaxInt64 {
+		t.Errorf("expected the largest duration, got %s", d)
+	}
+}
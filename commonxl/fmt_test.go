@@ -42,31 +42,31 @@ var commas = []testcaseNums{
 	{uint16(10000), "10,000"},
 	{100000, "100,000"},
 	{float64(100000), "100,000"},
-	{float64(100000) + 0.12345, "100,000.12345"},
+	{float64(100000) + 0.12345, "100,000.1235"},
 	{-100000, "-100,000"},
 	{float64(-100000), "-100,000"},
 	{float64(-100000) + 0.12345, "-99,999.87655"},
 	{uint64(100000), "100,000"},
 	{1000000, "1,000,000"},
-	{float64(1000000), "1e+06"},
-	{float64(1000000) + 0.12345, "1.00000012345e+06"},
+	{float64(1000000), "1,000,000"},
+	{float64(1000000) + 0.12345, "1,000,000.123"},
 	{-1000000, "-1,000,000"},
-	{float64(-1000000), "-1e+06"},
-	{float64(-1000000) + 0.12345, "-999,999.87655"},
+	{float64(-1000000), "-1,000,000"},
+	{float64(-1000000) + 0.12345, "-999,999.8766"},
 	{uint64(1000000), "1,000,000"},
 	{10000000, "10,000,000"},
-	{float64(10000000), "1e+07"},
-	{float64(10000000) + 0.12345, "1.000000012345e+07"},
+	{float64(10000000), "10,000,000"},
+	{float64(10000000) + 0.12345, "10,000,000.12"},
 	{-10000000, "-10,000,000"},
-	{float64(-10000000), "-1e+07"},
-	{float64(-10000000) + 0.12345, "-9.99999987655e+06"},
+	{float64(-10000000), "-10,000,000"},
+	{float64(-10000000) + 0.12345, "-9,999,999.877"},
 	{uint64(10000000), "10,000,000"},
 	{100000000, "100,000,000"},
-	{float64(100000000), "1e+08"},
-	{float64(100000000) + 0.12345, "1.0000000012345e+08"},
+	{float64(100000000), "100,000,000"},
+	{float64(100000000) + 0.12345, "100,000,000.1"},
 	{-100000000, "-100,000,000"},
-	{float64(-100000000), "-1e+08"},
-	{float64(-100000000) + 0.12345, "-9.999999987655e+07"},
+	{float64(-100000000), "-100,000,000"},
+	{float64(-100000000) + 0.12345, "-99,999,999.88"},
 	{uint64(100000000), "100,000,000"},
 }
 
@@ -139,3 +139,79 @@ func TestBoolFormats(t *testing.T) {
 		t.Fatal(`-99.0 should be "yes"`)
 	}
 }
+
+func TestFormatCodes(t *testing.T) {
+	fx := &Formatter{}
+	fx.Add(164, `0.0%;[Red]-0.0%`)
+	s := &Sheet{Formatter: fx}
+	s.Resize(1, 3)
+	s.Put(0, 0, 0.25, 164)
+	s.Put(0, 1, 44259.0, 14)
+	s.Put(0, 2, 1.5, 300)
+	s.Next()
+	codes := s.Formats()
+	if codes[0] != `0.0%;[Red]-0.0%` || codes[1] != `mm-dd-yy` || codes[2] != `General` {
+		t.Errorf("unexpected format codes %q", codes)
+	}
+	if strs := s.Strings(); strs[0] != "25.0%" || strs[2] != "1.5" {
+		t.Errorf("unexpected strings %q", strs)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if s := Format(`#,##0.00`, 1234.5, nil); s != "1,234.50" {
+		t.Errorf("expected 1,234.50, got %s", s)
+	}
+	de, _ := LookupLocale("de-DE")
+	if s := Format(`#,##0.00`, 1234.5, &FormatOptions{Locale: de}); s != "1.234,50" {
+		t.Errorf("expected 1.234,50, got %s", s)
+	}
+	if s := Format(`yyyy-mm-dd`, 0, &FormatOptions{Date1904: true}); s != "1904-01-01" {
+		t.Errorf("expected 1904-01-01, got %s", s)
+	}
+	if s := Format(`yyyy-mm-dd`, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), nil); s != "2021-03-04" {
+		t.Errorf("expected 2021-03-04, got %s", s)
+	}
+}
+
+func TestBuiltInFormats(t *testing.T) {
+	fx := &Formatter{}
+	cases := []struct {
+		id  uint16
+		v   interface{}
+		exp string
+	}{
+		{1, 1234.5, "1235"},
+		{2, 0.125, "0.13"},
+		{2, int64(7), "7.00"},
+		{3, int64(-1234), "-1,234"},
+		{9, 0.125, "13%"},
+		{11, 1234.5, "1.23E+03"},
+		{48, 0.125, "125.0E-3"},
+		{0, true, "TRUE"},
+		{0, "007", "007"},
+		{49, "007", "007"},
+	}
+	for _, c := range cases {
+		if s, ok := fx.Apply(c.id, c.v); !ok || s != c.exp {
+			t.Errorf("format %d of %T(%v): expected %q, got %q", c.id, c.v, c.v, c.exp, s)
+		}
+	}
+}
+
+func TestFormatBuiltIns(t *testing.T) {
+	values := []interface{}{1234.5678, -0.25, 0.0, 0.125, int64(7), int64(-1234)}
+	for id, code := range builtInFormats {
+		s := &Sheet{Formatter: &Formatter{}}
+		s.Resize(1, len(values))
+		for i, v := range values {
+			s.Put(0, i, v, id)
+		}
+		s.Next()
+		for i, got := range s.Strings() {
+			if exp := Format(code, values[i], nil); got != exp {
+				t.Errorf("format %d (%s) of %T(%v): cell %q, Format %q", id, code, values[i], values[i], got, exp)
+			}
+		}
+	}
+}
@@ -2,16 +2,18 @@ package commonxl
 
 import (
 	"errors"
-	"fmt"
-	"regexp"
 	"strings"
 )
 
 // Formatter contains formatting methods common to Excel spreadsheets.
 type Formatter struct {
-	flags           uint64
-	customCodes     map[uint16]FmtFunc
-	customCodeTypes map[uint16]CellType
+	flags             uint64
+	locale            *Locale
+	customCodes       map[uint16]FmtFunc
+	customCodeTypes   map[uint16]CellType
+	customCodeStrs    map[uint16]string
+	customCodeClasses map[uint16]NumberClass
+	overrides         overrides
 }
 
 const (
@@ -28,14 +30,33 @@ func (x *Formatter) Mode1904(enabled bool) {
 	}
 }
 
-// Add a custom number format to the formatter.
+// SetLocale sets the locale used to render values whose format code
+// does not specify one. A nil Locale selects the default (en-US).
+func (x *Formatter) SetLocale(l *Locale) {
+	x.locale = l
+}
+
+// Locale returns the locale used to render values.
+func (x *Formatter) Locale() *Locale {
+	if x == nil || x.locale == nil {
+		return LocaleEnUS
+	}
+	return x.locale
+}
+
+// Add a custom number format to the formatter. Built-in formats cannot
+// be replaced, use OverrideID to change how they are rendered.
 func (x *Formatter) Add(fmtID uint16, formatCode string) error {
 	if x.customCodes == nil {
 		x.customCodes = make(map[uint16]FmtFunc)
 		x.customCodeTypes = make(map[uint16]CellType)
+		x.customCodeStrs = make(map[uint16]string)
+		x.customCodeClasses = make(map[uint16]NumberClass)
 	}
 	if strings.ToLower(formatCode) == "general" {
 		x.customCodes[fmtID] = goFormatters[0]
+		x.customCodeStrs[fmtID] = formatCode
+		x.customCodeClasses[fmtID] = NumberClass{Category: GeneralNumber}
 		return nil
 	}
 	_, ok := goFormatters[fmtID]
@@ -49,9 +70,23 @@ func (x *Formatter) Add(fmtID uint16, formatCode string) error {
 	}
 
 	x.customCodes[fmtID], x.customCodeTypes[fmtID] = makeFormatter(formatCode)
+	x.customCodeStrs[fmtID] = formatCode
+	x.customCodeClasses[fmtID] = parseNumFmt(formatCode).classify()
 	return nil
 }
 
+// Code returns the format code string for the number format, it returns
+// "General" and false when fmtID is unknown.
+func (x *Formatter) Code(fmtID uint16) (string, bool) {
+	if code, ok := builtInFormats[fmtID]; ok {
+		return code, true
+	}
+	if code, ok := x.customCodeStrs[fmtID]; ok {
+		return code, true
+	}
+	return builtInFormats[0], false
+}
+
 func (x *Formatter) getCellType(fmtID uint16) (CellType, bool) {
 	if ct, ok := builtInFormatTypes[fmtID]; ok {
 		return ct, true
@@ -63,136 +98,38 @@ func (x *Formatter) getCellType(fmtID uint16) (CellType, bool) {
 	return 0, false
 }
 
-var (
-	minsMatch = regexp.MustCompile("h.*m.*s")
-	nonEsc    = regexp.MustCompile(`([^"]|^)"`)
-	squash    = regexp.MustCompile(`[*_].`)
-	fixEsc    = regexp.MustCompile(`\\(.)`)
-
-	formatMatchBrackets    = regexp.MustCompile(`\[[^\]]*\]`)
-	formatMatchTextLiteral = regexp.MustCompile(`"[^"]*"`)
-)
-
+// makeFormatter parses an Excel format code, returning a function to
+// render values with it and the type of values it describes.
 func makeFormatter(s string) (FmtFunc, CellType) {
-	//log.Printf("makeFormatter('%s')", s)
-	// remove any coloring marks
-	s = formatMatchBrackets.ReplaceAllString(s, "")
-	if strings.Contains(s, ";") {
-		parts := strings.Split(s, ";")
-		posFF, ctypePos := makeFormatter(parts[0])
-		rem := make([]FmtFunc, len(parts)-1)
-		for i, ps := range parts[1:] {
-			rem[i], _ = makeFormatter(ps)
-		}
-		return switchFmtFunc(posFF, rem...), ctypePos
-	}
-
-	// escaped characters, and quoted text
-	s2 := fixEsc.ReplaceAllString(s, "")
-	s2 = formatMatchTextLiteral.ReplaceAllString(s, "")
-
-	if strings.ContainsAny(s2, "ymdhs") {
-		// it's a date/time format
-
-		if loc := minsMatch.FindStringIndex(s); loc != nil {
-			// m or mm in loc[0]:loc[1] is a minute format
-			inner := s[loc[0]:loc[1]]
-			inner = strings.Replace(inner, "mm", "04", 1)
-			inner = strings.Replace(inner, "m", "4", 1)
-			s = s[:loc[0]] + inner + s[loc[1]:]
-		}
-		dfreps := [][]string{
-			{"hh", "15"}, {"h", "15"},
-			{"ss", "05"}, {"s", "5"},
-			{"mmmmm", "Jan"}, // super ambiguous, replace with 3-letter month
-			{"mmmm", "January"}, {"mmm", "Jan"},
-			{"mm", "01"}, {"m", "1"},
-			{"dddd", "Monday"}, {"ddd", "Mon"},
-			{"dd", "02"}, {"d", "2"},
-			{"yyyy", "2006"}, {"yy", "06"},
-		}
-		if strings.Contains(s, "AM") || strings.Contains(s, "PM") {
-			dfreps[0][1] = "03"
-			dfreps[1][1] = "3"
-		}
-		for _, dfr := range dfreps {
-			s = strings.Replace(s, dfr[0], dfr[1], 1)
-		}
-
-		s = nonEsc.ReplaceAllString(s, `$1`)
-		s = squash.ReplaceAllString(s, ``)
-		s = fixEsc.ReplaceAllString(s, `$1`)
-
-		//log.Printf("   made time formatter '%s'", s)
-		return timeFmtFunc(s), DateCell
-	}
-
-	var ff FmtFunc
-	var ctype CellType
-	if strings.ContainsAny(s, ".Ee") {
-		verb := "f"
-		if strings.ContainsAny(s, "Ee") {
-			verb = "E"
-		}
-		s = regexp.MustCompile("[eE]+[+-]0+").ReplaceAllString(s, "")
-		s2 := strings.ReplaceAll(s, ",", "")
-		i1 := strings.IndexAny(s2, "0")
-		i2 := strings.IndexByte(s2, '.')
-		i3 := strings.LastIndexAny(s2, "0.")
-		mul := 1
-		if strings.Contains(s2, "%") {
-			mul = 100
-		}
-		sf := fmt.Sprintf("%%%d.%d%s", i3-i1, i3-i2, verb)
-		//log.Printf("   made float formatter '%s'", sf)
-		ff = sprintfFunc(sf, mul)
-		ctype = FloatCell
-	} else {
-		s2 := strings.ReplaceAll(s, ",", "")
-		i1 := strings.IndexAny(s2, "0")
-		i2 := strings.LastIndexAny(s2, "0.")
-		mul := 1
-		if strings.Contains(s2, "%") {
-			mul = 100
-		}
-		sf := fmt.Sprintf("%%%dd", i2-i1)
-		if (i2 - i1) == 0 {
-			sf = "%d"
-		}
-		//log.Printf("   made int formatter '%s'", sf)
-		ff = sprintfFunc(sf, mul)
-		ctype = IntegerCell
+	nf := parseNumFmt(s)
+	ff := FmtFunc(nf.format)
+	if nf.locale != nil {
+		// e.g. [$-411] selects the locale for the whole format
+		ff = localeFmtFunc(nf.locale, ff)
 	}
+	return ff, nf.cellType()
+}
 
-	if strings.Contains(s, ",") {
-		ff = addCommas(ff)
-		//log.Printf("   added commas")
-	}
+// FormatOptions are the settings used by Format.
+type FormatOptions struct {
+	// Locale used to render values, if the code does not specify one.
+	// The default is en-US.
+	Locale *Locale
 
-	surReg := regexp.MustCompile(`[0#?,.]+`)
-	prepost := surReg.Split(s, 2)
-	if len(prepost) > 0 && len(prepost[0]) > 0 {
-		prepost[0] = nonEsc.ReplaceAllString(prepost[0], `$1`)
-		prepost[0] = squash.ReplaceAllString(prepost[0], ``)
-		prepost[0] = fixEsc.ReplaceAllString(prepost[0], `$1`)
-	}
-	if len(prepost) == 1 {
-		if prepost[0] == "@" {
-			return identFunc, StringCell
-		}
-		//log.Printf("   added static ('%s')", prepost[0])
-		return staticFmtFunc(prepost[0]), StringCell
-	}
-	if len(prepost[0]) > 0 || len(prepost[1]) > 0 {
-		prepost[1] = nonEsc.ReplaceAllString(prepost[1], `$1`)
-		prepost[1] = squash.ReplaceAllString(prepost[1], ``)
-		prepost[1] = fixEsc.ReplaceAllString(prepost[1], `$1`)
+	// Date1904 is true if date serial numbers count days from Jan 1, 1904.
+	Date1904 bool
+}
 
-		ff = surround(prepost[0], ff, prepost[1])
-		//log.Printf("   added surround ('%s' ... '%s')", prepost[0], prepost[1])
+// Format renders a value with an arbitrary Excel number format code, in
+// the same way as cells in a workbook. The opts may be nil for defaults.
+func Format(code string, value interface{}, opts *FormatOptions) string {
+	x := &Formatter{}
+	if opts != nil {
+		x.SetLocale(opts.Locale)
+		x.Mode1904(opts.Date1904)
 	}
-
-	return ff, ctype
+	ff, _ := makeFormatter(code)
+	return ff(x, value)
 }
 
 // Get the number format func to use for formatting values,
@@ -202,23 +139,37 @@ func (x *Formatter) Get(fmtID uint16) (FmtFunc, bool) {
 	if !ok {
 		fs, ok2 := x.customCodes[fmtID]
 		if ok2 {
-			return fs, true
+			ff, ok = fs, true
+		} else {
+			ff = identFunc
+		}
+	}
+	if x != nil && !x.overrides.empty() {
+		orig := ff
+		ff = func(x2 *Formatter, v interface{}) string {
+			if of, found := x.override(fmtID, v); found {
+				return of(x2, v)
+			}
+			return orig(x2, v)
 		}
-		ff = identFunc
 	}
-
 	return ff, ok
 }
 
-// Apply the specified number format to the value.
-// Returns false when fmtID is unknown.
+// Apply the specified number format to the value, or the override
+// registered for it. Returns false when fmtID is unknown.
 func (x *Formatter) Apply(fmtID uint16, val interface{}) (string, bool) {
+	if ff, found := x.override(fmtID, val); found {
+		_, ok := x.Code(fmtID)
+		return ff(x, val), ok
+	}
 	ff, ok := goFormatters[fmtID]
 	if !ok {
 		fs, ok2 := x.customCodes[fmtID]
 		if ok2 {
 			return fs(x, val), true
 		}
+		ff = identFunc
 	}
 	return ff(x, val), ok
 }
@@ -336,8 +287,8 @@ var builtInFormatTypes = map[uint16]CellType{
 	42: IntegerCell,
 	43: FloatCell,
 	44: FloatCell,
-	45: DateCell, // Durations?
-	46: DateCell,
+	45: DateCell,
+	46: DurationCell,
 	47: DateCell,
 	48: FloatCell,
 	49: StringCell,
@@ -376,7 +327,7 @@ var builtInFormatTypes = map[uint16]CellType{
 	76: DateCell,
 	77: DateCell,
 	78: DateCell,
-	79: DateCell,
+	79: DurationCell,
 	80: DateCell,
 	81: DateCell,
 }
@@ -51,8 +51,8 @@ var fracs = []testcaseFrac{
 	{-math.Pi, "-3 14093/99532", 5},
 	{-math.Pi, "-3 14093/99532", 6},
 
-	// TODO: fixed denominator fractions (e.g. "??/8" )
-	// TODO: string interpolations (e.g. '0 "pounds and " ??/100 "pence"')
+	// fixed denominators (e.g. "??/8") and string interpolations
+	// (e.g. '0 "pounds and " ??/100 "pence"') are tested in numfmt_test.go
 	// examples: https://bettersolutions.com/excel/formatting/number-tab-fractions.htm
 }
 
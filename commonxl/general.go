@@ -0,0 +1,107 @@
+package commonxl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// generalWidth is the number of characters shown by the General format in
+// a standard width column, not counting the minus sign of negative values.
+const generalWidth = 11
+
+// formatGeneral renders a number as Excel does with the General format:
+// up to 11 characters from the 15 significant digits Excel keeps, switching
+// to scientific notation (e.g. "1.23457E+11") when the value does not fit.
+func formatGeneral(v float64) string {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	w := generalWidth
+	if v < 0 {
+		w++
+	}
+
+	var s string
+	e := int(math.Floor(math.Log10(math.Abs(v))))
+	switch {
+	case e >= -4 && e <= -1:
+		s = toPrecision(v, 10+e)
+	case e >= -9 && e <= 9:
+		s = stripDecimal(toFixed(v, 12))
+		if len(s) > w {
+			s = toPrecision(v, 10)
+			if len(s) > w {
+				s = strconv.FormatFloat(v, 'e', 5, 64)
+			}
+		}
+	case e == 10:
+		s = toFixed(v, 0)
+		if len(s) > w {
+			s = toPrecision(v, 6)
+		}
+	default:
+		s = stripDecimal(toFixed(v, 11))
+		if len(s) > w || s == "0" || s == "-0" {
+			s = toPrecision(v, 6)
+		}
+	}
+	return stripDecimal(normalizeExp(strings.ToUpper(s)))
+}
+
+// toFixed formats v with dec decimal places, rounding half away from zero.
+func toFixed(v float64, dec int) string {
+	intStr, decStr := roundNumber(math.Abs(v), dec)
+	if intStr == "" {
+		intStr = "0"
+	}
+	if v < 0 {
+		intStr = "-" + intStr
+	}
+	if dec == 0 {
+		return intStr
+	}
+	return intStr + "." + decStr
+}
+
+// toPrecision formats v with p significant digits, using scientific
+// notation when the exponent is less than -6 or at least p.
+func toPrecision(v float64, p int) string {
+	s := strconv.FormatFloat(v, 'e', p-1, 64)
+	e, _ := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:])
+	if e < -6 || e >= p {
+		return s
+	}
+	return toFixed(v, p-1-e)
+}
+
+// stripDecimal removes trailing zeros after the decimal point, and the
+// decimal point if nothing remains after it.
+func stripDecimal(s string) string {
+	if strings.IndexByte(s, '.') < 0 || strings.ContainsAny(s, "Ee") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// normalizeExp strips trailing zeros from the mantissa of scientific
+// notation and ensures the exponent has at least 2 digits.
+func normalizeExp(s string) string {
+	i := strings.IndexByte(s, 'E')
+	if i < 0 {
+		return s
+	}
+	mant, exp := s[:i], s[i+1:]
+	if strings.IndexByte(mant, '.') >= 0 {
+		mant = strings.TrimSuffix(strings.TrimRight(mant, "0"), ".")
+	}
+	sign := "+"
+	if exp[0] == '+' || exp[0] == '-' {
+		sign, exp = exp[:1], exp[1:]
+	}
+	exp = strings.TrimLeft(exp, "0")
+	for len(exp) < 2 {
+		exp = "0" + exp
+	}
+	return mant + "E" + sign + exp
+}
@@ -0,0 +1,46 @@
+package commonxl
+
+import "testing"
+
+// values as displayed by Excel in a standard width column with the
+// General format.
+var generalGolden = []struct {
+	v float64
+	s string
+}{
+	{0, "0"},
+	{1, "1"},
+	{-1, "-1"},
+	{0.5, "0.5"},
+	{-0.5, "-0.5"},
+	{0.1 + 0.2, "0.3"},
+	{1.0 / 3, "0.333333333"},
+	{2.0 / 3, "0.666666667"},
+	{-2.0 / 3, "-0.666666667"},
+	{0.0001, "0.0001"},
+	{0.00001, "0.00001"},
+	{0.000012345678, "1.23457E-05"},
+	{1e-10, "1E-10"},
+	{123.456, "123.456"},
+	{123456.789012345, "123456.789"},
+	{100000.12345, "100000.1235"},
+	{1234567890, "1234567890"},
+	{12345678901, "12345678901"},
+	{-12345678901, "-12345678901"},
+	{99999999999.5, "1E+11"},
+	{123456789012, "1.23457E+11"},
+	{1234567890123456789, "1.23457E+18"},
+	{2.5e15, "2.5E+15"},
+	{1e100, "1E+100"},
+	{-1e100, "-1E+100"},
+	{44259, "44259"},
+	{44259.25, "44259.25"},
+}
+
+func TestFormatGeneral(t *testing.T) {
+	for _, c := range generalGolden {
+		if s := formatGeneral(c.v); s != c.s {
+			t.Errorf("formatGeneral(%v) = %q, expected %q", c.v, s, c.s)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package commonxl
+
+import "strings"
+
+// Hierarchy wraps a Sheet to reconstruct the row hierarchy expressed
+// by the indent level of a label column, e.g. in financial reports:
+//
+//	Revenue
+//	  Product
+//	    Region
+//
+// Each record reports its Depth, Parent row and the Path of labels leading
+// to it. Rows with an empty label are not part of the hierarchy.
+type Hierarchy struct {
+	*Sheet
+
+	// LabelCol is the 0-based column holding the indented labels.
+	LabelCol int
+
+	stack  []hierarchyNode
+	depth  int
+	parent int
+	path   []string
+}
+
+type hierarchyNode struct {
+	row    int
+	indent int
+	label  string
+}
+
+// NewHierarchy wraps the sheet, using the labels found in column labelCol.
+// Iteration starts from the beginning of the sheet.
+func NewHierarchy(s *Sheet, labelCol int) *Hierarchy {
+	s.CurRow = 0
+	return &Hierarchy{Sheet: s, LabelCol: labelCol, parent: -1}
+}
+
+// Next advances to the next record of content.
+// It MUST be called prior to any Scan().
+func (h *Hierarchy) Next() bool {
+	if !h.Sheet.Next() {
+		return false
+	}
+	row := h.Sheet.CurRow - 1
+	h.depth, h.parent, h.path = 0, -1, nil
+
+	label := strings.TrimSpace(h.Sheet.cellString(row, h.LabelCol))
+	if label == "" {
+		return true
+	}
+	indent := 0
+	if st := h.Sheet.cellStyle(h.Sheet.at(row, h.LabelCol)); st != nil {
+		indent = st.Alignment.Indent
+	}
+
+	// the parent is the closest preceding label with a smaller indent
+	for len(h.stack) > 0 && h.stack[len(h.stack)-1].indent >= indent {
+		h.stack = h.stack[:len(h.stack)-1]
+	}
+	h.depth = len(h.stack)
+	if h.depth > 0 {
+		h.parent = h.stack[h.depth-1].row
+	}
+	h.stack = append(h.stack, hierarchyNode{row: row, indent: indent, label: label})
+
+	h.path = make([]string, len(h.stack))
+	for i, n := range h.stack {
+		h.path[i] = n.label
+	}
+	return true
+}
+
+// Depth returns the hierarchy depth of the current record, 0 for top-level rows.
+func (h *Hierarchy) Depth() int {
+	return h.depth
+}
+
+// Parent returns the 0-based sheet row index of the current record's
+// parent, or -1 if it has none.
+func (h *Hierarchy) Parent() int {
+	return h.parent
+}
+
+// Path returns the labels from the top-level ancestor down to the current
+// record, e.g. ["Revenue", "Product", "Region"]. It is empty when the
+// current record has no label.
+func (h *Hierarchy) Path() []string {
+	return h.path
+}
@@ -0,0 +1,57 @@
+package commonxl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHierarchy(t *testing.T) {
+	styles := []*Style{{}, {Alignment: Alignment{Indent: 1}}, {Alignment: Alignment{Indent: 2}}}
+	s := &Sheet{Formatter: &Formatter{}, XFStyles: styles}
+	rows := []struct {
+		label  string
+		indent uint16
+	}{
+		{"Revenue", 0},
+		{"Product", 1},
+		{"North", 2},
+		{"South", 2},
+		{"Services", 1},
+		{"", 0},
+		{"Expenses", 0},
+		{"Payroll", 2},
+	}
+	s.Resize(len(rows), 2)
+	for i, r := range rows {
+		if r.label != "" {
+			s.Put(i, 0, r.label, 0)
+			s.SetXF(i, 0, r.indent)
+		}
+		s.Put(i, 1, float64(i), 0)
+	}
+
+	want := []struct {
+		depth, parent int
+		path          string
+	}{
+		{0, -1, "Revenue"},
+		{1, 0, "Revenue > Product"},
+		{2, 1, "Revenue > Product > North"},
+		{2, 1, "Revenue > Product > South"},
+		{1, 0, "Revenue > Services"},
+		{0, -1, ""},
+		{0, -1, "Expenses"},
+		{1, 6, "Expenses > Payroll"},
+	}
+	h := NewHierarchy(s, 0)
+	for i, w := range want {
+		if !h.Next() {
+			t.Fatalf("expected row %d", i)
+		}
+		path := strings.Join(h.Path(), " > ")
+		if h.Depth() != w.depth || h.Parent() != w.parent || path != w.path {
+			t.Errorf("row %d = (%d, %d, %q), expected (%d, %d, %q)", i,
+				h.Depth(), h.Parent(), path, w.depth, w.parent, w.path)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package commonxl
+
+// Hyperlink describes the target of a hyperlink cell.
+type Hyperlink struct {
+	URL      string // an external link, e.g. "https://example.com" or "mailto:..."
+	Location string // a location within the workbook, e.g. "Sheet2!A1"
+	File     string // a file path or UNC name, e.g. `\\server\share\report.xls`
+
+	Display string // the text displayed in the cell, if given
+	Tooltip string
+}
+
+// Target returns the destination of the hyperlink: the URL or file,
+// followed by "#" and the location if both are present.
+func (h *Hyperlink) Target() string {
+	t := h.URL
+	if t == "" {
+		t = h.File
+	}
+	if h.Location != "" {
+		if t == "" {
+			return h.Location
+		}
+		t += "#" + h.Location
+	}
+	return t
+}
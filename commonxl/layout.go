@@ -0,0 +1,159 @@
+package commonxl
+
+import "sort"
+
+// RowLayout describes the display properties of a sheet row.
+type RowLayout struct {
+	Hidden       bool
+	Height       float64 // in points, 0 if not specified
+	CustomHeight bool    // true if the height was set manually
+
+	OutlineLevel int  // grouping depth, 0-7
+	Collapsed    bool // true if the group below this row is collapsed
+}
+
+// ColLayout describes the display properties of a sheet column.
+type ColLayout struct {
+	Hidden bool
+	Width  float64 // in characters, 0 if not specified
+
+	OutlineLevel int  // grouping depth, 0-7
+	Collapsed    bool // true if the group next to this column is collapsed
+}
+
+// OutlineSettings describes how rows and columns are grouped in a sheet.
+// The zero value matches the Excel defaults.
+type OutlineSettings struct {
+	MaxRowLevel int // deepest row outline level in use
+	MaxColLevel int // deepest column outline level in use
+
+	SummaryAbove bool // summary rows are above their detail rows
+	SummaryLeft  bool // summary columns are left of their detail columns
+}
+
+// SetRowLayout records the layout for the given row.
+func (s *Sheet) SetRowLayout(row int, l RowLayout) {
+	if s.rowLayouts == nil {
+		s.rowLayouts = make(map[int]RowLayout)
+	}
+	s.rowLayouts[row] = l
+}
+
+// colRange is the layout of the columns first through last (inclusive).
+type colRange struct {
+	first, last int
+	layout      ColLayout
+}
+
+// SetColLayout records the layout for the columns first through last (inclusive).
+// Ranges are kept sorted and non-overlapping, as a single range often covers
+// all remaining columns of a sheet.
+func (s *Sheet) SetColLayout(first, last int, l ColLayout) {
+	if first < 0 {
+		first = 0
+	}
+	if last >= maxCols {
+		last = maxCols - 1
+	}
+	if first > last {
+		return
+	}
+
+	// trim or split any ranges overlapping the new one
+	res := make([]colRange, 0, len(s.colLayouts)+2)
+	for _, r := range s.colLayouts {
+		if r.last < first || r.first > last {
+			res = append(res, r)
+			continue
+		}
+		if r.first < first {
+			res = append(res, colRange{r.first, first - 1, r.layout})
+		}
+		if r.last > last {
+			res = append(res, colRange{last + 1, r.last, r.layout})
+		}
+	}
+	i := sort.Search(len(res), func(i int) bool { return res[i].first > last })
+	res = append(res, colRange{})
+	copy(res[i+1:], res[i:])
+	res[i] = colRange{first, last, l}
+	s.colLayouts = res
+}
+
+// RowLayout returns the layout for the given row.
+func (s *Sheet) RowLayout(row int) RowLayout {
+	return s.rowLayouts[row]
+}
+
+// ColLayout returns the layout for the given column.
+func (s *Sheet) ColLayout(col int) ColLayout {
+	i := sort.Search(len(s.colLayouts), func(i int) bool { return s.colLayouts[i].last >= col })
+	if i < len(s.colLayouts) && s.colLayouts[i].first <= col {
+		return s.colLayouts[i].layout
+	}
+	return ColLayout{}
+}
+
+// RowParents reconstructs the row hierarchy encoded by outline levels.
+// It returns the index of the parent (summary) row for every row in the
+// sheet, or -1 for top-level rows. Indexes refer to sheet rows and are not
+// affected by SkipHidden.
+func (s *Sheet) RowParents() []int {
+	n := s.height()
+	res := make([]int, n)
+	stack := make([]int, 0, 8)
+
+	// summary rows are usually below their details, so walk backwards
+	i, step := n-1, -1
+	if s.Outline.SummaryAbove {
+		i, step = 0, 1
+	}
+	for ; i >= 0 && i < n; i += step {
+		level := s.rowLayouts[i].OutlineLevel
+		for len(stack) > 0 && s.rowLayouts[stack[len(stack)-1]].OutlineLevel >= level {
+			stack = stack[:len(stack)-1]
+		}
+		res[i] = -1
+		if len(stack) > 0 {
+			res[i] = stack[len(stack)-1]
+		}
+		stack = append(stack, i)
+	}
+	return res
+}
+
+// RowIndex returns the 0-based index of the current record within the sheet.
+func (s *Sheet) RowIndex() int {
+	return s.CurRow - 1
+}
+
+// current returns the column indexes of the cells in the current
+// record, omitting hidden columns when SkipHidden is set.
+func (s *Sheet) current() []int {
+	n := s.rowWidth(s.CurRow - 1)
+	res := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if !s.SkipHidden || i < s.NumCols && !s.ColLayout(i).Hidden {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// width returns the number of columns in each record.
+func (s *Sheet) width() int {
+	if !s.SkipHidden || len(s.colLayouts) == 0 {
+		return s.NumCols
+	}
+	n := s.NumCols
+	for _, r := range s.colLayouts {
+		if r.layout.Hidden && r.first < s.NumCols {
+			last := r.last
+			if last >= s.NumCols {
+				last = s.NumCols - 1
+			}
+			n -= last - r.first + 1
+		}
+	}
+	return n
+}
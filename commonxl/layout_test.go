@@ -0,0 +1,119 @@
+package commonxl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSkipHidden(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(3, 3)
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			s.Put(r, c, string(rune('a'+r))+string(rune('1'+c)), 0)
+		}
+	}
+	s.SetRowLayout(1, RowLayout{Hidden: true, Height: 15})
+	s.SetColLayout(0, 0, ColLayout{Hidden: true, Width: 8.5})
+
+	if !s.RowLayout(1).Hidden || s.RowLayout(0).Hidden {
+		t.Errorf("unexpected row layouts %+v %+v", s.RowLayout(0), s.RowLayout(1))
+	}
+	if s.ColLayout(0).Width != 8.5 {
+		t.Errorf("unexpected column width %f", s.ColLayout(0).Width)
+	}
+
+	// hidden content is included by default
+	n := 0
+	for s.Next() {
+		n++
+		if len(s.Strings()) != 3 {
+			t.Errorf("expected 3 columns, got %d", len(s.Strings()))
+		}
+	}
+	if n != s.height() {
+		t.Errorf("expected %d rows, got %d", s.height(), n)
+	}
+
+	s.SkipHidden = true
+	s.CurRow = 0
+	var got []string
+	for s.Next() {
+		row := s.Strings()
+		if len(row) != 2 {
+			t.Fatalf("expected 2 columns, got %d", len(row))
+		}
+		got = append(got, strings.Join(row, ","))
+	}
+	want := "a2,a3|c2,c3|,"
+	if strings.Join(got, "|") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, "|"))
+	}
+}
+
+func TestRowParents(t *testing.T) {
+	// Revenue
+	//   Product
+	//   Services
+	//     Support
+	// Expenses
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(5, 1)
+	s.Outline.SummaryAbove = true
+	for i, level := range []int{0, 1, 1, 2, 0} {
+		s.SetRowLayout(i, RowLayout{OutlineLevel: level})
+	}
+	want := []int{-1, 0, 0, 2, -1, -1}
+	got := s.RowParents()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d parent = %d, expected %d", i, got[i], want[i])
+		}
+	}
+
+	// same structure with totals below the details
+	s.Outline.SummaryAbove = false
+	for i, level := range []int{1, 2, 1, 0, 0} {
+		s.SetRowLayout(i, RowLayout{OutlineLevel: level})
+	}
+	want = []int{3, 2, 3, -1, -1, -1}
+	got = s.RowParents()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d parent = %d, expected %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestColLayoutRanges(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(2, 8)
+	s.SetColLayout(4, maxCols-1, ColLayout{Hidden: true})
+	s.SetColLayout(1, 2, ColLayout{Width: 12})
+	s.SetColLayout(6, 6, ColLayout{Width: 20})
+	s.SetColLayout(2, 4, ColLayout{Width: 9})
+
+	if len(s.colLayouts) != 5 {
+		t.Errorf("expected 5 column ranges, got %d", len(s.colLayouts))
+	}
+	want := []ColLayout{
+		{}, {Width: 12}, {Width: 9}, {Width: 9}, {Width: 9},
+		{Hidden: true}, {Width: 20}, {Hidden: true},
+	}
+	for c, l := range want {
+		if s.ColLayout(c) != l {
+			t.Errorf("column %d: expected %+v, got %+v", c, l, s.ColLayout(c))
+		}
+	}
+	if !s.ColLayout(maxCols - 1).Hidden {
+		t.Error("expected the last column to be hidden")
+	}
+	if s.ColLayout(maxCols).Hidden {
+		t.Error("expected no layout past the last column")
+	}
+
+	s.SkipHidden = true
+	if s.width() != 6 {
+		t.Errorf("expected 6 visible columns, got %d", s.width())
+	}
+}
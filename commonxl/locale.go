@@ -0,0 +1,264 @@
+package commonxl
+
+import (
+	"strings"
+	"time"
+)
+
+// Locale describes the regional conventions used to render values:
+// number separators, currency symbol, and month and day names.
+type Locale struct {
+	// Name is the IETF language tag, e.g. "en-US".
+	Name string
+	// LCID is the Windows locale identifier, e.g. 0x409.
+	LCID uint32
+
+	Decimal   string
+	Thousands string
+	Currency  string
+
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string
+	ShortDays   [7]string
+	AM, PM      string
+
+	// Eras lists the start of each era in ascending order, for
+	// calendars which count years from the start of an era.
+	Eras []Era
+
+	// eraYears is true when year codes (y) are rendered as the era year,
+	// as requested by the calendar type of a format's LCID.
+	eraYears bool
+}
+
+// Era is a named period of an era-based calendar.
+type Era struct {
+	Start time.Time
+	// Name is the full era name, e.g. "令和". Short and Initial are
+	// the abbreviated forms, e.g. "令" and "R".
+	Name, Short, Initial string
+}
+
+// era returns the era containing t and the year within that era.
+// Locales without eras use the Gregorian year.
+func (l *Locale) era(t time.Time) (*Era, int) {
+	for i := len(l.Eras) - 1; i >= 0; i-- {
+		e := &l.Eras[i]
+		if !t.Before(e.Start) {
+			return e, t.Year() - e.Start.Year() + 1
+		}
+	}
+	return nil, t.Year()
+}
+
+// localizeNumber replaces the decimal point of a formatted number.
+func (l *Locale) localizeNumber(s string) string {
+	if l.Decimal == "." {
+		return s
+	}
+	return strings.Replace(s, ".", l.Decimal, 1)
+}
+
+// Calendar types found in bits 16-23 of a format code LCID.
+const (
+	calendarJapanese = 0x03
+	calendarTaiwan   = 0x04
+	calendarThai     = 0x07
+)
+
+// LookupLCID returns the Locale for a Windows locale identifier as found
+// in format codes such as "[$-411]" or "[$-30411]". The calendar type in
+// bits 16-23 selects era-based years for Japanese, Taiwanese and Thai formats.
+func LookupLCID(lcid uint32) (*Locale, bool) {
+	cal := (lcid >> 16) & 0xFF
+	l, ok := localesByLCID[lcid&0xFFFF]
+	if !ok {
+		if cal == 0 {
+			return nil, false
+		}
+		l = LocaleEnUS
+	}
+	var eras []Era
+	switch cal {
+	case calendarJapanese:
+		eras = japaneseEras
+	case calendarTaiwan:
+		eras = taiwanEras
+	case calendarThai:
+		eras = thaiEras
+	default:
+		return l, true
+	}
+	l2 := *l
+	l2.Eras = eras
+	l2.eraYears = true
+	return &l2, true
+}
+
+// LookupLocale returns the Locale for a language tag such as "de-DE" or "ja_JP".
+func LookupLocale(name string) (*Locale, bool) {
+	name = strings.ReplaceAll(name, "_", "-")
+	for _, l := range localesByLCID {
+		if strings.EqualFold(l.Name, name) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+var (
+	japaneseEras = []Era{
+		{time.Date(1868, 9, 8, 0, 0, 0, 0, time.UTC), "明治", "明", "M"},
+		{time.Date(1912, 7, 30, 0, 0, 0, 0, time.UTC), "大正", "大", "T"},
+		{time.Date(1926, 12, 25, 0, 0, 0, 0, time.UTC), "昭和", "昭", "S"},
+		{time.Date(1989, 1, 8, 0, 0, 0, 0, time.UTC), "平成", "平", "H"},
+		{time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC), "令和", "令", "R"},
+	}
+	taiwanEras = []Era{
+		{time.Date(1912, 1, 1, 0, 0, 0, 0, time.UTC), "中華民國", "民國", "民國"},
+	}
+	thaiEras = []Era{
+		{time.Date(-542, 1, 1, 0, 0, 0, 0, time.UTC), "พุทธศักราช", "พ.ศ.", "พ.ศ."},
+	}
+)
+
+var (
+	englishMonths      = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	englishShortMonths = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	englishDays        = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	englishShortDays   = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+	cjkMonths       = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+	chineseMonths   = [12]string{"一月", "二月", "三月", "四月", "五月", "六月", "七月", "八月", "九月", "十月", "十一月", "十二月"}
+	chineseDays     = [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+	spanishShortDay = [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"}
+)
+
+// LocaleEnUS is the default locale used when none is set.
+var LocaleEnUS = &Locale{
+	Name: "en-US", LCID: 0x409,
+	Decimal: ".", Thousands: ",", Currency: "$",
+	Months: englishMonths, ShortMonths: englishShortMonths,
+	Days: englishDays, ShortDays: englishShortDays,
+	AM: "AM", PM: "PM",
+}
+
+var localesByLCID = map[uint32]*Locale{
+	0x409: LocaleEnUS,
+	0x809: {
+		Name: "en-GB", LCID: 0x809,
+		Decimal: ".", Thousands: ",", Currency: "£",
+		Months: englishMonths, ShortMonths: englishShortMonths,
+		Days: englishDays, ShortDays: englishShortDays,
+		AM: "AM", PM: "PM",
+	},
+	0x407: {
+		Name: "de-DE", LCID: 0x407,
+		Decimal: ",", Thousands: ".", Currency: "€",
+		Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mrz", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		AM:          "AM", PM: "PM",
+	},
+	0x40C: {
+		Name: "fr-FR", LCID: 0x40C,
+		Decimal: ",", Thousands: "\u00a0", Currency: "€",
+		Months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		AM:          "AM", PM: "PM",
+	},
+	0x410: {
+		Name: "it-IT", LCID: 0x410,
+		Decimal: ",", Thousands: ".", Currency: "€",
+		Months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		ShortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		Days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		AM:          "AM", PM: "PM",
+	},
+	0xC0A: {
+		Name: "es-ES", LCID: 0xC0A,
+		Decimal: ",", Thousands: ".", Currency: "€",
+		Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"},
+		Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:   spanishShortDay,
+		AM:          "a. m.", PM: "p. m.",
+	},
+	0x413: {
+		Name: "nl-NL", LCID: 0x413,
+		Decimal: ",", Thousands: ".", Currency: "€",
+		Months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		ShortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		Days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		ShortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		AM:          "a.m.", PM: "p.m.",
+	},
+	0x416: {
+		Name: "pt-BR", LCID: 0x416,
+		Decimal: ",", Thousands: ".", Currency: "R$",
+		Months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		ShortMonths: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		Days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		ShortDays:   [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+		AM:          "AM", PM: "PM",
+	},
+	0x419: {
+		Name: "ru-RU", LCID: 0x419,
+		Decimal: ",", Thousands: "\u00a0", Currency: "₽",
+		Months:      [12]string{"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь", "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"},
+		ShortMonths: [12]string{"янв", "фев", "мар", "апр", "май", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"},
+		Days:        [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		ShortDays:   [7]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"},
+		AM:          "AM", PM: "PM",
+	},
+	0x411: {
+		Name: "ja-JP", LCID: 0x411,
+		Decimal: ".", Thousands: ",", Currency: "¥",
+		Months: cjkMonths, ShortMonths: cjkMonths,
+		Days:      [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		ShortDays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+		AM:        "午前", PM: "午後",
+		Eras: japaneseEras,
+	},
+	0x404: {
+		Name: "zh-TW", LCID: 0x404,
+		Decimal: ".", Thousands: ",", Currency: "NT$",
+		Months: chineseMonths, ShortMonths: cjkMonths,
+		Days:      chineseDays,
+		ShortDays: [7]string{"週日", "週一", "週二", "週三", "週四", "週五", "週六"},
+		AM:        "上午", PM: "下午",
+		Eras: taiwanEras,
+	},
+	0x804: {
+		Name: "zh-CN", LCID: 0x804,
+		Decimal: ".", Thousands: ",", Currency: "¥",
+		Months: chineseMonths, ShortMonths: cjkMonths,
+		Days:      chineseDays,
+		ShortDays: [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+		AM:        "上午", PM: "下午",
+	},
+	0x412: {
+		Name: "ko-KR", LCID: 0x412,
+		Decimal: ".", Thousands: ",", Currency: "₩",
+		Months:      [12]string{"1월", "2월", "3월", "4월", "5월", "6월", "7월", "8월", "9월", "10월", "11월", "12월"},
+		ShortMonths: [12]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"},
+		Days:        [7]string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"},
+		ShortDays:   [7]string{"일", "월", "화", "수", "목", "금", "토"},
+		AM:          "오전", PM: "오후",
+	},
+	0x41E: {
+		Name: "th-TH", LCID: 0x41E,
+		Decimal: ".", Thousands: ",", Currency: "฿",
+		Months:      [12]string{"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน", "กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม"},
+		ShortMonths: [12]string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.", "ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."},
+		Days:        [7]string{"อาทิตย์", "จันทร์", "อังคาร", "พุธ", "พฤหัสบดี", "ศุกร์", "เสาร์"},
+		ShortDays:   [7]string{"อา.", "จ.", "อ.", "พ.", "พฤ.", "ศ.", "ส."},
+		AM:          "AM", PM: "PM",
+		Eras: thaiEras,
+	},
+}
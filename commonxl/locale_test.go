@@ -0,0 +1,91 @@
+package commonxl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleFormats(t *testing.T) {
+	d := time.Date(2021, 3, 4, 15, 4, 5, 0, time.UTC)
+	for code, expect := range map[string]string{
+		`[$-411]ggge"年"m"月"d"日"`:      "令和3年3月4日",
+		`[$-411]ge.m.d`:               "R3.3.4",
+		`[$-30411]yy/mm/dd`:           "03/03/04",
+		`[$-404]e/m/d`:                "110/3/4",
+		`[$-407]d. mmmm yyyy`:         "4. März 2021",
+		`[$-40C]dddd d mmm`:           "jeudi 4 mars",
+		`[$-411]h:mm AM/PM`:           "3:04 午後",
+		`[$-409]mmmmm-yy`:             "M-21",
+		`[$-411]yyyy"年"m"月"d"日" aaaa`: "2021年3月4日 木曜日",
+		`[$-411]m/d(aaa)`:             "3/4(木)",
+		`[$-804]yyyy"年"m"月"d"日" aaaa`: "2021年3月4日 星期四",
+		`[$-804]aaa`:                  "周四",
+		`[$-409]aaaa`:                 "Thursday",
+	} {
+		ff, _ := makeFormatter(code)
+		if s := ff(&Formatter{}, d); s != expect {
+			t.Errorf("%s: expected %s, got %s", code, expect, s)
+		}
+	}
+
+	for code, expect := range map[string]string{
+		`#,##0.00 [$€-407]`:  "1.234,50 €",
+		`[$$-409]#,##0.00`:   "$1,234.50",
+		`[$-419]0.00`:        "1234,50",
+		`#,##0.00 [$CHF]`:    "1,234.50 CHF",
+		`0.0 [$-41E]"units"`: "1234.5 units",
+	} {
+		ff, _ := makeFormatter(code)
+		if s := ff(&Formatter{}, 1234.5); s != expect {
+			t.Errorf("%s: expected %s, got %s", code, expect, s)
+		}
+	}
+}
+
+func TestFormatterLocale(t *testing.T) {
+	de, ok := LookupLocale("de_DE")
+	if !ok {
+		t.Fatal("de-DE locale not found")
+	}
+	x := &Formatter{}
+	x.SetLocale(de)
+
+	d := time.Date(2021, 3, 4, 15, 4, 5, 0, time.UTC)
+	if s, _ := x.Apply(15, d); s != "4-Mrz-21" {
+		t.Errorf("expected 4-Mrz-21, got %s", s)
+	}
+	if s, _ := x.Apply(4, 1234.5); s != "1.234,50" {
+		t.Errorf("expected 1.234,50, got %s", s)
+	}
+	if s, _ := x.Apply(0, 1.5); s != "1,5" {
+		t.Errorf("expected 1,5, got %s", s)
+	}
+
+	// format codes with an LCID ignore the formatter locale
+	x.Add(164, `[$-409]d-mmm-yy`)
+	if s, _ := x.Apply(164, d); s != "4-Mar-21" {
+		t.Errorf("expected 4-Mar-21, got %s", s)
+	}
+
+	// zh-cn and th built-ins
+	x.SetLocale(nil)
+	if s, _ := x.Apply(35, d); s != "下午 3时04分05秒" {
+		t.Errorf("expected 下午 3时04分05秒, got %s", s)
+	}
+	if s, _ := x.Apply(81, d); s != "4/3/64" {
+		t.Errorf("expected 4/3/64, got %s", s)
+	}
+}
+
+func TestLookupLCID(t *testing.T) {
+	if _, ok := LookupLCID(0x7F); ok {
+		t.Error("unexpected locale for invariant LCID")
+	}
+	l, ok := LookupLCID(0x70409)
+	if !ok || l.Name != "en-US" || len(l.Eras) != 1 {
+		t.Errorf("expected en-US with thai calendar, got %+v", l)
+	}
+	if LocaleEnUS.Eras != nil {
+		t.Error("calendar must not modify the base locale")
+	}
+}
@@ -0,0 +1,852 @@
+package commonxl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// numFmt is a parsed Excel number format code, made of up to four sections:
+//
+//	positive;negative;zero;text
+//
+// Sections may instead be chosen by conditions such as [>=100].
+type numFmt struct {
+	sections []*fmtSection
+	text     *fmtSection // the text section, if any
+	textOnly bool        // only a text section, numbers use General
+	locale   *Locale     // from the first LCID in the code, if any
+	currency string      // from the first [$sym-LCID] in the code, if any
+	hasCond  bool
+}
+
+type sectionKind int
+
+const (
+	sectionLiteral sectionKind = iota
+	sectionNumber
+	sectionDate
+	sectionElapsed
+	sectionText
+)
+
+// fmtSection is a single section of a number format code.
+type fmtSection struct {
+	kind  sectionKind
+	color string
+	cond  *fmtCondition
+
+	toks    []fmtToken  // number, text and literal sections
+	dates   []dateToken // date sections
+	elapsed string      // elapsed time sections, e.g. "[h]:mm"
+
+	// number layout
+	intDigits, decDigits, expDigits []byte
+	numDigits, denDigits            []byte
+	fixedDen                        int
+	hasDecimal, hasFraction         bool
+	hasExp, expPlus                 bool
+	thousands, general              bool
+	percent, fill                   bool
+	scale                           float64
+}
+
+// fmtCondition is a section condition such as [>=100].
+type fmtCondition struct {
+	op  string
+	val float64
+}
+
+func (c *fmtCondition) match(v float64) bool {
+	switch c.op {
+	case "<":
+		return v < c.val
+	case "<=":
+		return v <= c.val
+	case ">":
+		return v > c.val
+	case ">=":
+		return v >= c.val
+	case "<>":
+		return v != c.val
+	}
+	return v == c.val
+}
+
+type tokenKind int
+
+const (
+	tokLiteral tokenKind = iota
+	tokDigit             // 0, # or ?
+	tokDecimal           // the decimal point
+	tokExp               // E+ or E-
+	tokSlash             // fraction bar
+	tokComma             // thousands separator or scaling
+	tokText              // @
+	tokGeneral           // General
+)
+
+type digitZone int
+
+const (
+	zoneInt digitZone = iota
+	zoneDec
+	zoneExp
+	zoneNum
+	zoneDen
+)
+
+// fmtToken is a single element of a number, text or literal section.
+type fmtToken struct {
+	kind tokenKind
+	lit  string
+	ch   byte // placeholder character for tokDigit
+	zone digitZone
+}
+
+var colorNames = map[string]bool{
+	"black": true, "blue": true, "cyan": true, "green": true,
+	"magenta": true, "red": true, "white": true, "yellow": true,
+}
+
+// splitSections splits a format code on the unquoted semicolons.
+func splitSections(code string) []string {
+	var res []string
+	start := 0
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '"':
+			if end := strings.IndexByte(code[i+1:], '"'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(code)
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			if end := strings.IndexByte(code[i:], ']'); end >= 0 {
+				i += end
+			}
+		case ';':
+			res = append(res, code[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, code[start:])
+}
+
+// parseNumFmt parses a format code into its sections.
+func parseNumFmt(code string) *numFmt {
+	nf := &numFmt{}
+	parts := splitSections(code)
+	if len(parts) > 4 {
+		parts = parts[:4]
+	}
+	for i, p := range parts {
+		sec := nf.parseSection(p)
+		if sec.cond != nil {
+			nf.hasCond = true
+		}
+		if sec.kind == sectionText || i == 3 {
+			nf.text = sec
+			continue
+		}
+		nf.sections = append(nf.sections, sec)
+	}
+	if len(nf.sections) == 0 {
+		nf.textOnly = true
+		nf.sections = append(nf.sections, &fmtSection{kind: sectionNumber, general: true,
+			scale: 1, toks: []fmtToken{{kind: tokGeneral}}})
+	}
+	return nf
+}
+
+// parseSection tokenizes a single section. Bracketed codes set the color,
+// condition and locale, or mark elapsed time units.
+func (nf *numFmt) parseSection(s string) *fmtSection {
+	sec := &fmtSection{scale: 1}
+	var toks []fmtToken
+	var plain strings.Builder // the section with all bracketed codes removed
+	var elapsed strings.Builder
+	isDate, isElapsed := false, false
+
+	lit := func(s string) {
+		if n := len(toks); n > 0 && toks[n-1].kind == tokLiteral {
+			toks[n-1].lit += s
+			return
+		}
+		toks = append(toks, fmtToken{kind: tokLiteral, lit: s})
+	}
+	hasDigits := func() bool {
+		for _, t := range toks {
+			if t.kind == tokDigit {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			lit(s[i+1 : i+1+end])
+			plain.WriteString(s[i:min(i+end+2, len(s))])
+			elapsed.WriteString(s[i:min(i+end+2, len(s))])
+			i += end + 2
+			continue
+		case (c == '\\' || c == '_' || c == '*') && i+1 < len(s):
+			_, sz := utf8.DecodeRuneInString(s[i+1:])
+			if c == '\\' {
+				lit(s[i+1 : i+1+sz])
+			} else if c == '_' {
+				lit(" ")
+			} else {
+				sec.fill = true
+			}
+			plain.WriteString(s[i : i+1+sz])
+			elapsed.WriteString(s[i : i+1+sz])
+			i += 1 + sz
+			continue
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				lit(s[i:])
+				i = len(s)
+				continue
+			}
+			b := s[i+1 : i+end]
+			i += end + 1
+			lb := strings.ToLower(b)
+			switch {
+			case strings.HasPrefix(b, "$"):
+				sym, lcid := b[1:], ""
+				if j := strings.LastIndexByte(sym, '-'); j >= 0 {
+					sym, lcid = sym[:j], sym[j+1:]
+				}
+				if id, err := strconv.ParseUint(lcid, 16, 32); err == nil && nf.locale == nil {
+					nf.locale, _ = LookupLCID(uint32(id))
+				}
+				if sym != "" && nf.currency == "" {
+					nf.currency = sym
+				}
+				if sym != "" {
+					lit(sym)
+					plain.WriteString(`"` + sym + `"`)
+					elapsed.WriteString(`"` + sym + `"`)
+				}
+			case isElapsedUnit(lb):
+				isElapsed = true
+				elapsed.WriteString("[" + b + "]")
+			case len(b) > 0 && strings.IndexByte("<>=", b[0]) >= 0:
+				op := strings.TrimRight(b[:min(2, len(b))], "0123456789.-+ ")
+				v, err := strconv.ParseFloat(strings.TrimSpace(b[len(op):]), 64)
+				if err == nil {
+					sec.cond = &fmtCondition{op: op, val: v}
+				}
+			case colorNames[lb] || strings.HasPrefix(lb, "color"):
+				sec.color = b
+			}
+			continue
+		case strings.EqualFold(s[i:min(i+7, len(s))], "general"):
+			toks = append(toks, fmtToken{kind: tokGeneral})
+			sec.general = true
+			i += 7
+			continue
+		case c == '0' || c == '#' || c == '?':
+			toks = append(toks, fmtToken{kind: tokDigit, ch: c})
+		case c == '.':
+			toks = append(toks, fmtToken{kind: tokDecimal})
+		case c == ',':
+			toks = append(toks, fmtToken{kind: tokComma})
+		case c == '%':
+			toks = append(toks, fmtToken{kind: tokLiteral, lit: "%"})
+			sec.scale *= 100
+			sec.percent = true
+		case c == '@':
+			toks = append(toks, fmtToken{kind: tokText})
+		case c == '/':
+			toks = append(toks, fmtToken{kind: tokSlash, lit: "/"})
+		case (c == 'E' || c == 'e') && i+1 < len(s) && (s[i+1] == '+' || s[i+1] == '-') && hasDigits():
+			toks = append(toks, fmtToken{kind: tokExp, lit: s[i : i+2]})
+			plain.WriteString(s[i : i+2])
+			elapsed.WriteString(s[i : i+2])
+			i += 2
+			continue
+		case strings.HasPrefix(strings.ToUpper(s[i:]), "AM/PM") || strings.HasPrefix(strings.ToUpper(s[i:]), "A/P") ||
+			strings.HasPrefix(s[i:], "上午/下午"):
+			isDate = true
+			lit(s[i : i+1])
+		case strings.IndexByte("ymdhsgeb", c|0x20) >= 0 && (c|0x20) >= 'a',
+			strings.HasPrefix(strings.ToLower(s[i:]), "aaa"):
+			isDate = true
+			lit(s[i : i+1])
+		default:
+			_, sz := utf8.DecodeRuneInString(s[i:])
+			lit(s[i : i+sz])
+			plain.WriteString(s[i : i+sz])
+			elapsed.WriteString(s[i : i+sz])
+			i += sz
+			continue
+		}
+		plain.WriteByte(c)
+		elapsed.WriteByte(c)
+		i++
+	}
+
+	switch {
+	case isElapsed:
+		sec.kind = sectionElapsed
+		sec.elapsed = elapsed.String()
+	case isDate:
+		sec.kind = sectionDate
+		sec.dates = parseDateFormat(plain.String())
+	default:
+		sec.toks = toks
+		sec.layout()
+	}
+	return sec
+}
+
+// layout assigns the digit placeholders of a number section to the
+// integer, decimal, exponent and fraction parts of the number, and
+// interprets commas as thousands separators or scaling.
+func (sec *fmtSection) layout() {
+	toks := sec.toks
+	zone := zoneInt
+	for i := 0; i < len(toks); i++ {
+		t := &toks[i]
+		switch t.kind {
+		case tokText:
+			sec.kind = sectionText
+			return
+		case tokDigit:
+			t.zone = zone
+		case tokDecimal:
+			if zone != zoneInt || sec.hasFraction {
+				t.kind, t.lit = tokLiteral, "."
+				continue
+			}
+			sec.hasDecimal = true
+			zone = zoneDec
+		case tokExp:
+			if zone == zoneExp {
+				t.kind = tokLiteral
+				continue
+			}
+			sec.hasExp = true
+			sec.expPlus = t.lit[1] == '+'
+			zone = zoneExp
+		case tokSlash:
+			// the placeholders just before the slash are the numerator
+			j := i - 1
+			for j >= 0 && toks[j].kind == tokDigit && toks[j].zone == zoneInt {
+				j--
+			}
+			if zone != zoneInt || j == i-1 || sec.hasDecimal {
+				t.kind, t.lit = tokLiteral, "/"
+				continue
+			}
+			for k := j + 1; k < i; k++ {
+				toks[k].zone = zoneNum
+			}
+			sec.hasFraction = true
+			zone = zoneDen
+			sec.fixedDenominator(i)
+		case tokComma:
+			// commas between integer placeholders are thousands separators,
+			// commas following the last placeholder scale by 1000 each.
+			switch {
+			case zone == zoneInt && i+1 < len(toks) && toks[i+1].kind == tokDigit && sec.hasDigitBefore(i):
+				sec.thousands = true
+			case i > 0 && (toks[i-1].kind == tokDigit || toks[i-1].kind == tokComma) && !sec.hasDigitAfter(i):
+				sec.scale /= 1000
+			default:
+				t.kind, t.lit = tokLiteral, ","
+			}
+		}
+	}
+
+	if !sec.hasDigits() && !sec.general {
+		sec.kind = sectionLiteral
+		return
+	}
+	sec.kind = sectionNumber
+
+	// a number without integer placeholders shows the integer part
+	// anyway, as if there were a # before the decimal point (or exponent)
+	if !sec.hasFraction {
+		hasInt := false
+		insertAt := -1
+		for i, t := range toks {
+			if t.kind == tokDigit && t.zone == zoneInt {
+				hasInt = true
+			}
+			if insertAt < 0 && (t.kind == tokDecimal || t.kind == tokExp) {
+				insertAt = i
+			}
+		}
+		if !hasInt && insertAt >= 0 {
+			toks = append(toks[:insertAt], append([]fmtToken{{kind: tokDigit, ch: '#'}}, toks[insertAt:]...)...)
+		}
+	}
+	sec.toks = toks
+
+	for _, t := range toks {
+		if t.kind != tokDigit {
+			continue
+		}
+		switch t.zone {
+		case zoneInt:
+			sec.intDigits = append(sec.intDigits, t.ch)
+		case zoneDec:
+			sec.decDigits = append(sec.decDigits, t.ch)
+		case zoneExp:
+			sec.expDigits = append(sec.expDigits, t.ch)
+		case zoneNum:
+			sec.numDigits = append(sec.numDigits, t.ch)
+		case zoneDen:
+			sec.denDigits = append(sec.denDigits, t.ch)
+		}
+	}
+}
+
+// fixedDenominator reads a literal denominator (e.g. ??/16) after the
+// fraction bar at toks[i]. Its digits are moved into the bar's text.
+func (sec *fmtSection) fixedDenominator(i int) {
+	toks := sec.toks
+	digits := ""
+	for k := i + 1; k < len(toks); k++ {
+		t := &toks[k]
+		if t.kind == tokDigit && t.ch == '0' && digits != "" {
+			digits += "0"
+			t.kind = tokLiteral
+			continue
+		}
+		if t.kind != tokLiteral || digits != "" || t.lit == "" || t.lit[0] < '1' || t.lit[0] > '9' {
+			break
+		}
+		n := 1
+		for n < len(t.lit) && t.lit[n] >= '0' && t.lit[n] <= '9' {
+			n++
+		}
+		digits, t.lit = t.lit[:n], t.lit[n:]
+		if t.lit != "" {
+			break
+		}
+	}
+	if digits != "" {
+		sec.fixedDen, _ = strconv.Atoi(digits)
+		toks[i].lit = "/" + digits
+	}
+}
+
+func (sec *fmtSection) hasDigits() bool {
+	for _, t := range sec.toks {
+		if t.kind == tokDigit {
+			return true
+		}
+	}
+	return false
+}
+
+func (sec *fmtSection) hasDigitBefore(i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if sec.toks[j].kind == tokDigit {
+			return true
+		}
+	}
+	return false
+}
+
+func (sec *fmtSection) hasDigitAfter(i int) bool {
+	for j := i + 1; j < len(sec.toks); j++ {
+		if sec.toks[j].kind == tokDigit {
+			return true
+		}
+	}
+	return false
+}
+
+// isElapsedUnit returns true for the elapsed time units of a format code,
+// e.g. "h" or "mm" in [h]:[mm].
+func isElapsedUnit(b string) bool {
+	if b == "" || strings.IndexByte("hms", b[0]) < 0 {
+		return false
+	}
+	return strings.Count(b, b[:1]) == len(b)
+}
+
+// cellType returns the type of values described by the format.
+func (nf *numFmt) cellType() CellType {
+	if nf.textOnly {
+		return StringCell
+	}
+	sec := nf.sections[0]
+	switch sec.kind {
+	case sectionElapsed:
+		return DurationCell
+	case sectionDate:
+		// including times of day, only [h], [m] and [s] are elapsed
+		return DateCell
+	case sectionNumber:
+		if sec.hasDecimal || sec.hasExp || sec.hasFraction || sec.general || sec.scale != 1 {
+			return FloatCell
+		}
+		return IntegerCell
+	}
+	return StringCell
+}
+
+// format renders a value using the format.
+func (nf *numFmt) format(x *Formatter, v interface{}) string {
+	switch v.(type) {
+	case string:
+		// text is never rendered as a number, e.g. "007"
+		if nf.text != nil {
+			return nf.text.format(x, v, false)
+		}
+		return identFunc(x, v)
+	case bool:
+		if nf.sections[0].general {
+			return identFunc(x, v)
+		}
+	}
+	val, ok := convertToFloat64(v)
+	if !ok {
+		if _, isTime := x.toTime(v); isTime {
+			for _, sec := range nf.sections {
+				if sec.kind == sectionDate || sec.kind == sectionElapsed {
+					return sec.format(x, v, false)
+				}
+			}
+		}
+		if nf.text != nil {
+			return nf.text.format(x, v, false)
+		}
+		return identFunc(x, v)
+	}
+
+	sec, neg := nf.choose(val)
+	if sec == nil {
+		return identFunc(x, v)
+	}
+	if sec.kind == sectionDate || sec.kind == sectionElapsed {
+		return sec.format(x, v, false)
+	}
+	return sec.format(x, val, neg)
+}
+
+// choose selects the section for a numeric value, and whether a
+// minus sign must be shown.
+func (nf *numFmt) choose(v float64) (*fmtSection, bool) {
+	secs := nf.sections
+	if nf.hasCond {
+		// a section without a condition is for all other values
+		for _, sec := range secs {
+			if sec.cond == nil || sec.cond.match(v) {
+				return sec, v < 0
+			}
+		}
+		return nil, false
+	}
+	switch {
+	case len(secs) >= 3 && v == 0:
+		return secs[2], false
+	case len(secs) >= 2 && v < 0:
+		return secs[1], false
+	}
+	return secs[0], v < 0
+}
+
+// format renders a value with a single section. Numeric values are
+// rendered as their absolute value, with a leading minus if neg is set.
+func (sec *fmtSection) format(x *Formatter, v interface{}, neg bool) string {
+	loc := x.Locale()
+	switch sec.kind {
+	case sectionDate:
+		t, ok := x.toTime(v)
+		if !ok {
+			return identFunc(x, v)
+		}
+		return formatDate(loc, sec.dates, t)
+	case sectionElapsed:
+		return elapsedFmtFunc(sec.elapsed)(x, v)
+	case sectionText:
+		var sb strings.Builder
+		for _, t := range sec.toks {
+			if t.kind == tokText {
+				sb.WriteString(identFunc(x, v))
+			} else {
+				sb.WriteString(t.lit)
+			}
+		}
+		return sb.String()
+	case sectionLiteral:
+		var sb strings.Builder
+		if neg {
+			sb.WriteByte('-')
+		}
+		for _, t := range sec.toks {
+			sb.WriteString(t.lit)
+		}
+		return sb.String()
+	}
+
+	val, _ := convertToFloat64(v)
+	return sec.formatNumber(loc, x, math.Abs(val)*sec.scale, neg)
+}
+
+// formatNumber renders a non-negative value with a number section.
+func (sec *fmtSection) formatNumber(loc *Locale, x *Formatter, val float64, neg bool) string {
+	var intStr, decStr, expStr, numStr, denStr string
+	expNeg := false
+	hideFraction := false
+
+	switch {
+	case sec.hasFraction:
+		whole := 0.0
+		frac := val
+		if len(sec.intDigits) > 0 {
+			whole, frac = math.Modf(val)
+		}
+		var num, den int
+		if sec.fixedDen > 0 {
+			den = sec.fixedDen
+			num = int(math.Round(frac * float64(den)))
+		} else {
+			nn := len(sec.numDigits)
+			if len(sec.intDigits) == 0 {
+				nn = 15
+			}
+			num, den = DecimalToFraction(frac, nn, len(sec.denDigits))
+		}
+		if len(sec.intDigits) > 0 && num == den && num != 0 {
+			whole++
+			num = 0
+		}
+		if num == 0 && len(sec.intDigits) > 0 {
+			hideFraction = true
+			if whole == 0 {
+				intStr = "0"
+			}
+		}
+		if whole != 0 {
+			intStr = strconv.FormatFloat(whole, 'f', 0, 64)
+		}
+		numStr = strconv.Itoa(num)
+		denStr = strconv.Itoa(den)
+		if num == 0 && whole == 0 && len(sec.intDigits) == 0 {
+			numStr = "0"
+		}
+
+	case sec.hasExp:
+		// the exponent is a multiple of the number of integer placeholders,
+		// e.g. ##0.0E+0 is engineering notation
+		nInt := len(sec.intDigits)
+		exp := 0
+		if val != 0 {
+			e := int(math.Floor(math.Log10(val)))
+			exp = int(math.Floor(float64(e)/float64(nInt))) * nInt
+		}
+		intStr, decStr = roundNumber(val/math.Pow10(exp), len(sec.decDigits))
+		if len(intStr) > nInt {
+			exp += nInt
+			intStr, decStr = roundNumber(val/math.Pow10(exp), len(sec.decDigits))
+		}
+		if exp < 0 {
+			expNeg = true
+			exp = -exp
+		}
+		if exp != 0 {
+			expStr = strconv.Itoa(exp)
+		}
+
+	default:
+		intStr, decStr = roundNumber(val, len(sec.decDigits))
+	}
+
+	if neg && !sec.general && strings.Trim(intStr+decStr+numStr, "0") == "" {
+		// values which round to zero show no sign
+		neg = false
+	}
+
+	sep := ""
+	if sec.thousands {
+		sep = loc.Thousands
+	}
+	intOut := fillRight(sec.intDigits, intStr, sep)
+	decOut := fillLeft(sec.decDigits, decStr, true)
+	expOut := fillRight(sec.expDigits, expStr, "")
+	numOut := fillRight(sec.numDigits, numStr, "")
+	denOut := fillLeft(sec.denDigits, denStr, false)
+
+	var sb strings.Builder
+	if neg {
+		sb.WriteByte('-')
+	}
+	var ii, di, ei, ni, dni int
+	for _, t := range sec.toks {
+		switch t.kind {
+		case tokLiteral:
+			sb.WriteString(t.lit)
+		case tokGeneral:
+			sb.WriteString(identFunc(x, val))
+		case tokDecimal:
+			sb.WriteString(loc.Decimal)
+		case tokExp:
+			sb.WriteByte(t.lit[0])
+			if expNeg {
+				sb.WriteByte('-')
+			} else if sec.expPlus {
+				sb.WriteByte('+')
+			}
+		case tokSlash:
+			if hideFraction {
+				sb.WriteString(strings.Repeat(" ", len(t.lit)))
+			} else {
+				sb.WriteString(t.lit)
+			}
+		case tokDigit:
+			switch t.zone {
+			case zoneInt:
+				sb.WriteString(intOut[ii])
+				ii++
+			case zoneDec:
+				sb.WriteString(decOut[di])
+				di++
+			case zoneExp:
+				sb.WriteString(expOut[ei])
+				ei++
+			case zoneNum:
+				if hideFraction {
+					sb.WriteByte(' ')
+				} else {
+					sb.WriteString(numOut[ni])
+				}
+				ni++
+			case zoneDen:
+				if hideFraction {
+					sb.WriteByte(' ')
+				} else {
+					sb.WriteString(denOut[dni])
+				}
+				dni++
+			}
+		}
+	}
+	return sb.String()
+}
+
+// roundNumber rounds a non-negative value to dec decimal places, half
+// away from zero on the 15 significant digits which Excel keeps. The
+// integer digits are returned without leading zeros (so zero is empty).
+func roundNumber(val float64, dec int) (string, string) {
+	if math.IsInf(val, 0) || math.IsNaN(val) {
+		return strconv.FormatFloat(val, 'f', -1, 64), ""
+	}
+	s := strconv.FormatFloat(val, 'e', 14, 64)
+	digits := s[:1] + s[2:16]
+	exp, _ := strconv.Atoi(s[17:])
+
+	point := exp + 1 // number of integer digits
+	if point < 0 {
+		digits = strings.Repeat("0", -point) + digits
+		point = 0
+	}
+	n := point + dec
+	if len(digits) < n+1 {
+		digits += strings.Repeat("0", n+1-len(digits))
+	}
+	res := []byte(digits[:n])
+	if digits[n] >= '5' {
+		i := n - 1
+		for ; i >= 0 && res[i] == '9'; i-- {
+			res[i] = '0'
+		}
+		if i >= 0 {
+			res[i]++
+		} else {
+			res = append([]byte{'1'}, res...)
+			point++
+		}
+	}
+	return strings.TrimLeft(string(res[:point]), "0"), string(res[point:])
+}
+
+// fillRight places digits into placeholders aligned on the right, as for
+// integers. Extra digits go to the first placeholder, and missing digits
+// become "0", " " or nothing for the 0, ? and # placeholders.
+func fillRight(ph []byte, digits string, sep string) []string {
+	res := make([]string, len(ph))
+	n := len(ph)
+	for k := 0; k < n; k++ {
+		r := n - 1 - k
+		var sb strings.Builder
+		if k == 0 {
+			for j := len(digits) - 1; j >= n; j-- {
+				sb.WriteByte(digits[len(digits)-1-j])
+				if sep != "" && j%3 == 0 {
+					sb.WriteString(sep)
+				}
+			}
+		}
+		shown := true
+		if r < len(digits) {
+			sb.WriteByte(digits[len(digits)-1-r])
+		} else {
+			switch ph[k] {
+			case '0':
+				sb.WriteByte('0')
+			case '?':
+				sb.WriteByte(' ')
+				shown = false
+			default:
+				shown = false
+			}
+		}
+		if shown && sep != "" && r > 0 && r%3 == 0 {
+			sb.WriteString(sep)
+		}
+		res[k] = sb.String()
+	}
+	return res
+}
+
+// fillLeft places digits into placeholders aligned on the left, as for
+// decimals. When trim is set, trailing zeros are treated as missing.
+// Extra digits go to the last placeholder.
+func fillLeft(ph []byte, digits string, trim bool) []string {
+	if trim {
+		digits = strings.TrimRight(digits, "0")
+	}
+	res := make([]string, len(ph))
+	for k := range ph {
+		if k < len(digits) {
+			res[k] = digits[k : k+1]
+			if k == len(ph)-1 {
+				res[k] = digits[k:]
+			}
+			continue
+		}
+		switch ph[k] {
+		case '0':
+			res[k] = "0"
+		case '?':
+			res[k] = " "
+		}
+	}
+	return res
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
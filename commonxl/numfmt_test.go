@@ -0,0 +1,217 @@
+package commonxl
+
+import "testing"
+
+// Expected outputs as rendered by Excel (en-US).
+var numFmtCases = []struct {
+	code   string
+	v      interface{}
+	expect string
+}{
+	// digit placeholders
+	{`0`, 1.5, "2"},
+	{`0`, -1.5, "-2"},
+	{`00000`, 123.0, "00123"},
+	{`####.#`, 1234.59, "1234.6"},
+	{`#.000`, 8.9, "8.900"},
+	{`0.#`, 0.631, "0.6"},
+	{`#.0#`, 12.0, "12.0"},
+	{`#.0#`, 1234.568, "1234.57"},
+	{`#.##`, 0.0, "."},
+	{`#`, 0.0, ""},
+	{`.00`, 12.5, "12.50"},
+	{`???.???`, 44.398, " 44.398"},
+	{`???.???`, 102.65, "102.65 "},
+	{`???.???`, 2.8, "  2.8  "},
+	{`0.00`, 2.675, "2.68"},
+	{`0.00`, -0.001, "0.00"},
+	{`000-00-0000`, 123456789.0, "123-45-6789"},
+	{`00-00`, 12345.0, "123-45"},
+
+	// thousands separators and scaling
+	{`#,###`, 12000.0, "12,000"},
+	{`#,##0`, -1234.5, "-1,235"},
+	{`#,##0.00`, 1234567.891, "1,234,567.89"},
+	{`0,000`, 5.0, "0,005"},
+	{`#,`, 12000.0, "12"},
+	{`0.0,,`, 12200000.0, "12.2"},
+	{`#,##0.0,,"M"`, 12345678.0, "12.3M"},
+	{`0.000,`, 1234567.0, "1234.567"},
+	{`#,##0,"K"`, 1500.0, "2K"},
+
+	// percentages
+	{`0%`, 0.5, "50%"},
+	{`0.00%`, 0.123456, "12.35%"},
+	{`0.0\%`, 5.0, "5.0%"},
+
+	// scientific
+	{`0.00E+00`, 12200000.0, "1.22E+07"},
+	{`0.00E+00`, 0.000123, "1.23E-04"},
+	{`0.00E+00`, 0.0, "0.00E+00"},
+	{`0.00E+00`, -1234.0, "-1.23E+03"},
+	{`##0.0E+0`, 12200000.0, "12.2E+6"},
+	{`##0.0E+0`, 1234.0, "1.2E+3"},
+	{`##0.00E+00`, 0.00012345, "123.45E-06"},
+	{`0E+0`, 5.0, "5E+0"},
+	{`0.00E-00`, 1234.0, "1.23E03"},
+	{`0.00E-00`, 0.01234, "1.23E-02"},
+	{`0.0E+00`, 9.99, "1.0E+01"},
+	{`00.00E+00`, 1234.5, "12.35E+02"},
+
+	// fractions
+	{`# ?/?`, 5.25, "5 1/4"},
+	{`# ?/?`, 0.5, " 1/2"},
+	{`# ?/?`, 5.0, "5    "},
+	{`# ?/?`, 0.0, "0    "},
+	{`# ??/??`, 5.25, "5  1/4 "},
+	{`# ??/??`, 3.14159, "3  1/7 "},
+	{`# ???/???`, 3.14159, "3  16/113"},
+	{`?/?`, 1.5, "3/2"},
+	{`# ?/8`, 1.3, "1 2/8"},
+	{`# ??/16`, 0.5, "  8/16"},
+	{`# ??/100`, 3.14, "3 14/100"},
+	{`0 "pounds and " ??/100 "pence"`, 5.25, "5 pounds and  25/100 pence"},
+	{`# ?/?`, -5.25, "-5 1/4"},
+	{`# ?/4`, 2.9, "3    "},
+
+	// literals, padding and fills
+	{`"Sales "0.00`, 12.5, "Sales 12.50"},
+	{`#,##0.00 "kg"`, 1234.5, "1,234.50 kg"},
+	{`\$0.00`, 1.5, "$1.50"},
+	{`$#,##0.00_);($#,##0.00)`, 1234.5, "$1,234.50 "},
+	{`$#,##0.00_);($#,##0.00)`, -1234.5, "($1,234.50)"},
+	{`0.00_);[Red](0.00)`, -5.0, "(5.00)"},
+	{`_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`, 1234.0, " 1,234 "},
+	{`_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`, -1234.0, " (1,234)"},
+	{`_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`, 0.0, " - "},
+	{`_(* #,##0_);_(* \(#,##0\);_(* "-"_);_(@_)`, "abc", " abc "},
+	{`**0`, 5.0, "5"},
+	{`"$"-0`, 5.0, "$-5"},
+
+	// sections
+	{`0;-0;"zero"`, 0.0, "zero"},
+	{`0;-0;;@`, 0.0, ""},
+	{`0;(0)`, -5.0, "(5)"},
+	{`0;;`, -5.0, ""},
+	{`0.00;-0.00;0;"text: "@`, "abc", "text: abc"},
+	{`@" units"`, "abc", "abc units"},
+	{`@`, 1.5, "1.5"},
+	{`;;;`, 5.0, ""},
+	{`"yes";"yes";"no"`, true, "yes"},
+	{`"yes";"yes";"no"`, false, "no"},
+	{`"x"`, -5.0, "-x"},
+
+	// colors and conditions
+	{`[Red]0.00`, 1.0, "1.00"},
+	{`[Color10]0`, 3.0, "3"},
+	{`[Red][<=100]0;[Blue][>100]0.0`, 50.0, "50"},
+	{`[Red][<=100]0;[Blue][>100]0.0`, 150.0, "150.0"},
+	{`[>=100]"big";"small"`, 150.0, "big"},
+	{`[>=100]"big";"small"`, 5.0, "small"},
+	{`[<=9999999]###-####;(###) ###-####`, 5551234.0, "555-1234"},
+	{`[<=9999999]###-####;(###) ###-####`, 8005551234.0, "(800) 555-1234"},
+	{`[<1000]0;[<1000000]0.0,"K";0.0,,"M"`, 512.0, "512"},
+	{`[<1000]0;[<1000000]0.0,"K";0.0,,"M"`, 51200.0, "51.2K"},
+	{`[<1000]0;[<1000000]0.0,"K";0.0,,"M"`, 5120000.0, "5.1M"},
+	{`[=1]"one";[=2]"two";0`, 2.0, "two"},
+	{`[=1]"one";[=2]"two";0`, 3.0, "3"},
+
+	// general
+	{`General`, 1.5, "1.5"},
+	{`General" items"`, 5.0, "5 items"},
+	{`General;-General`, -5.0, "-5"},
+	{`General;(General)`, -5.0, "(5)"},
+
+	// dates and times
+	{`yyyy-mm-dd`, 44259.0, "2021-03-04"},
+	{`m/d/yy h:mm`, 44259.5, "3/4/21 12:00"},
+	{`dddd, mmmm d, yyyy`, 44259.0, "Thursday, March 4, 2021"},
+	{`ddd mmm dd`, 44259.0, "Thu Mar 04"},
+	{`mmmmm`, 44259.0, "M"},
+	{`h:mm AM/PM`, 0.75, "6:00 PM"},
+	{`hh:mm a/p`, 0.25, "06:00 a"},
+	{`h:mm:ss`, 0.999999, "0:00:00"},
+	{`mm:ss.000`, 1.5 / 86400, "00:01.500"},
+	{`h "hours" m "minutes"`, 0.5 + 5.0/1440, "12 hours 5 minutes"},
+	{`yyyy-mm-dd;@`, "n/a", "n/a"},
+	{`[$-409]mmm d`, 44259.0, "Mar 4"},
+	{`[h]:mm:ss`, 1.5, "36:00:00"},
+	{`[Red][mm]:ss`, 1.0 / 24, "60:00"},
+}
+
+func TestNumFmt(t *testing.T) {
+	x := &Formatter{}
+	for _, c := range numFmtCases {
+		ff, _ := makeFormatter(c.code)
+		if s := ff(x, c.v); s != c.expect {
+			t.Errorf("%s: %v expected %q, got %q", c.code, c.v, c.expect, s)
+		}
+	}
+}
+
+func TestNumFmtTypes(t *testing.T) {
+	for code, expect := range map[string]CellType{
+		`0`:          IntegerCell,
+		`#,##0`:      IntegerCell,
+		`0.00`:       FloatCell,
+		`0%`:         FloatCell,
+		`0.00E+00`:   FloatCell,
+		`# ?/?`:      FloatCell,
+		`#,##0,"K"`:  FloatCell,
+		`@`:          StringCell,
+		`"yes";"no"`: StringCell,
+		`mmm-yy`:     DateCell,
+		`mmmm`:       DateCell,
+		`h:mm`:       DateCell,
+		`[h]:mm`:     DurationCell,
+	} {
+		if _, ct := makeFormatter(code); ct != expect {
+			t.Errorf("%s: expected %s, got %s", code, expect, ct)
+		}
+	}
+}
+
+func TestNumFmtClasses(t *testing.T) {
+	for code, expect := range map[string]string{
+		`General`:                   "general",
+		`0.00`:                      "number",
+		`#,##0`:                     "number",
+		`0.0%`:                      "percent",
+		`0.00E+00`:                  "scientific",
+		`# ??/??`:                   "fraction",
+		`"$"#,##0.00`:               "currency:$",
+		`\$#,##0;[Red]\-\$#,##0`:    "currency:$",
+		`#,##0.00 [$€-407]`:         "currency:€",
+		`[$USD] #,##0.00`:           "currency:USD",
+		`"EUR "#,##0.00`:            "currency:EUR",
+		`[$zł-415]#,##0.00`:         "currency:zł",
+		`_(* #,##0_);_(* \(#,##0\)`: "accounting",
+		`_("£"* #,##0.00_)`:         "accounting:£",
+		`[$-409]mmm-yy`:             "",
+		`@`:                         "",
+		`#,##0,"K"`:                 "number",
+	} {
+		if c := parseNumFmt(code).classify(); c.String() != expect {
+			t.Errorf("%s: expected %q, got %q", code, expect, c)
+		}
+	}
+
+	fx := &Formatter{}
+	fx.Add(164, `[$€-407]#,##0.00`)
+	s := &Sheet{Formatter: fx, NumberCategories: true}
+	s.Resize(1, 4)
+	s.Put(0, 0, 0.25, 9)
+	s.Put(0, 1, 12.5, 164)
+	s.Put(0, 2, 12.0, 44)
+	s.Put(0, 3, 12.5, 2)
+	s.Next()
+	types := s.Types()
+	for i, x := range []string{"percent", "currency:€", "accounting:$", "float"} {
+		if types[i] != x {
+			t.Errorf("column %d: expected %q, got %q", i, x, types[i])
+		}
+	}
+	if c := s.Classes()[1]; c.Category != CurrencyNumber || c.Currency != "€" {
+		t.Errorf("unexpected class %+v", c)
+	}
+}
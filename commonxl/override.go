@@ -0,0 +1,116 @@
+package commonxl
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+// overrides are the custom renderings registered on a Formatter.
+type overrides struct {
+	ids   map[uint16]FmtFunc
+	codes []codeOverride
+	types map[CellType]FmtFunc
+}
+
+type codeOverride struct {
+	pattern *regexp.Regexp
+	ff      FmtFunc
+}
+
+// OverrideID renders values with the number format fmtID using ff, in
+// place of the format. Built-in formats may be overridden.
+func (x *Formatter) OverrideID(fmtID uint16, ff FmtFunc) {
+	if x.overrides.ids == nil {
+		x.overrides.ids = make(map[uint16]FmtFunc)
+	}
+	x.overrides.ids[fmtID] = ff
+}
+
+// OverrideCode renders values using ff when the code of their number
+// format matches the pattern. Patterns are tried in the order added.
+func (x *Formatter) OverrideCode(pattern *regexp.Regexp, ff FmtFunc) {
+	x.overrides.codes = append(x.overrides.codes, codeOverride{pattern, ff})
+}
+
+// OverrideType renders all values of the given type using ff, e.g. to
+// render every DateCell as RFC3339, regardless of their number format.
+func (x *Formatter) OverrideType(t CellType, ff FmtFunc) {
+	if x.overrides.types == nil {
+		x.overrides.types = make(map[CellType]FmtFunc)
+	}
+	x.overrides.types[t] = ff
+}
+
+// SetOverrides registers the format overrides requested in the options.
+func (x *Formatter) SetOverrides(o *grate.Options) {
+	for fmtID, fn := range o.FormatIDs {
+		x.OverrideID(fmtID, wrapFormatFunc(fn))
+	}
+	for _, c := range o.FormatCodes {
+		x.OverrideCode(c.Pattern, wrapFormatFunc(c.Func))
+	}
+	for name, fn := range o.FormatTypes {
+		for t := BlankCell; t <= DurationCell; t++ {
+			if t.String() == name {
+				x.OverrideType(t, wrapFormatFunc(fn))
+			}
+		}
+	}
+}
+
+func wrapFormatFunc(fn grate.FormatFunc) FmtFunc {
+	return func(x *Formatter, v interface{}) string {
+		return fn(v)
+	}
+}
+
+// override returns the custom rendering for the value with the
+// number format fmtID, or false if there is none.
+func (x *Formatter) override(fmtID uint16, v interface{}) (FmtFunc, bool) {
+	if x == nil {
+		return nil, false
+	}
+	if ff, ok := x.overrides.ids[fmtID]; ok {
+		return ff, true
+	}
+	if len(x.overrides.codes) > 0 {
+		code, _ := x.Code(fmtID)
+		for _, c := range x.overrides.codes {
+			if c.pattern.MatchString(code) {
+				return c.ff, true
+			}
+		}
+	}
+	if len(x.overrides.types) > 0 {
+		ff, ok := x.overrides.types[valueType(v)]
+		return ff, ok
+	}
+	return nil, false
+}
+
+func (o *overrides) empty() bool {
+	return len(o.ids) == 0 && len(o.codes) == 0 && len(o.types) == 0
+}
+
+// valueType returns the CellType of a value stored in a cell.
+func valueType(v interface{}) CellType {
+	switch v.(type) {
+	case nil:
+		return BlankCell
+	case bool:
+		return BooleanCell
+	case int64:
+		return IntegerCell
+	case float64:
+		return FloatCell
+	case time.Time:
+		return DateCell
+	case time.Duration:
+		return DurationCell
+	case grate.CellError:
+		return ErrorCell
+	}
+	return StringCell
+}
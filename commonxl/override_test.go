@@ -0,0 +1,50 @@
+package commonxl
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+func TestOverrides(t *testing.T) {
+	fx := &Formatter{}
+	fx.Add(164, `"€"#,##0.00`)
+	fx.Add(165, `0.000`)
+	fx.SetOverrides(grate.NewOptions(
+		grate.WithFormatType("date", func(v interface{}) string {
+			return v.(time.Time).Format(time.RFC3339)
+		}),
+		grate.WithFormatCode(regexp.MustCompile(`[$€£¥]`), func(v interface{}) string {
+			return strconv.FormatFloat(v.(float64), 'f', 2, 64)
+		}),
+		grate.WithFormatID(1, func(v interface{}) string {
+			return "#" + strconv.FormatInt(v.(int64), 10)
+		}),
+	))
+
+	s := &Sheet{Formatter: fx}
+	s.Resize(1, 6)
+	s.Put(0, 0, 44259.5, 22)
+	s.Put(0, 1, 1234.5, 164)
+	s.Put(0, 2, 1234.5, 44)
+	s.Put(0, 3, 42.0, 1)
+	s.Put(0, 4, 1.5, 165)
+	s.Put(0, 5, 44259.0, 14)
+	s.Next()
+
+	expect := []string{"2021-03-04T12:00:00Z", "1234.50", "1234.50", "#42", "1.500", "2021-03-04T00:00:00Z"}
+	strs := s.Strings()
+	for i, x := range expect {
+		if strs[i] != x {
+			t.Errorf("column %d: expected %q, got %q", i, x, strs[i])
+		}
+	}
+
+	ff, _ := fx.Get(14)
+	if s := ff(fx, fx.ConvertToDate(44259)); s != "2021-03-04T00:00:00Z" {
+		t.Errorf("unexpected override from Get %q", s)
+	}
+}
@@ -0,0 +1,70 @@
+package commonxl
+
+import "time"
+
+// PivotTable describes the layout of a pivot table on a sheet.
+type PivotTable struct {
+	Name     string
+	Cache    string // name of the source pivot cache
+	Location Range  // the cells covered by the pivot table body
+
+	// RowFields, ColFields and PageFields are the names of the
+	// cache fields placed on each axis, in display order.
+	RowFields  []string
+	ColFields  []string
+	PageFields []string
+
+	DataFields []PivotDataField
+}
+
+// PivotDataField describes a summarized value of a pivot table.
+type PivotDataField struct {
+	Name     string // the displayed caption, e.g. "Sum of Sales"
+	Field    string // the name of the summarized cache field
+	Function string // e.g. "sum", "count", "average", "max"
+}
+
+// AddPivotTable records a pivot table located on the sheet.
+func (s *Sheet) AddPivotTable(pt PivotTable) {
+	s.pivotTables = append(s.pivotTables, pt)
+}
+
+// PivotTables returns the pivot tables located on the sheet.
+func (s *Sheet) PivotTables() []PivotTable {
+	return s.pivotTables
+}
+
+// PivotCache holds the source records of one or more pivot tables.
+type PivotCache struct {
+	Fields  []string
+	Records [][]interface{} // values are nil, bool, int64, float64, string, time.Time or grate.CellError
+}
+
+// Sheet returns the pivot cache as a Sheet, with the field names in
+// the first row followed by one row for each record.
+func (c *PivotCache) Sheet(f *Formatter) *Sheet {
+	res := &Sheet{Formatter: f}
+	res.Resize(len(c.Records)+1, len(c.Fields))
+	res.truncate(res.NumRows) // no trailing blank row
+	for i, name := range c.Fields {
+		res.Put(0, i, name, 0)
+	}
+	for i, rec := range c.Records {
+		for j, v := range rec {
+			switch x := v.(type) {
+			case nil:
+				continue
+			case time.Time:
+				// dates without a time use the short date format
+				if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 {
+					res.Put(i+1, j, x, 14)
+				} else {
+					res.Put(i+1, j, x, 22)
+				}
+			default:
+				res.Put(i+1, j, v, 0)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+package commonxl
+
+import "strconv"
+
+// Range is a rectangular block of cells, using 0-based inclusive indexes.
+type Range struct {
+	FirstRow, FirstCol int
+	LastRow, LastCol   int
+}
+
+// Contains returns true if the cell location is within the range.
+func (r Range) Contains(row, col int) bool {
+	return row >= r.FirstRow && row <= r.LastRow &&
+		col >= r.FirstCol && col <= r.LastCol
+}
+
+// String returns the range in A1 notation, e.g. "B2:D10" or "A1" for
+// a single cell.
+func (r Range) String() string {
+	first := ColumnName(r.FirstCol) + strconv.Itoa(r.FirstRow+1)
+	if r.FirstRow == r.LastRow && r.FirstCol == r.LastCol {
+		return first
+	}
+	return first + ":" + ColumnName(r.LastCol) + strconv.Itoa(r.LastRow+1)
+}
+
+// ColumnName returns the column letters for a 0-based column index,
+// e.g. 0 = "A", 26 = "AA".
+func ColumnName(col int) string {
+	var buf [8]byte
+	i := len(buf)
+	for col >= 0 && i > 0 {
+		i--
+		buf[i] = byte('A' + col%26)
+		col = col/26 - 1
+	}
+	return string(buf[i:])
+}
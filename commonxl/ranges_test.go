@@ -0,0 +1,17 @@
+package commonxl
+
+import "testing"
+
+func TestColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA", 16383: "XFD"}
+	for col, want := range cases {
+		if got := ColumnName(col); got != want {
+			t.Errorf("ColumnName(%d) = %s, expected %s", col, got, want)
+		}
+	}
+
+	r := Range{FirstRow: 1, FirstCol: 1, LastRow: 9, LastCol: 3}
+	if r.String() != "B2:D10" || !r.Contains(5, 2) || r.Contains(0, 2) {
+		t.Errorf("unexpected range behavior for %s", r)
+	}
+}
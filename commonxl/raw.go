@@ -0,0 +1,53 @@
+package commonxl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+// RawString renders the canonical value of a cell without applying a number
+// format: the shortest representation of numbers which round-trips, dates
+// in ISO-8601 form, durations as serial numbers (fractional days) and
+// booleans as TRUE or FALSE.
+func RawString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		if x {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return rawFloat(x)
+	case string:
+		return x
+	case time.Time:
+		// serial dates are only precise to about a millisecond
+		x = x.Round(time.Millisecond)
+		if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 && x.Nanosecond() == 0 {
+			return x.Format("2006-01-02")
+		}
+		return x.Format("2006-01-02T15:04:05.999")
+	case time.Duration:
+		return rawFloat(float64(x) / float64(24*time.Hour))
+	case grate.CellError:
+		return x.Text
+	}
+	return fmt.Sprint(v)
+}
+
+// rawFloat uses plain decimal notation unless the exponent is very large or
+// very small, where scientific notation is much shorter.
+func rawFloat(v float64) string {
+	if a := math.Abs(v); a != 0 && (a < 1e-6 || a >= 1e21) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,41 @@
+package commonxl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+func TestRawValues(t *testing.T) {
+	tenth := 0.1
+	for _, mode := range []grate.ValueMode{grate.ValuesDisplayed, grate.ValuesRaw} {
+		s := &Sheet{Formatter: &Formatter{}, Values: mode}
+		s.Resize(1, 6)
+		s.Put(0, 0, 0.123456, 9)
+		s.Put(0, 1, 44259.5, 22)
+		s.Put(0, 2, 1234.5678, 1)
+		s.Put(0, 3, true, 0)
+		s.Put(0, 4, tenth+0.2, 0)
+		s.Put(0, 5, 1.5/24, 21)
+		s.Next()
+
+		expect := []string{"12%", "3/4/21 12:00", "1235", "TRUE", "0.3", "1:30:00"}
+		if mode == grate.ValuesRaw {
+			expect = []string{"0.123456", "2021-03-04T12:00:00", "1234.5678", "TRUE", "0.30000000000000004", "1899-12-30T01:30:00"}
+		}
+		strs := s.Strings()
+		for i, x := range expect {
+			if strs[i] != x {
+				t.Errorf("mode %d column %d: expected %q, got %q", mode, i, x, strs[i])
+			}
+		}
+	}
+
+	if s := RawString(time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)); s != "2021-03-04" {
+		t.Errorf("unexpected date %q", s)
+	}
+	if s := RawString(1e-10); s != "1e-10" {
+		t.Errorf("unexpected float %q", s)
+	}
+}
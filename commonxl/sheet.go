@@ -3,6 +3,7 @@ package commonxl
 import (
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/pbnjay/grate"
@@ -13,43 +14,177 @@ type Sheet struct {
 	Formatter *Formatter
 	NumRows   int
 	NumCols   int
-	Rows      [][]Cell
+
+	// XFStyles are the resolved styles for each XF index in the workbook.
+	XFStyles []*Style
+
+	// SkipHidden omits hidden rows and columns during iteration.
+	SkipHidden bool
+
+	// Hyperlinks selects how hyperlink cells are rendered by Strings().
+	Hyperlinks grate.HyperlinkMode
+
+	// Values selects whether Strings() renders displayed or raw values.
+	Values grate.ValueMode
+
+	// NumberCategories reports the semantic category of numeric cells
+	// from Types(), e.g. "percent" or "currency:$".
+	NumberCategories bool
+
+	// MaxCells limits the number of non-blank cells, if non-zero. Cells
+	// beyond the limit are dropped, and Err returns an ErrLimitExceeded.
+	MaxCells int
+
+	// Outline describes the row and column grouping of the sheet.
+	Outline OutlineSettings
+
+	// View describes the frozen panes and selection of the sheet.
+	View SheetView
+
+	// AutoFilter is the filtered range of the sheet, or nil if none.
+	AutoFilter *AutoFilter
+
+	rows  [][]cellData
+	strs  strTable
+	links map[uint64]*Hyperlink
+
+	// sparse storage for large sheets with few values
+	sparse     map[uint64]cellData
+	sparseRows int
+	filled     int // number of non-blank cells
+	allocRows  int // number of dense rows allocated
+
+	err error
+
+	rowLayouts  map[int]RowLayout
+	colLayouts  []colRange // sorted by column
+	validations []Validation
+	condFormats []ConditionalFormat
+	pivotTables []PivotTable
 
 	CurRow int
 }
 
 // Resize the sheet for the number of rows and cols given.
-// Newly added cells default to blank.
+// Newly added cells default to blank. Rows are allocated on their first
+// write, and sheets which turn out to hold few values are stored sparsely.
 func (s *Sheet) Resize(rows, cols int) {
-	for i := range s.Rows {
+	if rows <= 0 {
+		rows = 1
+	} else if rows > maxRows {
+		rows = maxRows
+	}
+	if cols <= 0 {
+		cols = 1
+	} else if cols > maxCols {
+		cols = maxCols
+	}
+	// int64, as the largest sheets overflow 32-bit ints
+	if cells := int64(s.allocRows) * int64(cols); s.sparse == nil && cells > sparseMinCells && int64(s.filled)*8 < cells {
+		// a value far to the right of the filled cells
+		s.makeSparse()
+	}
+	if s.sparse != nil {
+		s.CurRow = 0
+		s.NumRows = rows
+		s.NumCols = cols
+		if rows >= s.sparseRows {
+			s.sparseRows = rows + 1
+		}
+		return
+	}
+
+	for i := range s.rows {
 		if i > rows {
 			break
 		}
-		n := cols - len(s.Rows[i])
-		if n <= 0 {
+		n := cols - len(s.rows[i])
+		if n <= 0 || s.rows[i] == nil {
 			continue
 		}
-		s.Rows[i] = append(s.Rows[i], make([]Cell, n)...)
+		s.rows[i] = append(s.rows[i], make([]cellData, n)...)
 	}
 
-	if rows <= 0 {
-		rows = 1
-	}
-	if cols <= 0 {
-		cols = 1
-	}
 	s.CurRow = 0
 	s.NumRows = rows
 	s.NumCols = cols
 
-	for rows >= len(s.Rows) {
-		s.Rows = append(s.Rows, make([]Cell, cols))
+	if rows >= len(s.rows) {
+		s.rows = append(s.rows, make([][]cellData, rows+1-len(s.rows))...)
+	}
+}
+
+// cellKey is the key of a cell location within the sparse maps of a Sheet.
+func cellKey(row, col int) uint64 {
+	return uint64(row)<<32 | uint64(uint32(col))
+}
+
+// Cell returns the cell at the location given, blank if it is out of bounds.
+func (s *Sheet) Cell(row, col int) Cell {
+	c := s.at(row, col)
+	cell := c.cell(s.strs)
+	cell.SetFormatNumber(c.fmtNo)
+	if h := s.links[cellKey(row, col)]; h != nil {
+		cell.grow(4)
+		cell[3] = h
 	}
+	cell.SetXF(c.xf)
+	return cell
 }
 
 // Put the value at the cell location given.
 func (s *Sheet) Put(row, col int, value interface{}, fmtNum uint16) {
-	//log.Println(row, col, value, fmtNum)
+	if spec, ok := value.(string); ok {
+		s.PutString(row, col, spec, fmtNum)
+		return
+	}
+	if s.prepare(row, col) {
+		s.store(row, col, packValue(&s.strs, value), fmtNum)
+	}
+}
+
+// PutFloat puts a float64 value at the cell location given. Unlike Put,
+// the value is not boxed in an interface{}, so it does not allocate.
+func (s *Sheet) PutFloat(row, col int, value float64, fmtNum uint16) {
+	if s.prepare(row, col) {
+		s.store(row, col, floatData(value), fmtNum)
+	}
+}
+
+// PutInt puts an int64 value at the cell location given, without boxing it.
+func (s *Sheet) PutInt(row, col int, value int64, fmtNum uint16) {
+	if s.prepare(row, col) {
+		s.store(row, col, intData(value), fmtNum)
+	}
+}
+
+// PutString puts a string value at the cell location given, without boxing it.
+func (s *Sheet) PutString(row, col int, value string, fmtNum uint16) {
+	if !s.prepare(row, col) {
+		return
+	}
+	if value == grate.EndRowMerged || value == grate.EndColumnMerged || value == grate.ContinueRowMerged || value == grate.ContinueColumnMerged {
+		c := s.strs.str(value)
+		c.kind = StaticCell
+		s.set(row, col, c)
+		return
+	}
+	var c cellData
+	if value != "" {
+		c = s.strs.str(value)
+	}
+	s.store(row, col, c, fmtNum)
+}
+
+// prepare checks a cell location for Put, growing the sheet if needed.
+// It returns false if the location is invalid.
+func (s *Sheet) prepare(row, col int) bool {
+	if row < 0 || col < 0 || row >= maxRows || col >= maxCols {
+		if grate.Debug {
+			log.Printf("grate: invalid cell location row %d, col %d", row, col)
+		}
+		return false
+	}
 	if row >= s.NumRows || col >= s.NumCols {
 		if grate.Debug {
 			log.Printf("grate: cell out of bounds row %d>=%d, col %d>=%d",
@@ -66,123 +201,252 @@ func (s *Sheet) Put(row, col int, value interface{}, fmtNum uint16) {
 		}
 		s.Resize(s.NumRows, s.NumCols)
 	}
+	return true
+}
 
-	if spec, ok := value.(string); ok {
-		if spec == grate.EndRowMerged || spec == grate.EndColumnMerged || spec == grate.ContinueRowMerged || spec == grate.ContinueColumnMerged {
-			s.Rows[row][col] = NewCell(value)
-			s.Rows[row][col][1] = StaticCell
-			return
-		}
-	}
-
+// store applies the number format to a packed value and sets the cell.
+func (s *Sheet) store(row, col int, c cellData, fmtNum uint16) {
 	ct, ok := s.Formatter.getCellType(fmtNum)
-	if !ok || fmtNum == 0 {
-		s.Rows[row][col] = NewCell(value)
-	} else {
-		s.Rows[row][col] = NewCellWithType(value, ct, s.Formatter)
+	if ct == IntegerCell && c.kind == FloatCell && c.float() != math.Trunc(c.float()) {
+		// keep the fraction hidden by integer formats, which
+		// also picks the section, e.g. "(0)" for -0.25
+		ct = FloatCell
+	}
+	if ok && fmtNum != 0 {
+		c = c.coerce(&s.strs, ct, s.Formatter)
 	}
-	s.Rows[row][col].SetFormatNumber(fmtNum)
+	c.fmtNo = fmtNum
+	s.set(row, col, c)
 }
 
 // Set changes the value in an existing cell location.
 // NB Currently only used for populating string results for formulas.
 func (s *Sheet) Set(row, col int, value interface{}) {
-	if row > s.NumRows || col > s.NumCols {
+	if !s.inBounds(row, col) {
 		log.Println("grate: cell out of bounds")
 		return
 	}
 
-	s.Rows[row][col][0] = value
-	s.Rows[row][col][1] = StringCell
+	c := s.at(row, col)
+	c.bits = s.strs.add(fmt.Sprint(value))
+	c.kind = StringCell
+	s.set(row, col, c)
 }
 
 // SetURL adds a hyperlink to an existing cell location.
 func (s *Sheet) SetURL(row, col int, link string) {
-	if row > s.NumRows || col > s.NumCols {
+	s.SetHyperlink(row, col, &Hyperlink{URL: link})
+}
+
+// SetHyperlink adds a hyperlink to an existing cell location. Blank and
+// string cells become hyperlink cells, other values keep their type.
+func (s *Sheet) SetHyperlink(row, col int, h *Hyperlink) {
+	if !s.inBounds(row, col) {
+		log.Println("grate: cell out of bounds")
+		return
+	}
+
+	c := s.at(row, col)
+	switch c.kind {
+	case BlankCell:
+		if h.Display != "" {
+			c.bits = s.strs.add(h.Display)
+		} else {
+			c.bits = s.strs.add(h.Target())
+		}
+		c.kind = HyperlinkStringCell
+	case StringCell:
+		c.kind = HyperlinkStringCell
+	}
+	s.set(row, col, c)
+	if s.links == nil {
+		s.links = make(map[uint64]*Hyperlink)
+	}
+	s.links[cellKey(row, col)] = h
+}
+
+// SetXF records the XF (extended format) index for an existing cell location.
+func (s *Sheet) SetXF(row, col int, xf uint16) {
+	if !s.inBounds(row, col) {
 		log.Println("grate: cell out of bounds")
 		return
 	}
 
-	s.Rows[row][col].SetURL(link)
+	c := s.at(row, col)
+	c.xf = xf
+	s.set(row, col, c)
 }
 
 // Next advances to the next record of content.
 // It MUST be called prior to any Scan().
 func (s *Sheet) Next() bool {
-	if (s.CurRow + 1) > len(s.Rows) {
-		return false
+	for {
+		if (s.CurRow + 1) > s.height() {
+			return false
+		}
+		s.CurRow++
+		if !s.SkipHidden || !s.rowLayouts[s.CurRow-1].Hidden {
+			return true
+		}
 	}
-	s.CurRow++
-	return true
+}
+
+// Rows returns every cell of the sheet as a grid, materialized from the
+// compact storage. Use Cell or NonEmpty instead, which do not allocate a
+// Cell for each location of the sheet.
+//
+// Deprecated: Rows replaces the Rows field of earlier releases, and is
+// kept for compatibility only.
+func (s *Sheet) Rows() [][]Cell {
+	res := make([][]Cell, s.height())
+	for row := range res {
+		res[row] = make([]Cell, s.rowWidth(row))
+		for col := range res[row] {
+			res[row][col] = s.Cell(row, col)
+		}
+	}
+	return res
 }
 
 // Raw extracts the raw Cell interfaces underlying the current row.
 func (s *Sheet) Raw() []Cell {
-	rr := make([]Cell, s.NumCols)
-	for i, cell := range s.Rows[s.CurRow-1] {
-		rr[i] = cell.Clone()
+	rr := make([]Cell, s.width())
+	for i, col := range s.current() {
+		rr[i] = s.Cell(s.CurRow-1, col)
 	}
 	return rr
 }
 
 // Strings extracts values from the current record into a list of strings.
 func (s *Sheet) Strings() []string {
-	res := make([]string, s.NumCols)
-	for i, cell := range s.Rows[s.CurRow-1] {
-		if cell.Type() == BlankCell {
-			res[i] = ""
-			continue
-		}
-		if cell.Type() == StaticCell {
-			res[i] = cell.Value().(string)
-			continue
-		}
-		val := cell.Value()
-		fs, ok := s.Formatter.Apply(cell.FormatNo(), val)
+	res := make([]string, s.width())
+	for i, col := range s.current() {
+		res[i] = s.cellString(s.CurRow-1, col)
+	}
+	return res
+}
+
+// cellString renders the value of a cell using its number format.
+func (s *Sheet) cellString(row, col int) string {
+	c := s.at(row, col)
+	switch c.kind {
+	case BlankCell:
+		return ""
+	case StaticCell:
+		return s.strs[c.bits]
+	case ErrorCell:
+		return s.strs[uint32(c.bits)]
+	}
+	val := c.value(s.strs)
+	var fs string
+	if s.Values == grate.ValuesRaw {
+		fs = RawString(val)
+	} else {
+		var ok bool
+		fs, ok = s.Formatter.Apply(c.fmtNo, val)
 		if !ok {
 			fs = fmt.Sprint(val)
 		}
-		res[i] = fs
 	}
-	return res
+	if h := s.links[cellKey(row, col)]; h != nil {
+		switch s.Hyperlinks {
+		case grate.HyperlinkURL:
+			return h.Target()
+		case grate.HyperlinkBoth:
+			return fs + " <" + h.Target() + ">"
+		}
+	}
+	return fs
 }
 
 // Types extracts the data types from the current record into a list.
-// options: "boolean", "integer", "float", "string", "date",
-// and special cases: "blank", "hyperlink" which are string types
+// options: "boolean", "integer", "float", "string", "date", "duration", "error",
+// and special cases: "blank", "hyperlink" which are string types.
+// When NumberCategories is set, numeric cells report their category instead
+// if it is more specific: "percent", "currency", "accounting", "scientific"
+// or "fraction", with the currency appended if known, e.g. "currency:€".
 func (s *Sheet) Types() []string {
-	res := make([]string, s.NumCols)
-	for i, cell := range s.Rows[s.CurRow-1] {
-		res[i] = cell.Type().String()
+	res := make([]string, s.width())
+	for i, col := range s.current() {
+		cell := s.at(s.CurRow-1, col)
+		res[i] = cell.kind.String()
+		if s.NumberCategories && (cell.kind == IntegerCell || cell.kind == FloatCell) {
+			if c := s.Formatter.Class(cell.fmtNo); c.Category > PlainNumber {
+				res[i] = c.String()
+			}
+		}
+	}
+	return res
+}
+
+// Classes extracts the semantic categories of the number formats for the
+// current record into a list. Non-numeric cells have NoCategory.
+func (s *Sheet) Classes() []NumberClass {
+	res := make([]NumberClass, s.width())
+	for i, col := range s.current() {
+		cell := s.at(s.CurRow-1, col)
+		if cell.kind == IntegerCell || cell.kind == FloatCell {
+			res[i] = s.Formatter.Class(cell.fmtNo)
+		}
 	}
 	return res
 }
 
 // Formats extracts the format code for the current record into a list.
 func (s *Sheet) Formats() []string {
-	ok := true
-	res := make([]string, s.NumCols)
-	for i, cell := range s.Rows[s.CurRow-1] {
-		res[i], ok = builtInFormats[cell.FormatNo()]
-		if !ok {
-			res[i] = fmt.Sprint(cell.FormatNo())
+	res := make([]string, s.width())
+	for i, col := range s.current() {
+		res[i], _ = s.Formatter.Code(s.at(s.CurRow-1, col).fmtNo)
+	}
+	return res
+}
+
+// Styles extracts the resolved cell styles for the current record into a list.
+// Cells sharing an XF index share the same *Style, entries are nil
+// when no style information is available.
+func (s *Sheet) Styles() []*Style {
+	res := make([]*Style, s.width())
+	for i, col := range s.current() {
+		res[i] = s.cellStyle(s.at(s.CurRow-1, col))
+	}
+	return res
+}
+
+// Indents extracts the alignment indent level for the current record into a list.
+func (s *Sheet) Indents() []int {
+	res := make([]int, s.width())
+	for i, col := range s.current() {
+		if st := s.cellStyle(s.at(s.CurRow-1, col)); st != nil {
+			res[i] = st.Alignment.Indent
 		}
 	}
 	return res
 }
 
+func (s *Sheet) cellStyle(cell cellData) *Style {
+	xf := int(cell.xf)
+	if xf < len(s.XFStyles) {
+		return s.XFStyles[xf]
+	}
+	return nil
+}
+
 // Scan extracts values from the current record into the provided arguments
-// Arguments must be pointers to one of 5 supported types:
-//     bool, int64, float64, string, or time.Time
+// Arguments must be pointers to one of 6 supported types:
+//     bool, int64, float64, string, time.Time, or time.Duration
 // If invalid, returns ErrInvalidScanType
+// If a cell contains an error value, the returned error wraps a grate.CellError.
 func (s *Sheet) Scan(args ...interface{}) error {
-	row := s.Rows[s.CurRow-1]
+	cols := s.current()
 
 	for i, a := range args {
-		val := row[i].Value()
+		val := s.at(s.CurRow-1, cols[i]).value(s.strs)
+		if ce, ok := val.(grate.CellError); ok {
+			return fmt.Errorf("scan argument %d: %w", i, ce)
+		}
 
 		switch v := a.(type) {
-		case bool, int64, float64, string, time.Time:
+		case bool, int64, float64, string, time.Time, time.Duration:
 			return fmt.Errorf("scan destinations must be pointer (arg %d is not)", i)
 		case *bool:
 			if x, ok := val.(bool); ok {
@@ -214,6 +478,12 @@ func (s *Sheet) Scan(args ...interface{}) error {
 			} else {
 				return fmt.Errorf("scan destination %d expected *%T, not *time.Time", i, val)
 			}
+		case *time.Duration:
+			if x, ok := val.(time.Duration); ok {
+				*v = x
+			} else {
+				return fmt.Errorf("scan destination %d expected *%T, not *time.Duration", i, val)
+			}
 		default:
 			return fmt.Errorf("scan destination for arg %d is not supported (%T)", i, a)
 		}
@@ -228,5 +498,5 @@ func (s *Sheet) IsEmpty() bool {
 
 // Err returns the last error that occured.
 func (s *Sheet) Err() error {
-	return nil
+	return s.err
 }
@@ -0,0 +1,179 @@
+package commonxl
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+const benchRows, benchCols = 1000, 50
+
+// BenchmarkSheetPut fills a sheet using the compact cell storage.
+func BenchmarkSheetPut(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := &Sheet{Formatter: &Formatter{}}
+		s.Resize(benchRows, benchCols)
+		for r := 0; r < benchRows; r++ {
+			for c := 0; c < benchCols; c++ {
+				s.Put(r, c, float64(r*c)+0.5, 2)
+			}
+		}
+	}
+}
+
+// BenchmarkSheetPutFloat fills a sheet using the typed PutFloat,
+// which does not box each value.
+func BenchmarkSheetPutFloat(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := &Sheet{Formatter: &Formatter{}}
+		s.Resize(benchRows, benchCols)
+		for r := 0; r < benchRows; r++ {
+			for c := 0; c < benchCols; c++ {
+				s.PutFloat(r, c, float64(r*c)+0.5, 2)
+			}
+		}
+	}
+}
+
+// BenchmarkCellSlices fills a grid of boxed Cell values, as sheets
+// were stored before the compact representation.
+func BenchmarkCellSlices(b *testing.B) {
+	b.ReportAllocs()
+	fx := &Formatter{}
+	for i := 0; i < b.N; i++ {
+		rows := make([][]Cell, benchRows)
+		for r := range rows {
+			rows[r] = make([]Cell, benchCols)
+			for c := range rows[r] {
+				rows[r][c] = NewCellWithType(float64(r*c)+0.5, FloatCell, fx)
+				rows[r][c].SetFormatNumber(2)
+			}
+		}
+	}
+}
+
+// BenchmarkSheetPutStrings fills a sheet with string values.
+func BenchmarkSheetPutStrings(b *testing.B) {
+	strs := make([]string, benchCols)
+	for c := range strs {
+		strs[c] = "value " + strconv.Itoa(c)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := &Sheet{Formatter: &Formatter{}}
+		s.Resize(benchRows, benchCols)
+		for r := 0; r < benchRows; r++ {
+			for c := 0; c < benchCols; c++ {
+				s.Put(r, c, strs[c], 0)
+			}
+		}
+	}
+}
+
+// BenchmarkSheetPutString fills a sheet using the typed PutString.
+func BenchmarkSheetPutString(b *testing.B) {
+	strs := make([]string, benchCols)
+	for c := range strs {
+		strs[c] = "value " + strconv.Itoa(c)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := &Sheet{Formatter: &Formatter{}}
+		s.Resize(benchRows, benchCols)
+		for r := 0; r < benchRows; r++ {
+			for c := 0; c < benchCols; c++ {
+				s.PutString(r, c, strs[c], 0)
+			}
+		}
+	}
+}
+
+func TestTypedPut(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(2, 4)
+	s.Put(0, 0, 1.5, 0)
+	s.Put(0, 1, int64(-7), 0)
+	s.Put(0, 2, "text", 0)
+	s.Put(0, 3, "", 0)
+	s.PutFloat(1, 0, 1.5, 0)
+	s.PutInt(1, 1, -7, 0)
+	s.PutString(1, 2, "text", 0)
+	s.PutString(1, 3, "", 0)
+	for c := 0; c < 4; c++ {
+		a, b := s.Cell(0, c), s.Cell(1, c)
+		if !a.Equal(b) || a.Type() != b.Type() {
+			t.Errorf("column %d: Put stored %v (%s), typed put stored %v (%s)", c, a, a.Type(), b, b.Type())
+		}
+	}
+
+	// integer formats apply to typed floats
+	s.PutFloat(1, 0, 3.0, 1)
+	if c := s.Cell(1, 0); c.Type() != IntegerCell {
+		t.Errorf("expected an integer cell, got %s", c.Type())
+	}
+
+	// typed puts grow the sheet, and ignore invalid locations
+	s.PutInt(4, 5, 1, 0)
+	s.PutFloat(-1, 0, 1, 0)
+	if s.NumRows != 5 || s.NumCols != 6 || s.Cell(4, 5).Value() != int64(1) {
+		t.Errorf("unexpected sheet size %dx%d", s.NumRows, s.NumCols)
+	}
+
+	s.PutString(1, 3, grate.EndRowMerged, 0)
+	if s.Cell(1, 3).Type() != StaticCell {
+		t.Errorf("expected a merged cell placeholder")
+	}
+}
+
+func TestCellStorage(t *testing.T) {
+	fx := &Formatter{}
+	s := &Sheet{Formatter: fx}
+	s.Resize(1, 8)
+	date := time.Date(2021, 3, 4, 10, 30, 15, 250e6, time.UTC)
+	values := []interface{}{int64(-42), 1.25, "text", true, date, 90 * time.Minute, nil, "link"}
+	for i, v := range values {
+		if v != nil {
+			s.Put(0, i, v, 0)
+		}
+	}
+	s.SetXF(0, 1, 7)
+	s.SetURL(0, 7, "https://example.com/")
+	s.SetURL(0, 6, "https://example.com/blank")
+	s.Next()
+
+	raw := s.Raw()
+	values[6] = "https://example.com/blank"
+	for i, v := range values {
+		if raw[i].Value() != v {
+			t.Errorf("column %d: expected %#v, got %#v", i, v, raw[i].Value())
+		}
+	}
+	if raw[1].XF() != 7 || raw[0].XF() != 0 {
+		t.Errorf("unexpected XF %d %d", raw[0].XF(), raw[1].XF())
+	}
+	if raw[7].Type() != HyperlinkStringCell || raw[7].Hyperlink().Target() != "https://example.com/" {
+		t.Errorf("unexpected hyperlink %v", raw[7])
+	}
+	if c := s.Cell(5, 5); c.Type() != BlankCell {
+		t.Errorf("expected a blank cell out of bounds, got %v", c)
+	}
+}
+
+func TestRows(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(2, 2)
+	s.Put(0, 0, "a", 0)
+	s.Put(1, 1, 2.5, 0)
+
+	rows := s.Rows()
+	if len(rows) < 2 || len(rows[0]) != 2 {
+		t.Fatalf("unexpected grid size %d", len(rows))
+	}
+	if rows[0][0].Value() != "a" || rows[1][1].Value() != 2.5 || rows[0][1].Type() != BlankCell {
+		t.Errorf("unexpected cells %v", rows[:2])
+	}
+}
@@ -0,0 +1,199 @@
+package commonxl
+
+import (
+	"sort"
+
+	"github.com/pbnjay/grate"
+)
+
+// sparseMinCells is the number of allocated cells above which sheets with
+// few values are stored in a map instead of a dense grid, e.g. when values
+// are scattered over the last column of many rows.
+const sparseMinCells = 1 << 20
+
+// maxRows and maxCols are the largest sheet dimensions supported by Excel.
+// Cells outside of these bounds can only come from corrupt files.
+const (
+	maxRows = 1 << 20
+	maxCols = 1 << 14
+)
+
+// inBounds returns true if the cell location is within the sheet.
+func (s *Sheet) inBounds(row, col int) bool {
+	if row < 0 || col < 0 {
+		return false
+	}
+	if s.sparse != nil {
+		return row < s.sparseRows && col < s.NumCols
+	}
+	return row < len(s.rows) && col < s.rowWidth(row)
+}
+
+// at returns the cell at the location given, or a blank cell.
+func (s *Sheet) at(row, col int) cellData {
+	if !s.inBounds(row, col) {
+		return cellData{}
+	}
+	if s.sparse != nil {
+		return s.sparse[cellKey(row, col)]
+	}
+	if s.rows[row] == nil {
+		return cellData{}
+	}
+	return s.rows[row][col]
+}
+
+// set stores the cell at a location within bounds, switching to dense
+// storage once half of the cells in a sparse sheet are filled.
+func (s *Sheet) set(row, col int, c cellData) {
+	old := s.at(row, col)
+	if old.kind == BlankCell && c.kind != BlankCell {
+		if s.MaxCells > 0 && s.filled >= s.MaxCells {
+			s.err = grate.ErrLimitExceeded{Limit: "MaxCells", Max: int64(s.MaxCells)}
+			return
+		}
+		s.filled++
+	} else if old.kind != BlankCell && c.kind == BlankCell {
+		s.filled--
+	}
+
+	if s.sparse == nil && s.rows[row] == nil {
+		if c == (cellData{}) {
+			return
+		}
+		s.allocRow(row)
+	}
+	if s.sparse == nil {
+		s.rows[row][col] = c
+		return
+	}
+	if c == (cellData{}) {
+		delete(s.sparse, cellKey(row, col))
+	} else {
+		s.sparse[cellKey(row, col)] = c
+	}
+	if int64(s.filled)*2 >= int64(s.sparseRows)*int64(s.NumCols) {
+		s.densify()
+	}
+}
+
+// allocRow allocates a dense row on its first write. Once the allocated rows
+// exceed sparseMinCells cells, and fewer than 1 in 8 of them are filled, the
+// sheet switches to sparse storage instead.
+func (s *Sheet) allocRow(row int) {
+	s.allocRows++
+	if cells := int64(s.allocRows) * int64(s.NumCols); cells > sparseMinCells && int64(s.filled)*8 < cells {
+		s.makeSparse()
+		return
+	}
+	s.rows[row] = make([]cellData, s.NumCols)
+}
+
+// height returns the number of rows stored, including blank rows.
+func (s *Sheet) height() int {
+	if s.sparse != nil {
+		return s.sparseRows
+	}
+	return len(s.rows)
+}
+
+// rowWidth returns the number of cells stored in the row.
+func (s *Sheet) rowWidth(row int) int {
+	if s.sparse != nil || s.rows[row] == nil {
+		return s.NumCols
+	}
+	return len(s.rows[row])
+}
+
+// truncate discards the rows after the first n.
+func (s *Sheet) truncate(n int) {
+	if s.sparse == nil {
+		for _, cells := range s.rows[n:] {
+			if cells != nil {
+				s.allocRows--
+			}
+		}
+		s.rows = s.rows[:n]
+		return
+	}
+	for k, c := range s.sparse {
+		if int(k>>32) >= n {
+			if c.kind != BlankCell {
+				s.filled--
+			}
+			delete(s.sparse, k)
+		}
+	}
+	s.sparseRows = n
+}
+
+// makeSparse moves the cells of the sheet into sparse storage.
+func (s *Sheet) makeSparse() {
+	s.sparse = make(map[uint64]cellData, s.filled)
+	s.sparseRows = len(s.rows)
+	for row, cells := range s.rows {
+		for col, c := range cells {
+			if c != (cellData{}) {
+				s.sparse[cellKey(row, col)] = c
+			}
+		}
+	}
+	s.rows = nil
+	s.allocRows = 0
+}
+
+// densify moves the cells of the sheet into dense storage.
+func (s *Sheet) densify() {
+	s.rows = make([][]cellData, s.sparseRows)
+	for k, c := range s.sparse {
+		row := s.rows[k>>32]
+		if row == nil {
+			row = make([]cellData, s.NumCols)
+			s.rows[k>>32] = row
+			s.allocRows++
+		}
+		row[uint32(k)] = c
+	}
+	s.sparse = nil
+	s.sparseRows = 0
+}
+
+// NonEmpty calls fn for each cell holding a value, in row-major order,
+// until fn returns false. Blank cells and merged cell placeholders are
+// skipped, as are hidden rows and columns when SkipHidden is set. This
+// is much faster than Next() for scanning sparse sheets.
+func (s *Sheet) NonEmpty(fn func(row, col int, cell Cell) bool) {
+	visit := func(row, col int, c cellData) bool {
+		if c.kind == BlankCell || c.kind == StaticCell {
+			return true
+		}
+		if s.SkipHidden && (s.rowLayouts[row].Hidden || s.ColLayout(col).Hidden) {
+			return true
+		}
+		return fn(row, col, s.Cell(row, col))
+	}
+
+	if s.sparse == nil {
+		for row, cells := range s.rows {
+			for col, c := range cells {
+				if !visit(row, col, c) {
+					return
+				}
+			}
+		}
+		return
+	}
+
+	keys := make([]uint64, 0, s.filled)
+	for k, c := range s.sparse {
+		if c.kind != BlankCell {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, k := range keys {
+		if !visit(int(k>>32), int(uint32(k)), s.sparse[k]) {
+			return
+		}
+	}
+}
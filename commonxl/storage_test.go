@@ -0,0 +1,88 @@
+package commonxl
+
+import "testing"
+
+func TestSparseStorage(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(1048576, 16384)
+	s.Put(0, 0, "first", 0)
+	s.Put(1048575, 16383, 42.5, 0)
+	s.Put(5, 2, 7, 0)
+	if s.sparse != nil || s.allocRows != 3 {
+		t.Fatalf("expected 3 dense rows, got %d", s.allocRows)
+	}
+
+	// values scattered down the last column
+	for r := 1; r <= 100; r++ {
+		s.Put(r*1000, 16383, r, 0)
+	}
+	if s.sparse == nil {
+		t.Fatal("expected sparse storage")
+	}
+
+	var visited []string
+	s.NonEmpty(func(row, col int, cell Cell) bool {
+		visited = append(visited, Range{row, col, row, col}.String())
+		return true
+	})
+	if len(visited) != 103 || visited[0] != "A1" || visited[1] != "C6" ||
+		visited[2] != "XFD1001" || visited[102] != "XFD1048576" {
+		t.Errorf("unexpected cells visited %v", visited)
+	}
+	if c := s.Cell(1048575, 16383); c.Value() != 42.5 {
+		t.Errorf("unexpected value %v", c.Value())
+	}
+
+	s.CurRow = 6
+	if strs := s.Strings(); len(strs) != 16384 || strs[2] != "7" {
+		t.Errorf("unexpected strings %q", strs[:3])
+	}
+}
+
+func TestSparseDensify(t *testing.T) {
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(2000, 1000)
+	for r := 0; r < 2000; r++ {
+		s.Put(r, 999, r, 0)
+	}
+	if s.sparse == nil {
+		t.Fatal("expected sparse storage")
+	}
+	for r := 0; r < 2000; r++ {
+		for c := 0; c < 1000; c++ {
+			s.Put(r, c, r+c, 0)
+		}
+	}
+	if s.sparse != nil {
+		t.Fatal("expected dense storage")
+	}
+	if c := s.Cell(1999, 999); c.Value() != int64(2998) {
+		t.Errorf("unexpected value %v", c.Value())
+	}
+
+	n := 0
+	s.NonEmpty(func(row, col int, cell Cell) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Errorf("expected iteration to stop after 10 cells, got %d", n)
+	}
+}
+
+func TestDenseStorage(t *testing.T) {
+	// a filled sheet over sparseMinCells is never stored sparsely
+	s := &Sheet{Formatter: &Formatter{}}
+	s.Resize(2000, 1000)
+	for r := 0; r < 2000; r++ {
+		for c := 0; c < 1000; c++ {
+			s.PutInt(r, c, int64(r+c), 0)
+			if s.sparse != nil {
+				t.Fatalf("unexpected sparse storage at row %d, col %d", r, c)
+			}
+		}
+	}
+	if s.allocRows != 2000 {
+		t.Errorf("expected 2000 dense rows, got %d", s.allocRows)
+	}
+}
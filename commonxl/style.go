@@ -0,0 +1,220 @@
+package commonxl
+
+import (
+	"fmt"
+	"math"
+)
+
+// ColorKind describes how a Color was specified in the source file.
+type ColorKind uint8
+
+// ColorKinds used by spreadsheet styles.
+const (
+	NoColor      ColorKind = iota // not specified
+	RGBColor                      // explicit ARGB value
+	IndexedColor                  // index into the workbook palette
+	ThemeColor                    // index into the workbook theme
+	AutoColor                     // application-defined (usually black text)
+)
+
+// Color is a style color. ARGB holds the resolved value for
+// indexed and theme colors once the Style has been resolved.
+type Color struct {
+	Kind  ColorKind
+	Index int     // palette or theme index
+	Tint  float64 // lighten (>0) or darken (<0) amount, -1.0 to 1.0
+	ARGB  uint32
+}
+
+// String returns the resolved color as "#RRGGBB", or an empty string if not set.
+func (c Color) String() string {
+	if c.Kind == NoColor {
+		return ""
+	}
+	return fmt.Sprintf("#%06X", c.ARGB&0xFFFFFF)
+}
+
+// Font describes the text styling of a cell.
+type Font struct {
+	Name      string
+	Size      float64 // in points
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+	Color     Color
+}
+
+// Fill describes the background of a cell.
+type Fill struct {
+	Pattern string // ST_PatternType name, e.g. "none", "solid", "gray125"
+	FgColor Color
+	BgColor Color
+}
+
+// BorderEdge describes one edge of a cell's border.
+type BorderEdge struct {
+	Style string // ST_BorderStyle name, e.g. "thin", "double", empty if none
+	Color Color
+}
+
+// Border describes the borders surrounding a cell.
+type Border struct {
+	Left, Right, Top, Bottom BorderEdge
+}
+
+// Alignment describes the text layout within a cell.
+type Alignment struct {
+	Horizontal  string // e.g. "general", "left", "center", "right"
+	Vertical    string // e.g. "top", "center", "bottom"
+	WrapText    bool
+	ShrinkToFit bool
+	Indent      int
+	Rotation    int // degrees, or 255 for vertical text
+}
+
+// Style is the resolved cell formatting for an XF (extended format) record.
+// Cells sharing an XF index share the same *Style.
+type Style struct {
+	NumFmt    uint16
+	Font      Font
+	Fill      Fill
+	Border    Border
+	Alignment Alignment
+}
+
+// DefaultPalette is the default indexed color table shared by XLS and XLSX.
+// Indexes 64 and 65 are the system foreground and background colors.
+var DefaultPalette = []uint32{
+	0xFF000000, 0xFFFFFFFF, 0xFFFF0000, 0xFF00FF00, 0xFF0000FF, 0xFFFFFF00, 0xFFFF00FF, 0xFF00FFFF,
+	0xFF000000, 0xFFFFFFFF, 0xFFFF0000, 0xFF00FF00, 0xFF0000FF, 0xFFFFFF00, 0xFFFF00FF, 0xFF00FFFF,
+	0xFF800000, 0xFF008000, 0xFF000080, 0xFF808000, 0xFF800080, 0xFF008080, 0xFFC0C0C0, 0xFF808080,
+	0xFF9999FF, 0xFF993366, 0xFFFFFFCC, 0xFFCCFFFF, 0xFF660066, 0xFFFF8080, 0xFF0066CC, 0xFFCCCCFF,
+	0xFF000080, 0xFFFF00FF, 0xFFFFFF00, 0xFF00FFFF, 0xFF800080, 0xFF800000, 0xFF008080, 0xFF0000FF,
+	0xFF00CCFF, 0xFFCCFFFF, 0xFFCCFFCC, 0xFFFFFF99, 0xFF99CCFF, 0xFFFF99CC, 0xFFCC99FF, 0xFFFFCC99,
+	0xFF3366FF, 0xFF33CCCC, 0xFF99CC00, 0xFFFFCC00, 0xFFFF9900, 0xFFFF6600, 0xFF666699, 0xFF969696,
+	0xFF003366, 0xFF339966, 0xFF003300, 0xFF333300, 0xFF993300, 0xFF993366, 0xFF333399, 0xFF333333,
+	0xFF000000, 0xFFFFFFFF,
+}
+
+// ColorTable resolves indexed and theme colors to ARGB values.
+type ColorTable struct {
+	// Indexed colors, if nil the DefaultPalette is used.
+	Indexed []uint32
+	// Theme colors in SpreadsheetML index order:
+	//   lt1, dk1, lt2, dk2, accent1-6, hlink, folHlink
+	Theme []uint32
+}
+
+// Resolve returns the color with its ARGB value filled in.
+func (t *ColorTable) Resolve(c Color) Color {
+	switch c.Kind {
+	case IndexedColor:
+		pal := t.Indexed
+		if pal == nil {
+			pal = DefaultPalette
+		}
+		if c.Index >= 0 && c.Index < len(pal) {
+			c.ARGB = pal[c.Index]
+		} else if c.Index >= 0 && c.Index < len(DefaultPalette) {
+			c.ARGB = DefaultPalette[c.Index]
+		}
+	case ThemeColor:
+		if c.Index >= 0 && c.Index < len(t.Theme) {
+			c.ARGB = t.Theme[c.Index]
+		}
+	case AutoColor:
+		c.ARGB = 0xFF000000
+	}
+	if c.Tint != 0 {
+		c.ARGB = applyTint(c.ARGB, c.Tint)
+	}
+	return c
+}
+
+// ResolveStyle fills in the ARGB values of all colors in the Style.
+func (t *ColorTable) ResolveStyle(s *Style) {
+	s.Font.Color = t.Resolve(s.Font.Color)
+	s.Fill.FgColor = t.Resolve(s.Fill.FgColor)
+	s.Fill.BgColor = t.Resolve(s.Fill.BgColor)
+	s.Border.Left.Color = t.Resolve(s.Border.Left.Color)
+	s.Border.Right.Color = t.Resolve(s.Border.Right.Color)
+	s.Border.Top.Color = t.Resolve(s.Border.Top.Color)
+	s.Border.Bottom.Color = t.Resolve(s.Border.Bottom.Color)
+}
+
+// applyTint adjusts the luminance of the color as described in
+// ECMA-376 section 18.8.19 (the tint attribute of CT_Color).
+func applyTint(argb uint32, tint float64) uint32 {
+	r := float64((argb>>16)&0xFF) / 255.0
+	g := float64((argb>>8)&0xFF) / 255.0
+	b := float64(argb&0xFF) / 255.0
+
+	// RGB to HLS
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l := (max + min) / 2
+	var h, s float64
+	if max != min {
+		d := max - min
+		if l > 0.5 {
+			s = d / (2 - max - min)
+		} else {
+			s = d / (max + min)
+		}
+		switch max {
+		case r:
+			h = (g - b) / d
+			if g < b {
+				h += 6
+			}
+		case g:
+			h = (b-r)/d + 2
+		default:
+			h = (r-g)/d + 4
+		}
+		h /= 6
+	}
+
+	if tint < 0 {
+		l = l * (1 + tint)
+	} else {
+		l = l*(1-tint) + tint
+	}
+
+	// HLS to RGB
+	if s == 0 {
+		r, g, b = l, l, l
+	} else {
+		var q float64
+		if l < 0.5 {
+			q = l * (1 + s)
+		} else {
+			q = l + s - l*s
+		}
+		p := 2*l - q
+		r = hueToRGB(p, q, h+1.0/3)
+		g = hueToRGB(p, q, h)
+		b = hueToRGB(p, q, h-1.0/3)
+	}
+	return (argb & 0xFF000000) |
+		uint32(math.Round(r*255))<<16 | uint32(math.Round(g*255))<<8 | uint32(math.Round(b*255))
+}
+
+func hueToRGB(p, q, t float64) float64 {
+	if t < 0 {
+		t++
+	}
+	if t > 1 {
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 0.5:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	}
+	return p
+}
@@ -0,0 +1,30 @@
+package commonxl
+
+import "testing"
+
+func TestColorResolve(t *testing.T) {
+	ct := &ColorTable{Theme: []uint32{0xFFFFFFFF, 0xFF000000, 0xFFE7E6E6, 0xFF44546A, 0xFF4472C4}}
+	cases := []struct {
+		c    Color
+		want string
+	}{
+		{Color{}, ""},
+		{Color{Kind: RGBColor, ARGB: 0xFFFF0000}, "#FF0000"},
+		{Color{Kind: IndexedColor, Index: 10}, "#FF0000"},
+		{Color{Kind: IndexedColor, Index: 64}, "#000000"},
+		{Color{Kind: ThemeColor, Index: 4}, "#4472C4"},
+		{Color{Kind: ThemeColor, Index: 0, Tint: -0.1499984740745262}, "#D9D9D9"},
+		{Color{Kind: ThemeColor, Index: 4, Tint: 0.7999816888943144}, "#DAE3F3"},
+		{Color{Kind: AutoColor}, "#000000"},
+	}
+	for _, c := range cases {
+		if got := ct.Resolve(c.c).String(); got != c.want {
+			t.Errorf("Resolve(%+v) = %s, expected %s", c.c, got, c.want)
+		}
+	}
+
+	ct.Indexed = []uint32{0xFF123456}
+	if got := ct.Resolve(Color{Kind: IndexedColor, Index: 0}).String(); got != "#123456" {
+		t.Errorf("custom palette color = %s, expected #123456", got)
+	}
+}
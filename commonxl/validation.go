@@ -0,0 +1,56 @@
+package commonxl
+
+import "strings"
+
+// Validation describes a data validation rule applied to ranges of cells.
+// Type, Operator and ErrorStyle use the SpreadsheetML names.
+type Validation struct {
+	Ranges []Range
+
+	Type     string // "none", "whole", "decimal", "list", "date", "time", "textLength", "custom"
+	Operator string // e.g. "between", "equal", "greaterThan"
+
+	// Formula1 and Formula2 hold the constraint values, e.g. the
+	// minimum and maximum for "between", or the source of a list.
+	Formula1 string
+	Formula2 string
+
+	// List holds the allowed values when a list is given explicitly
+	// instead of as a cell reference.
+	List []string
+
+	AllowBlank       bool
+	ShowDropDown     bool // false if the in-cell dropdown is suppressed
+	ShowInputMessage bool
+	ShowErrorMessage bool
+
+	ErrorStyle  string // "stop", "warning", "information"
+	ErrorTitle  string
+	Error       string
+	PromptTitle string
+	Prompt      string
+}
+
+// AddValidation records a data validation rule for the sheet.
+// List is filled in from Formula1 for explicit list values.
+func (s *Sheet) AddValidation(v Validation) {
+	if v.Type == "list" && v.List == nil {
+		v.List = explicitList(v.Formula1)
+	}
+	s.validations = append(s.validations, v)
+}
+
+// explicitList splits a quoted, comma-separated list formula
+// e.g. "Yes,No" into its values. Returns nil for references.
+func explicitList(f string) []string {
+	if len(f) < 2 || f[0] != '"' || f[len(f)-1] != '"' {
+		return nil
+	}
+	f = strings.ReplaceAll(f[1:len(f)-1], `""`, `"`)
+	return strings.Split(f, ",")
+}
+
+// Validations returns the data validation rules defined on the sheet.
+func (s *Sheet) Validations() []Validation {
+	return s.validations
+}
@@ -0,0 +1,58 @@
+package commonxl
+
+// SheetView describes the saved view state of a sheet.
+type SheetView struct {
+	FrozenRows int // number of rows frozen at the top of the sheet
+	FrozenCols int // number of columns frozen at the left of the sheet
+
+	// ActiveRow and ActiveCol are the 0-based location of the active cell.
+	ActiveRow int
+	ActiveCol int
+
+	// Selection holds the selected ranges of the active pane.
+	Selection []Range
+}
+
+// AutoFilter describes the filter dropdowns on a range of cells.
+type AutoFilter struct {
+	Range   Range          // including the header row
+	Columns []FilterColumn // only columns with active criteria
+}
+
+// FilterColumn describes the active filter criteria for a column.
+type FilterColumn struct {
+	Col  int    // 0-based column index within the sheet
+	Type string // "values", "custom", "top10" or "dynamic"
+
+	// Values are the displayed values selected by a "values" filter.
+	// Blank is true if blank cells are also selected.
+	Values []string
+	Blank  bool
+
+	// Criteria are the comparisons of a "custom" filter, which must all
+	// match if And is true (otherwise any may match). For "dynamic" filters
+	// the Operator holds the dynamic filter type, e.g. "aboveAverage".
+	Criteria []FilterCriterion
+	And      bool
+
+	// Top, Percent and Rank describe a "top10" filter.
+	Top     bool
+	Percent bool
+	Rank    float64
+}
+
+// FilterCriterion is a single comparison within a custom filter.
+type FilterCriterion struct {
+	Operator string // e.g. "equal", "greaterThan", "notEqual"
+	Value    string
+}
+
+// HeaderRows returns the number of header rows suggested by the view
+// metadata: the rows up to and including the first row of the autofilter
+// range, or else the frozen rows. Returns 0 if there is no hint.
+func (s *Sheet) HeaderRows() int {
+	if s.AutoFilter != nil {
+		return s.AutoFilter.Range.FirstRow + 1
+	}
+	return s.View.FrozenRows
+}
@@ -1,6 +1,10 @@
 package grate
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"log"
+)
 
 var (
 	// configure at build time by adding go build arguments:
@@ -12,7 +16,7 @@ var (
 )
 
 // ErrInvalidScanType is returned by Scan for invalid arguments.
-var ErrInvalidScanType = errors.New("grate: Scan only supports *bool, *int, *float64, *string, *time.Time arguments")
+var ErrInvalidScanType = errors.New("grate: Scan only supports *bool, *int, *float64, *string, *time.Time, *time.Duration arguments")
 
 // ErrNotInFormat is used to auto-detect file types using the defined OpenFunc
 // It is returned by OpenFunc when the code does not detect correct file formats.
@@ -21,6 +25,10 @@ var ErrNotInFormat = errors.New("grate: file is not in this format")
 // ErrUnknownFormat is used when grate does not know how to open a file format.
 var ErrUnknownFormat = errors.New("grate: file format is not known/supported")
 
+// ErrUnknownLocale is returned when opening a spreadsheet with WithLocale
+// and a locale name which is not known.
+var ErrUnknownLocale = errors.New("grate: unknown locale")
+
 type errx struct {
 	errs []error
 }
@@ -42,3 +50,48 @@ func WrapErr(e ...error) error {
 	}
 	return errx{errs: e}
 }
+
+// ParseError is returned when a file is too corrupt to be parsed, e.g. when
+// a record is truncated.
+type ParseError struct {
+	Format  string // the file format, e.g. "xls"
+	Context string // where the error occurred, e.g. the record being read
+	Err     error  // the underlying error
+}
+
+// Error implements the error interface.
+func (e ParseError) Error() string {
+	if e.Context == "" {
+		return fmt.Sprintf("grate: unable to parse %s file: %v", e.Format, e.Err)
+	}
+	return fmt.Sprintf("grate: unable to parse %s file at %s: %v", e.Format, e.Context, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Recover converts a panic during parsing into a ParseError, which is
+// stored in *err. It must be deferred directly by the parsing function,
+// which uses a named error result. The context func, if not nil,
+// describes where the parser was, e.g. the record being read.
+func Recover(err *error, format string, context func() string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	pe := ParseError{Format: format}
+	if context != nil {
+		pe.Context = context()
+	}
+	if e, ok := r.(error); ok {
+		pe.Err = e
+	} else {
+		pe.Err = fmt.Errorf("%v", r)
+	}
+	if Debug {
+		log.Println(pe.Error())
+	}
+	*err = pe
+}
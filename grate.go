@@ -30,17 +30,19 @@ type Collection interface {
 	Strings() []string
 
 	// Types extracts the data types from the current record into a list.
-	// options: "boolean", "integer", "float", "string", "date",
-	// and special cases: "blank", "hyperlink" which are string types
+	// options: "boolean", "integer", "float", "string", "date", "duration", "error",
+	// and special cases: "blank", "hyperlink" which are string types.
+	// See WithNumberCategories for the detailed numeric types.
 	Types() []string
 
 	// Formats extracts the format codes for the current record into a list.
 	Formats() []string
 
 	// Scan extracts values from the current record into the provided arguments
-	// Arguments must be pointers to one of 5 supported types:
-	//     bool, int64, float64, string, or time.Time
+	// Arguments must be pointers to one of 6 supported types:
+	//     bool, int64, float64, string, time.Time, or time.Duration
 	// If invalid, returns ErrInvalidScanType
+	// If a value is an error, returns an error wrapping the CellError
 	Scan(args ...interface{}) error
 
 	// IsEmpty returns true if there are no data values.
@@ -50,14 +52,42 @@ type Collection interface {
 	Err() error
 }
 
+// PivotSource is implemented by Sources which can extract pivot caches.
+type PivotSource interface {
+	// PivotCaches lists the names of the pivot caches within this source.
+	PivotCaches() ([]string, error)
+
+	// GetPivotCache returns the named pivot cache as a Collection, with
+	// the field names as the first record.
+	GetPivotCache(name string) (Collection, error)
+}
+
+// Metadata describes properties of a workbook as a whole.
+type Metadata struct {
+	// Date1904 is true if date values count days from Jan 1, 1904 (as
+	// saved by early Mac versions of Excel) instead of Jan 1, 1900.
+	Date1904 bool
+
+	// Locale is the language tag (e.g. "en-US") of the locale used to
+	// render values.
+	Locale string
+}
+
+// MetadataSource is implemented by Sources which can report workbook metadata.
+type MetadataSource interface {
+	// Metadata returns the properties of the workbook.
+	Metadata() Metadata
+}
+
 // OpenFunc defines a Source's instantiation function.
 // It should return ErrNotInFormat immediately if filename is not of the correct file type.
-type OpenFunc func(filename string) (Source, error)
+type OpenFunc func(filename string, opts ...Option) (Source, error)
 
 // Open a tabular data file and return a Source for accessing it's contents.
-func Open(filename string) (Source, error) {
+// Options are passed through to the underlying format implementation.
+func Open(filename string, opts ...Option) (Source, error) {
 	for _, o := range srcTable {
-		src, err := o.op(filename)
+		src, err := o.open(filename, opts...)
 		if err == nil {
 			return src, nil
 		}
@@ -77,6 +107,12 @@ type srcOpenTab struct {
 	op   OpenFunc
 }
 
+// open the file, converting any panic into a ParseError.
+func (o *srcOpenTab) open(filename string, opts ...Option) (src Source, err error) {
+	defer Recover(&err, o.name, nil)
+	return o.op(filename, opts...)
+}
+
 var srcTable = make([]*srcOpenTab, 0, 20)
 
 // Register the named source as a grate datasource implementation.
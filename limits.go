@@ -0,0 +1,91 @@
+package grate
+
+import (
+	"fmt"
+	"io"
+)
+
+// Limits bounds the resources used to read a file, to protect against
+// malicious or corrupt input. Zero values impose no limit.
+type Limits struct {
+	// MaxPartBytes is the maximum decompressed size of a single part
+	// within a zip container (e.g. xl/sharedStrings.xml), or the maximum
+	// size of a csv or tsv file.
+	MaxPartBytes int64
+
+	// MaxCells is the maximum number of non-blank cells in a sheet.
+	MaxCells int
+
+	// MaxStrings is the maximum number of entries in a shared string table.
+	MaxStrings int
+
+	// MaxRecords is the maximum number of records in an xls substream.
+	MaxRecords int
+
+	// MaxSectorChain is the maximum length of a sector chain in a
+	// compound file (the container format of xls files).
+	MaxSectorChain int
+}
+
+// DefaultLimits are reasonable limits for reading untrusted uploads, which
+// still allow for large spreadsheets.
+var DefaultLimits = Limits{
+	MaxPartBytes:   512 << 20,
+	MaxCells:       50 << 20,
+	MaxStrings:     10 << 20,
+	MaxRecords:     64 << 20,
+	MaxSectorChain: 1 << 22,
+}
+
+// ErrLimitExceeded is returned when a file exceeds one of the Limits
+// given when opening it.
+type ErrLimitExceeded struct {
+	Limit string // the name of the limit, e.g. "MaxCells"
+	Max   int64  // the configured value of the limit
+}
+
+// Error implements the error interface.
+func (e ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("grate: file exceeds the %s limit of %d", e.Limit, e.Max)
+}
+
+// WithLimits sets the resource limits used when reading the file.
+// By default there are no limits, see DefaultLimits for untrusted input.
+func WithLimits(l Limits) Option {
+	return func(o *Options) {
+		o.Limits = l
+	}
+}
+
+// LimitReader returns a Reader which reads from r, but fails with an
+// ErrLimitExceeded naming the limit once more than max bytes are read.
+// If max is zero, r is returned unchanged.
+func LimitReader(r io.Reader, limit string, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, limit: limit, max: max, n: max}
+}
+
+type limitedReader struct {
+	r     io.Reader
+	limit string
+	max   int64
+	n     int64 // bytes remaining
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrLimitExceeded{Limit: l.limit, Max: l.max}
+	}
+	// read one byte past the limit to detect excess data
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), ErrLimitExceeded{Limit: l.limit, Max: l.max}
+	}
+	return n, err
+}
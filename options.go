@@ -0,0 +1,177 @@
+package grate
+
+import "regexp"
+
+// Options describes optional behaviors that can be requested when opening a
+// Source. Implementations should ignore options that do not apply to them.
+type Options struct {
+	// Phonetic returns the phonetic reading of strings (e.g. Japanese
+	// furigana) in place of the base text, where one is present.
+	Phonetic bool
+
+	// SkipHidden omits hidden rows and columns when iterating over records.
+	SkipHidden bool
+
+	// Hyperlinks selects how hyperlink cells are rendered as strings.
+	Hyperlinks HyperlinkMode
+
+	// Values selects whether Strings() returns the displayed or raw values.
+	Values ValueMode
+
+	// NumberCategories reports the semantic category of numeric values
+	// from Types(), e.g. "percent" or "currency:$", in place of "float".
+	NumberCategories bool
+
+	// FormatIDs replaces the rendering of values by number format id.
+	FormatIDs map[uint16]FormatFunc
+
+	// FormatCodes replaces the rendering of values whose number format code
+	// matches a pattern. The first matching pattern is used.
+	FormatCodes []FormatCodeOverride
+
+	// FormatTypes replaces the rendering of values by type name, as
+	// returned by Types() (e.g. "date" or "float").
+	FormatTypes map[string]FormatFunc
+
+	// Limits bounds the resources used to read the file.
+	Limits Limits
+
+	// Locale selects the regional conventions (e.g. "de-DE") used to render
+	// numbers and dates whose format does not specify a locale. When empty,
+	// the workbook's own locale is used if it is known.
+	Locale string
+}
+
+// HyperlinkMode selects how hyperlink cells are rendered as strings.
+type HyperlinkMode int
+
+const (
+	// HyperlinkDefault keeps the rendering of earlier releases (the default):
+	// xlsx cells show the external link target in place of their text, and
+	// xls cells show the display text followed by the target, e.g. "text <url>".
+	HyperlinkDefault HyperlinkMode = iota
+	// HyperlinkText renders the displayed text of the cell.
+	HyperlinkText
+	// HyperlinkURL renders the hyperlink target in place of the text.
+	HyperlinkURL
+	// HyperlinkBoth renders the text followed by the target, e.g. "text <url>".
+	HyperlinkBoth
+)
+
+// ValueMode selects how cell values are rendered as strings.
+type ValueMode int
+
+const (
+	// ValuesDisplayed renders values using their number format, as they
+	// are displayed in the spreadsheet (the default).
+	ValuesDisplayed ValueMode = iota
+	// ValuesRaw renders the canonical values behind the display: numbers
+	// with full precision, dates in ISO-8601 form, durations as serial
+	// numbers and booleans as TRUE or FALSE. Delimited text formats are
+	// unchanged, their values are always raw.
+	ValuesRaw
+)
+
+// FormatFunc renders a cell value as a string in place of its number format.
+// The value is one of bool, int64, float64, string, time.Time or time.Duration.
+type FormatFunc func(value interface{}) string
+
+// FormatCodeOverride replaces the rendering of values whose number format
+// code matches Pattern.
+type FormatCodeOverride struct {
+	Pattern *regexp.Regexp
+	Func    FormatFunc
+}
+
+// Option configures a single setting within Options.
+type Option func(*Options)
+
+// NewOptions returns the default Options with the given settings applied in order.
+func NewOptions(opts ...Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithPhonetic selects whether phonetic readings are returned in place of
+// the base text of strings. They are excluded by default.
+func WithPhonetic(enabled bool) Option {
+	return func(o *Options) {
+		o.Phonetic = enabled
+	}
+}
+
+// WithSkipHidden selects whether hidden rows and columns are skipped
+// during iteration. They are included by default.
+func WithSkipHidden(enabled bool) Option {
+	return func(o *Options) {
+		o.SkipHidden = enabled
+	}
+}
+
+// WithHyperlinks selects how hyperlink cells are rendered as strings.
+// By default they are rendered as in earlier releases, see HyperlinkDefault.
+func WithHyperlinks(mode HyperlinkMode) Option {
+	return func(o *Options) {
+		o.Hyperlinks = mode
+	}
+}
+
+// WithValues selects whether Strings() renders the displayed or the raw
+// values of cells. By default values are rendered using their number format.
+func WithValues(mode ValueMode) Option {
+	return func(o *Options) {
+		o.Values = mode
+	}
+}
+
+// WithLocale selects the locale (e.g. "de-DE" or "ja-JP") used to render
+// numbers and dates, in place of the workbook's default. Opening a
+// spreadsheet with an unknown locale fails with ErrUnknownLocale.
+func WithLocale(name string) Option {
+	return func(o *Options) {
+		o.Locale = name
+	}
+}
+
+// WithNumberCategories selects whether Types() reports the semantic
+// category of numeric values, such as "percent", "currency", "accounting",
+// "scientific" or "fraction". By default they are "integer" or "float".
+func WithNumberCategories(enabled bool) Option {
+	return func(o *Options) {
+		o.NumberCategories = enabled
+	}
+}
+
+// WithFormatID renders values with number format id fmtID using fn, in
+// place of the format. Overrides by id take precedence over all others.
+func WithFormatID(fmtID uint16, fn FormatFunc) Option {
+	return func(o *Options) {
+		if o.FormatIDs == nil {
+			o.FormatIDs = make(map[uint16]FormatFunc)
+		}
+		o.FormatIDs[fmtID] = fn
+	}
+}
+
+// WithFormatCode renders values using fn when their number format code
+// matches the pattern, e.g. `[$€£¥]|"\$"` for currency formats. Overrides
+// by code take precedence over overrides by type.
+func WithFormatCode(pattern *regexp.Regexp, fn FormatFunc) Option {
+	return func(o *Options) {
+		o.FormatCodes = append(o.FormatCodes, FormatCodeOverride{Pattern: pattern, Func: fn})
+	}
+}
+
+// WithFormatType renders all values of the named type (as returned by
+// Types(), e.g. "date") using fn, regardless of their number format.
+func WithFormatType(typ string, fn FormatFunc) Option {
+	return func(o *Options) {
+		if o.FormatTypes == nil {
+			o.FormatTypes = make(map[string]FormatFunc)
+		}
+		o.FormatTypes[typ] = fn
+	}
+}
@@ -11,7 +11,7 @@ var _ = grate.Register("csv", 15, OpenCSV)
 
 // OpenCSV defines a Source's instantiation function.
 // It should return ErrNotInFormat immediately if filename is not of the correct file type.
-func OpenCSV(filename string) (grate.Source, error) {
+func OpenCSV(filename string, opts ...grate.Option) (grate.Source, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -22,13 +22,19 @@ func OpenCSV(filename string) (grate.Source, error) {
 		iterRow:  -1,
 	}
 
-	s := csv.NewReader(f)
+	limits := grate.NewOptions(opts...).Limits
+	s := csv.NewReader(grate.LimitReader(f, "MaxPartBytes", limits.MaxPartBytes))
 	s.FieldsPerRecord = -1
 
-	total := 0
+	maxCells := limits.MaxCells
+	total, cells := 0, 0
 	ncols := make(map[int]int)
 	rec, err := s.Read()
 	for ; err == nil; rec, err = s.Read() {
+		cells += len(rec)
+		if maxCells > 0 && cells > maxCells {
+			return nil, grate.ErrLimitExceeded{Limit: "MaxCells", Max: int64(maxCells)}
+		}
 		ncols[len(rec)]++
 		total++
 		t.rows = append(t.rows, rec)
@@ -37,6 +43,8 @@ func OpenCSV(filename string) (grate.Source, error) {
 		switch perr := err.(type) {
 		case *csv.ParseError:
 			return nil, grate.WrapErr(perr, grate.ErrNotInFormat)
+		case grate.ErrLimitExceeded:
+			return nil, perr
 		}
 		if total < 10 {
 			// probably? not in this format
@@ -0,0 +1,35 @@
+package simple
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+func TestLimitBytes(t *testing.T) {
+	dir := t.TempDir()
+	for sep, open := range map[string]func(string, ...grate.Option) (grate.Source, error){
+		",":  OpenCSV,
+		"\t": OpenTSV,
+	} {
+		fn := filepath.Join(dir, "big.txt")
+		row := strings.Join([]string{"a", "b", "c"}, sep) + "\n"
+		if err := os.WriteFile(fn, []byte(strings.Repeat(row, 1000)), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := open(fn, grate.WithLimits(grate.Limits{MaxPartBytes: 1024}))
+		var lerr grate.ErrLimitExceeded
+		if !errors.As(err, &lerr) || lerr.Limit != "MaxPartBytes" {
+			t.Errorf("%q: expected MaxPartBytes ErrLimitExceeded, got %v", sep, err)
+		}
+
+		if _, err = open(fn, grate.WithLimits(grate.Limits{MaxPartBytes: 1 << 20})); errors.As(err, &lerr) {
+			t.Errorf("%q: unexpected error %v", sep, err)
+		}
+	}
+}
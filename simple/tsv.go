@@ -12,7 +12,7 @@ var _ = grate.Register("tsv", 10, OpenTSV)
 
 // OpenTSV defines a Source's instantiation function.
 // It should return ErrNotInFormat immediately if filename is not of the correct file type.
-func OpenTSV(filename string) (grate.Source, error) {
+func OpenTSV(filename string, opts ...grate.Option) (grate.Source, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -23,11 +23,17 @@ func OpenTSV(filename string) (grate.Source, error) {
 		iterRow:  -1,
 	}
 
-	s := bufio.NewScanner(f)
-	total := 0
+	limits := grate.NewOptions(opts...).Limits
+	s := bufio.NewScanner(grate.LimitReader(f, "MaxPartBytes", limits.MaxPartBytes))
+	maxCells := limits.MaxCells
+	total, cells := 0, 0
 	ncols := make(map[int]int)
 	for s.Scan() {
 		r := strings.Split(s.Text(), "\t")
+		cells += len(r)
+		if maxCells > 0 && cells > maxCells {
+			return nil, grate.ErrLimitExceeded{Limit: "MaxCells", Max: int64(maxCells)}
+		}
 		ncols[len(r)]++
 		total++
 		t.rows = append(t.rows, r)
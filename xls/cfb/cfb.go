@@ -80,6 +80,9 @@ func (d *directory) String() string {
 	if (d.NameByteLen&1) == 1 || d.NameByteLen > 64 {
 		return "<invalid utf16 string>"
 	}
+	if d.NameByteLen < 2 {
+		return ""
+	}
 	r16 := utf16.Decode(d.Name[:int(d.NameByteLen)/2])
 	// trim off null terminator
 	return string(r16[:len(r16)-1])
@@ -100,6 +103,47 @@ type Document struct {
 
 	ministreamstart uint32
 	ministreamsize  uint32
+
+	// maximum number of sectors in a chain, or 0 for no limit
+	maxChain int
+}
+
+// errCorrupt is returned when sectors are missing or chains are invalid.
+var errCorrupt = errors.New("ole2: corrupt data format")
+
+// sector returns the contents of the sector.
+func (d *Document) sector(sid uint32) ([]byte, error) {
+	size := int64(1) << d.header.SectorShift
+	offs := int64(1+sid) << d.header.SectorShift
+	if sid > secMaxRegular || offs+size > int64(len(d.data)) {
+		return nil, errCorrupt
+	}
+	return d.data[offs : offs+size], nil
+}
+
+// next returns the sector following sid in its chain.
+func (d *Document) next(sid uint32) (uint32, error) {
+	if int(sid) >= len(d.fat) {
+		return 0, errCorrupt
+	}
+	return d.fat[sid], nil
+}
+
+// checkChain returns an error if a chain of n sectors is too long.
+func (d *Document) checkChain(n int) error {
+	if d.maxChain > 0 && n > d.maxChain {
+		return grate.ErrLimitExceeded{Limit: "MaxSectorChain", Max: int64(d.maxChain)}
+	}
+	return nil
+}
+
+// checkFATChain is checkChain for chains of regular sectors, which
+// cannot be longer than the file unless they contain a loop.
+func (d *Document) checkFATChain(n int) error {
+	if n > len(d.data)>>d.header.SectorShift {
+		return errCorrupt
+	}
+	return d.checkChain(n)
 }
 
 func (d *Document) load(rx io.ReadSeeker) error {
@@ -159,6 +203,10 @@ func (d *Document) load(rx io.ReadSeeker) error {
 
 	numFATentries := (1 << (h.SectorShift - 2))
 	le := binary.LittleEndian
+	if h.NumFATSectors < 0 || int(h.NumFATSectors) > len(d.data)>>h.SectorShift ||
+		h.NumMiniFATSectors < 0 || int(h.NumMiniFATSectors) > len(d.data)>>h.SectorShift {
+		return errors.New("xls/cfb: invalid sector counts")
+	}
 	d.fat = make([]uint32, 0, numFATentries*int(1+d.header.NumFATSectors))
 	d.minifat = make([]uint32, 0, numFATentries*int(1+h.NumMiniFATSectors))
 
@@ -168,11 +216,10 @@ func (d *Document) load(rx io.ReadSeeker) error {
 		if sid == secFree {
 			break
 		}
-		offs := int64(1+sid) << int32(h.SectorShift)
-		if offs >= int64(len(d.data)) {
+		sector, err := d.sector(sid)
+		if err != nil {
 			return errors.New("xls/cfb: unable to load file")
 		}
-		sector := d.data[offs:]
 		for j := 0; j < numFATentries; j++ {
 			sid2 := le.Uint32(sector)
 			d.fat = append(d.fat, sid2)
@@ -182,9 +229,14 @@ func (d *Document) load(rx io.ReadSeeker) error {
 	if h.NumDIFATSectors > 0 {
 		sid1 := h.FirstDIFATSectorLocation
 
-		for sid1 != secEndOfChain {
-			offs := int64(1+sid1) << int32(h.SectorShift)
-			difatSector := d.data[offs:]
+		for n := 1; sid1 != secEndOfChain; n++ {
+			if err := d.checkFATChain(n); err != nil {
+				return err
+			}
+			difatSector, err := d.sector(sid1)
+			if err != nil {
+				return errors.New("xls/cfb: unable to load file")
+			}
 
 			for i := 0; i < numFATentries-1; i++ {
 				sid2 := le.Uint32(difatSector)
@@ -193,11 +245,10 @@ func (d *Document) load(rx io.ReadSeeker) error {
 					continue
 				}
 
-				offs := int64(1+sid2) << int32(h.SectorShift)
-				if offs >= int64(len(d.data)) {
+				sector, err := d.sector(sid2)
+				if err != nil {
 					return errors.New("xls/cfb: unable to load file")
 				}
-				sector := d.data[offs:]
 				for j := 0; j < numFATentries; j++ {
 					sid3 := le.Uint32(sector)
 					d.fat = append(d.fat, sid3)
@@ -213,24 +264,27 @@ func (d *Document) load(rx io.ReadSeeker) error {
 
 	// step 2: read the mini FAT
 	sid := h.FirstMiniFATSectorLocation
-	for sid != secEndOfChain {
-		offs := int64(1+sid) << int32(h.SectorShift)
-		if offs >= int64(len(d.data)) {
+	for n := 1; sid != secEndOfChain; n++ {
+		if err := d.checkFATChain(n); err != nil {
+			return err
+		}
+		sector, err := d.sector(sid)
+		if err != nil {
 			return errors.New("xls/cfb: unable to load file")
 		}
-		sector := d.data[offs:]
 		for j := 0; j < numFATentries; j++ {
-			sid = le.Uint32(sector)
-			d.minifat = append(d.minifat, sid)
+			d.minifat = append(d.minifat, le.Uint32(sector))
 			sector = sector[4:]
 		}
 
-		if len(d.minifat) >= int(h.NumMiniFATSectors) {
+		if len(d.minifat) >= int(h.NumMiniFATSectors)*numFATentries {
 			break
 		}
 
 		// chain the next mini FAT sector
-		sid = le.Uint32(sector)
+		if sid, err = d.next(sid); err != nil {
+			return err
+		}
 	}
 
 	// step 3: read the Directory Entries
@@ -242,57 +296,101 @@ func (d *Document) load(rx io.ReadSeeker) error {
 func (d *Document) buildDirs(br *bytes.Reader) error {
 	h := d.header
 	le := binary.LittleEndian
+	perSector := (1 << h.SectorShift) / 128
 
-	// step 2: read the Directory
+	// step 2: read the Directory, following the sector chain
 	sid := h.FirstDirectorySectorLocation
-	offs := int64(1+sid) << int64(h.SectorShift)
-	br.Seek(offs, io.SeekStart)
-
-	for j := 0; j < 4; j++ {
-		dirent := &directory{}
-		binary.Read(br, le, dirent)
-		if d.header.MajorVersion == 3 {
-			// mask out upper 32bits
-			dirent.StreamSize = dirent.StreamSize & 0xFFFFFFFF
-		}
-
-		switch dirent.ObjectType {
-		case typeRootStorage:
-			d.ministreamstart = uint32(dirent.StartingSectorLocation)
-			d.ministreamsize = uint32(dirent.StreamSize)
-		case typeStorage:
-			//log.Println("got a storage? what to do now?")
-		case typeStream:
-			/*
-				var freader io.Reader
-				if dirent.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
-					freader = d.getMiniStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
-				} else if dirent.StreamSize != 0 {
-					freader = d.getStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
-				}
-			*/
-		case typeUnknown:
-			return nil
+	for n := 0; sid != secEndOfChain && sid != secFree; n++ {
+		if int(sid) >= len(d.fat) || n > len(d.fat) {
+			return errors.New("xls/cfb: invalid directory chain")
 		}
-		d.dir = append(d.dir, dirent)
+		if err := d.checkFATChain(n + 1); err != nil {
+			return err
+		}
+		offs := int64(1+sid) << int64(h.SectorShift)
+		br.Seek(offs, io.SeekStart)
+
+		for j := 0; j < perSector; j++ {
+			dirent := &directory{}
+			if err := binary.Read(br, le, dirent); err != nil {
+				return err
+			}
+			if d.header.MajorVersion == 3 {
+				// mask out upper 32bits
+				dirent.StreamSize = dirent.StreamSize & 0xFFFFFFFF
+			}
+
+			switch dirent.ObjectType {
+			case typeRootStorage:
+				d.ministreamstart = uint32(dirent.StartingSectorLocation)
+				d.ministreamsize = uint32(dirent.StreamSize)
+			case typeStorage:
+				// children are resolved by path, see paths()
+			case typeStream:
+				/*
+					var freader io.Reader
+					if dirent.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
+						freader = d.getMiniStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
+					} else if dirent.StreamSize != 0 {
+						freader = d.getStreamReader(uint32(dirent.StartingSectorLocation), dirent.StreamSize)
+					}
+				*/
+			case typeUnknown:
+				// unused entry, kept so that stream IDs stay aligned
+			}
+			d.dir = append(d.dir, dirent)
+		}
+		sid = d.fat[sid]
 	}
 
 	return nil
 }
 
+// paths returns the full path of each directory entry, with storage
+// names separated by "/". Unreachable entries have an empty path.
+func (d *Document) paths() []string {
+	res := make([]string, len(d.dir))
+	if len(d.dir) == 0 {
+		return res
+	}
+	seen := make([]bool, len(d.dir))
+	var walk func(id uint32, prefix string)
+	walk = func(id uint32, prefix string) {
+		// siblings form a tree, so recurse to the left and right
+		if id >= uint32(len(d.dir)) || seen[id] {
+			return
+		}
+		seen[id] = true
+		e := d.dir[id]
+		res[id] = prefix + e.String()
+		walk(e.LeftSiblingID, prefix)
+		walk(e.RightSiblingID, prefix)
+		if e.ObjectType == typeStorage {
+			walk(e.ChildID, res[id]+"/")
+		}
+	}
+	seen[0] = true
+	walk(d.dir[0].ChildID, "")
+	return res
+}
+
 func (d *Document) getStreamReader(sid uint32, size uint64) (io.ReadSeeker, error) {
+	if size > uint64(len(d.data)) {
+		return nil, errCorrupt
+	}
 	// NB streamData is a slice of slices of the raw data, so this is the
 	// only allocation - for the (much smaller) list of sector slices
 	streamData := make([][]byte, 1+(size>>d.header.SectorShift))
 
 	x := 0
-	secSize := int64(1) << int32(d.header.SectorShift)
 	for sid != secEndOfChain && sid != secFree {
-		offs := int64(1+sid) << int64(d.header.SectorShift)
-		if offs > int64(len(d.data)) {
-			return nil, errors.New("ole2: corrupt data format")
+		if err := d.checkFATChain(x + 1); err != nil {
+			return nil, err
+		}
+		slice, err := d.sector(sid)
+		if err != nil || x >= len(streamData) {
+			return nil, errCorrupt
 		}
-		slice := d.data[offs : offs+secSize]
 		if size < uint64(len(slice)) {
 			slice = slice[:size]
 			size = 0
@@ -303,7 +401,9 @@ func (d *Document) getStreamReader(sid uint32, size uint64) (io.ReadSeeker, erro
 		if size == 0 {
 			break
 		}
-		sid = d.fat[sid]
+		if sid, err = d.next(sid); err != nil {
+			return nil, err
+		}
 		x++
 	}
 	if size != 0 {
@@ -314,6 +414,9 @@ func (d *Document) getStreamReader(sid uint32, size uint64) (io.ReadSeeker, erro
 }
 
 func (d *Document) getMiniStreamReader(sid uint32, size uint64) (io.ReadSeeker, error) {
+	if size > uint64(d.ministreamsize) || d.ministreamsize > uint32(len(d.data)) {
+		return nil, errCorrupt
+	}
 	// TODO: move into a separate cache so we don't recalculate it each time
 	fatStreamData := make([][]byte, 1+(d.ministreamsize>>d.header.SectorShift))
 
@@ -324,10 +427,14 @@ func (d *Document) getMiniStreamReader(sid uint32, size uint64) (io.ReadSeeker,
 	x := 0
 	fsid := d.ministreamstart
 	fsize := uint64(d.ministreamsize)
-	secSize := int64(1) << int64(d.header.SectorShift)
 	for fsid != secEndOfChain && fsid != secFree {
-		offs := int64(1+fsid) << int64(d.header.SectorShift)
-		slice := d.data[offs : offs+secSize]
+		if err := d.checkFATChain(x + 1); err != nil {
+			return nil, err
+		}
+		slice, err := d.sector(fsid)
+		if err != nil || x >= len(fatStreamData) {
+			return nil, errCorrupt
+		}
 		if fsize < uint64(len(slice)) {
 			slice = slice[:fsize]
 			fsize = 0
@@ -336,18 +443,31 @@ func (d *Document) getMiniStreamReader(sid uint32, size uint64) (io.ReadSeeker,
 		}
 		fatStreamData[x] = slice
 		x++
-		fsid = d.fat[fsid]
+		if fsid, err = d.next(fsid); err != nil {
+			return nil, err
+		}
 	}
 
 	x = 0
+	secSize := int64(1) << int64(d.header.SectorShift)
 	miniSecSize := int64(1) << int64(d.header.MiniSectorShift)
 	for sid != secEndOfChain && sid != secFree {
+		if err := d.checkChain(x + 1); err != nil {
+			return nil, err
+		}
 		offs := int64(sid) << int64(d.header.MiniSectorShift)
 
 		so, si := offs/secSize, offs%secSize
-		data := fatStreamData[so]
+		if so >= int64(len(fatStreamData)) || si > int64(len(fatStreamData[so])) ||
+			x >= len(streamData) || int(sid) >= len(d.minifat) {
+			return nil, errCorrupt
+		}
+		data := fatStreamData[so][si:]
 
-		slice := data[si : si+miniSecSize]
+		slice := data
+		if int64(len(slice)) > miniSecSize {
+			slice = slice[:miniSecSize]
+		}
 		if size < uint64(len(slice)) {
 			slice = slice[:size]
 			size = 0
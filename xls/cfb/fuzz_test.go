@@ -0,0 +1,37 @@
+//go:build go1.18
+// +build go1.18
+
+package cfb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzLoad(f *testing.F) {
+	files, _ := filepath.Glob("../../testdata/*.xls")
+	for _, fn := range files {
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := &Document{}
+		if err := d.load(bytes.NewReader(data)); err != nil {
+			return
+		}
+		names, _ := d.List()
+		for _, name := range names {
+			r, err := d.Open(name)
+			if err == nil {
+				io.Copy(io.Discard, r)
+			}
+		}
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/pbnjay/grate"
 )
 
 // Open a Compound File Binary Format document.
-func Open(filename string) (*Document, error) {
-	d := &Document{}
+// Sector chains are bounded by the MaxSectorChain limit in the options.
+func Open(filename string, opts ...grate.Option) (*Document, error) {
+	d := &Document{maxChain: grate.NewOptions(opts...).Limits.MaxSectorChain}
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -20,12 +23,14 @@ func Open(filename string) (*Document, error) {
 	return d, nil
 }
 
-// List the streams contained in the document.
+// List the streams contained in the document. Streams within storages
+// are named by their path, e.g. "_SX_DB_CUR/0001".
 func (d *Document) List() ([]string, error) {
 	var res []string
-	for _, e := range d.dir {
-		if e.ObjectType == typeStream {
-			res = append(res, e.String())
+	paths := d.paths()
+	for i, e := range d.dir {
+		if e.ObjectType == typeStream && paths[i] != "" {
+			res = append(res, paths[i])
 		}
 	}
 	return res, nil
@@ -33,14 +38,24 @@ func (d *Document) List() ([]string, error) {
 
 // Open the named stream contained in the document.
 func (d *Document) Open(name string) (io.ReadSeeker, error) {
+	paths := d.paths()
+	for i, e := range d.dir {
+		if paths[i] == name && e.ObjectType == typeStream {
+			return d.openStream(e)
+		}
+	}
+	// fall back to matching the bare stream name
 	for _, e := range d.dir {
 		if e.String() == name && e.ObjectType == typeStream {
-			if e.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
-				return d.getMiniStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
-			} else if e.StreamSize != 0 {
-				return d.getStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
-			}
+			return d.openStream(e)
 		}
 	}
 	return nil, fmt.Errorf("cfb: stream '%s' not found", name)
 }
+
+func (d *Document) openStream(e *directory) (io.ReadSeeker, error) {
+	if e.StreamSize < uint64(d.header.MiniStreamCutoffSize) {
+		return d.getMiniStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
+	}
+	return d.getStreamReader(uint32(e.StartingSectorLocation), e.StreamSize)
+}
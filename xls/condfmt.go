@@ -0,0 +1,232 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var errInvalidCF = errors.New("xls: invalid conditional formatting record")
+
+// 2.4.56 CondFmt, returns the ranges for the CF records that follow.
+func parseCondFmt(data []byte) ([]commonxl.Range, error) {
+	// skip ccf, flags and the bounding range
+	if len(data) < 14 {
+		return nil, errInvalidCF
+	}
+	cref := int(binary.LittleEndian.Uint16(data[12:]))
+	data = data[14:]
+	if len(data) < cref*8 {
+		return nil, errInvalidCF
+	}
+	res := make([]commonxl.Range, cref)
+	for i := range res {
+		res[i] = commonxl.Range{
+			FirstRow: int(binary.LittleEndian.Uint16(data)),
+			LastRow:  int(binary.LittleEndian.Uint16(data[2:])),
+			FirstCol: int(binary.LittleEndian.Uint16(data[4:])),
+			LastCol:  int(binary.LittleEndian.Uint16(data[6:])),
+		}
+		data = data[8:]
+	}
+	return res, nil
+}
+
+// 2.4.42 CF
+func parseCF(data []byte, ranges []commonxl.Range) (commonxl.ConditionalFormat, error) {
+	cf := commonxl.ConditionalFormat{Ranges: ranges}
+	if len(data) < 6 {
+		return cf, errInvalidCF
+	}
+	switch data[0] {
+	case 1:
+		cf.Type = "cellIs"
+		if data[1] > 0 {
+			cf.Operator = lookupName(comparisonOperators, int(data[1])-1)
+		}
+	case 2:
+		cf.Type = "expression"
+	default:
+		return cf, errInvalidCF
+	}
+	cce1 := int(binary.LittleEndian.Uint16(data[2:]))
+	cce2 := int(binary.LittleEndian.Uint16(data[4:]))
+
+	var n int
+	var err error
+	cf.Format, n, err = parseDXFN(data[6:])
+	if err != nil {
+		return cf, err
+	}
+	data = data[6+n:]
+	if len(data) < cce1+cce2 {
+		return cf, errInvalidCF
+	}
+
+	// relative references are based on the top-left cell of the first range
+	var baseRow, baseCol int
+	if len(ranges) > 0 {
+		baseRow, baseCol = ranges[0].FirstRow, ranges[0].FirstCol
+	}
+	for _, rgce := range [][]byte{data[:cce1], data[cce1 : cce1+cce2]} {
+		if len(rgce) == 0 {
+			continue
+		}
+		f, err := decodeFormula(rgce, baseRow, baseCol)
+		if err != nil && grate.Debug {
+			log.Println("xls: unable to decode conditional formatting formula:", err)
+		}
+		cf.Formulas = append(cf.Formulas, f)
+	}
+	return cf, nil
+}
+
+// DXFN flags indicating which formatting blocks are present
+const (
+	dxfnNumFmt    = uint32(1) << 25
+	dxfnFont      = uint32(1) << 26
+	dxfnAlignment = uint32(1) << 27
+	dxfnBorder    = uint32(1) << 28
+	dxfnPattern   = uint32(1) << 29
+	dxfnProtect   = uint32(1) << 30
+)
+
+// 2.5.39 DXFN, returns the formatting properties and the number of bytes used.
+// Colors are not resolved. Returns a nil Style if no formatting is present.
+func parseDXFN(data []byte) (*commonxl.Style, int, error) {
+	if len(data) < 6 {
+		return nil, 0, errInvalidCF
+	}
+	flags := binary.LittleEndian.Uint32(data)
+	userFmt := (data[4] & 0x01) != 0
+	off := 6
+	if (flags & (dxfnNumFmt | dxfnFont | dxfnAlignment | dxfnBorder | dxfnPattern)) == 0 {
+		if (flags & dxfnProtect) != 0 {
+			off += 2
+		}
+		return nil, off, nil
+	}
+	st := &commonxl.Style{}
+
+	if (flags & dxfnNumFmt) != 0 {
+		if len(data) < off+2 {
+			return nil, 0, errInvalidCF
+		}
+		if userFmt {
+			// 2.5.43 DXFNumUsr, the format string itself is not kept
+			cb := int(binary.LittleEndian.Uint16(data[off:]))
+			if cb < 2 {
+				return nil, 0, errInvalidCF
+			}
+			off += cb
+		} else {
+			// 2.5.42 DXFNum
+			st.NumFmt = uint16(data[off+1])
+			off += 2
+		}
+	}
+
+	if (flags & dxfnFont) != 0 {
+		// 2.5.45 DXFFntD
+		if len(data) < off+118 {
+			return nil, 0, errInvalidCF
+		}
+		fnt := data[off:]
+		height := binary.LittleEndian.Uint32(fnt[64:])
+		style := binary.LittleEndian.Uint32(fnt[68:])
+		bls := binary.LittleEndian.Uint16(fnt[72:])
+		icv := binary.LittleEndian.Uint32(fnt[80:])
+		styleNinch := binary.LittleEndian.Uint32(fnt[88:])
+		ulsNinch := binary.LittleEndian.Uint32(fnt[96:])
+		blsNinch := binary.LittleEndian.Uint32(fnt[100:])
+		if height <= 0x7FFF {
+			st.Font.Size = float64(height) / 20.0
+		}
+		if (styleNinch & 0x02) == 0 {
+			st.Font.Italic = (style & 0x02) != 0
+		}
+		if blsNinch == 0 {
+			st.Font.Bold = bls >= 700 && bls != 0xFFFF
+		}
+		if (styleNinch & 0x80) == 0 {
+			st.Font.Strike = (style & 0x80) != 0
+		}
+		if (ulsNinch & 0x01) == 0 {
+			st.Font.Underline = fnt[76] != 0 && fnt[76] <= 0x7F
+		}
+		if icv <= 0x7FFF {
+			st.Font.Color = icvColor(uint16(icv))
+		}
+		off += 118
+	}
+
+	if (flags & dxfnAlignment) != 0 {
+		off += 8 // 2.5.40 DXFALC
+	}
+
+	if (flags & dxfnBorder) != 0 {
+		// 2.5.41 DXFBdr, same layout as the XF border fields
+		if len(data) < off+8 {
+			return nil, 0, errInvalidCF
+		}
+		b1 := binary.LittleEndian.Uint32(data[off:])
+		b2 := binary.LittleEndian.Uint32(data[off+4:])
+		edges := []struct {
+			edge  *commonxl.BorderEdge
+			ninch uint32
+			dg    uint32
+			icv   uint32
+		}{
+			{&st.Border.Left, 1 << 10, b1 & 0x0F, (b1 >> 16) & 0x7F},
+			{&st.Border.Right, 1 << 11, (b1 >> 4) & 0x0F, (b1 >> 23) & 0x7F},
+			{&st.Border.Top, 1 << 12, (b1 >> 8) & 0x0F, b2 & 0x7F},
+			{&st.Border.Bottom, 1 << 13, (b1 >> 12) & 0x0F, (b2 >> 7) & 0x7F},
+		}
+		for _, e := range edges {
+			if (flags&e.ninch) != 0 || e.dg == 0 {
+				continue
+			}
+			e.edge.Style = lookupName(borderStyles, int(e.dg))
+			e.edge.Color = icvColor(uint16(e.icv))
+		}
+		off += 8
+	}
+
+	if (flags & dxfnPattern) != 0 {
+		// 2.5.44 DXFPat
+		if len(data) < off+4 {
+			return nil, 0, errInvalidCF
+		}
+		fls := int(binary.LittleEndian.Uint16(data[off:])>>10) & 0x3F
+		icv := binary.LittleEndian.Uint16(data[off+2:])
+		patternUsed := (flags & (1 << 16)) == 0
+		fgUsed := (flags & (1 << 17)) == 0
+		bgUsed := (flags & (1 << 18)) == 0
+
+		// solid fills only specify the background color
+		if bgUsed && (!patternUsed || fls == 1) {
+			st.Fill.Pattern = "solid"
+			st.Fill.FgColor = icvColor((icv >> 7) & 0x7F)
+		} else if patternUsed {
+			st.Fill.Pattern = lookupName(fillPatterns, fls)
+			if fgUsed {
+				st.Fill.FgColor = icvColor(icv & 0x7F)
+			}
+			if bgUsed {
+				st.Fill.BgColor = icvColor((icv >> 7) & 0x7F)
+			}
+		}
+		off += 4
+	}
+
+	if (flags & dxfnProtect) != 0 {
+		off += 2
+	}
+	if len(data) < off {
+		return nil, 0, errInvalidCF
+	}
+	return st, off, nil
+}
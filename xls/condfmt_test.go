@@ -0,0 +1,72 @@
+package xls
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseCF(t *testing.T) {
+	condfmt := []byte{1, 0, 1, 0, 1, 0, 9, 0, 1, 0, 1, 0, 1, 0, 1, 0, 9, 0, 1, 0, 1, 0}
+	ranges, err := parseCondFmt(condfmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 || ranges[0].String() != "B2:B10" {
+		t.Fatalf("unexpected ranges %v", ranges)
+	}
+
+	// cellIs greater than 0.8 with a red font and a solid yellow fill,
+	// the pattern itself is not specified
+	data := []byte{1, 5, 9, 0, 0, 0}
+	dxfn := make([]byte, 6+118+4)
+	binary.LittleEndian.PutUint32(dxfn, dxfnFont|dxfnPattern|(1<<16)|(1<<17)|0x3FF)
+	fnt := dxfn[6:]
+	binary.LittleEndian.PutUint32(fnt[64:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint16(fnt[72:], 700)
+	binary.LittleEndian.PutUint32(fnt[80:], 10)
+	binary.LittleEndian.PutUint32(fnt[88:], 0x82) // italic and strikeout unchanged
+	binary.LittleEndian.PutUint32(fnt[96:], 1)
+	binary.LittleEndian.PutUint32(fnt[100:], 0)
+	binary.LittleEndian.PutUint16(dxfn[6+118+2:], 13<<7)
+	data = append(data, dxfn...)
+	data = append(data, 0x1F, 0x9A, 0x99, 0x99, 0x99, 0x99, 0x99, 0xE9, 0x3F)
+
+	cf, err := parseCF(data, ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Type != "cellIs" || cf.Operator != "greaterThan" ||
+		len(cf.Formulas) != 1 || cf.Formulas[0] != "0.8" {
+		t.Errorf("unexpected rule %+v", cf)
+	}
+	st := cf.Format
+	if st == nil {
+		t.Fatal("expected formatting")
+	}
+	if !st.Font.Bold || st.Font.Size != 0 || st.Font.Color.Index != 10 {
+		t.Errorf("unexpected font %+v", st.Font)
+	}
+	if st.Font.Italic || st.Font.Strike {
+		t.Errorf("unexpected font style %+v", st.Font)
+	}
+	if st.Fill.Pattern != "solid" || st.Fill.FgColor.Index != 13 {
+		t.Errorf("unexpected fill %+v", st.Fill)
+	}
+	if st.Border.Left.Style != "" {
+		t.Errorf("unexpected border %+v", st.Border)
+	}
+
+	// an unchanged weight leaves the font bold unset
+	binary.LittleEndian.PutUint32(data[6+6+100:], 1)
+	cf, err = parseCF(data, ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Format.Font.Bold {
+		t.Errorf("unexpected bold font %+v", cf.Format.Font)
+	}
+
+	if _, err = parseCF(data[:20], ranges); err == nil {
+		t.Error("expected error for truncated record")
+	}
+}
@@ -1,6 +1,6 @@
 // Package crypto implements excel encryption algorithms from the
-// MS-OFFCRYPTO design specs. Currently only standard/basic RC4
-// "obfuscation" is supported.
+// MS-OFFCRYPTO design specs. Currently standard/basic RC4 "obfuscation"
+// and RC4 CryptoAPI encryption are supported.
 package crypto
 
 import (
@@ -94,10 +94,9 @@ func NewBasicRC4(data []byte) (Decryptor, error) {
 			len(data))
 	}
 
-	d := &rc4Writer{
-		Salt: make([]byte, len(h.Salt)),
-	}
-	copy(d.Salt, h.Salt[:])
+	k := &std97Keys{salt: make([]byte, len(h.Salt))}
+	copy(k.salt, h.Salt[:])
+	d := &rc4Writer{keys: k}
 
 	return d, d.Verify(h.Verifier[:], h.VerifierHash[:])
 }
@@ -0,0 +1,205 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// 2.3.5.1
+type cryptoAPIHeader struct {
+	MajorVersion uint16
+	MinorVersion uint16
+	Flags        uint32
+	HeaderSize   uint32
+}
+
+// 2.3.2
+type encryptionHeader struct {
+	Flags        uint32
+	SizeExtra    uint32
+	AlgID        uint32
+	AlgIDHash    uint32
+	KeySize      uint32 // in bits
+	ProviderType uint32
+	Reserved1    uint32
+	Reserved2    uint32
+	// followed by the null-terminated UTF-16 CSP name
+}
+
+// 2.3.3 (with the RC4 verifier hash size)
+type encryptionVerifier struct {
+	SaltSize              uint32
+	Salt                  [16]byte
+	EncryptedVerifier     [16]byte
+	VerifierHashSize      uint32
+	EncryptedVerifierHash [20]byte
+}
+
+const (
+	algRC4  = 0x6801
+	algSHA1 = 0x8004
+)
+
+// NewCryptoAPIRC4 implements RC4 CryptoAPI decryption, as used by
+// Excel 2002 and later. The data is the EncryptionInfo after the
+// encryption type of the FilePass record.
+func NewCryptoAPIRC4(data []byte) (Decryptor, error) {
+	h := cryptoAPIHeader{}
+	b := bytes.NewReader(data)
+	err := binary.Read(b, binary.LittleEndian, &h)
+	if err != nil {
+		return nil, err
+	}
+	if h.MajorVersion < 2 || h.MajorVersion > 4 || h.MinorVersion != 2 {
+		return nil, fmt.Errorf("xls: unknown RC4 CryptoAPI version %d.%d",
+			h.MajorVersion, h.MinorVersion)
+	}
+	if h.HeaderSize < 32 || int64(h.HeaderSize) > int64(b.Len()) {
+		return nil, fmt.Errorf("xls: invalid RC4 CryptoAPI header size %d", h.HeaderSize)
+	}
+
+	eh := encryptionHeader{}
+	err = binary.Read(b, binary.LittleEndian, &eh)
+	if err != nil {
+		return nil, err
+	}
+	if eh.AlgID != 0 && eh.AlgID != algRC4 {
+		return nil, fmt.Errorf("xls: unsupported CryptoAPI algorithm 0x%04x", eh.AlgID)
+	}
+	if eh.AlgIDHash != 0 && eh.AlgIDHash != algSHA1 {
+		return nil, fmt.Errorf("xls: unsupported CryptoAPI hash algorithm 0x%04x", eh.AlgIDHash)
+	}
+	keyBits := int(eh.KeySize)
+	if keyBits == 0 {
+		keyBits = 40
+	}
+	if keyBits < 40 || keyBits > 128 || keyBits%8 != 0 {
+		return nil, fmt.Errorf("xls: invalid RC4 CryptoAPI key size %d", eh.KeySize)
+	}
+
+	// skip the CSP name
+	data = data[8+4+h.HeaderSize:]
+
+	v := encryptionVerifier{}
+	err = binary.Read(bytes.NewReader(data), binary.LittleEndian, &v)
+	if err != nil {
+		return nil, err
+	}
+	if v.SaltSize != 16 || v.VerifierHashSize != 20 {
+		return nil, fmt.Errorf("xls: invalid RC4 CryptoAPI verifier")
+	}
+
+	k := &cryptoAPIKeys{salt: make([]byte, len(v.Salt)), keyBits: keyBits}
+	copy(k.salt, v.Salt[:])
+	d := &rc4Writer{keys: k}
+
+	return d, d.Verify(v.EncryptedVerifier[:], v.EncryptedVerifierHash[:])
+}
+
+// cryptoAPIKeys derives keys for RC4 CryptoAPI encryption, per 2.3.5.2.
+type cryptoAPIKeys struct {
+	salt    []byte
+	keyBits int
+	h0      []byte // H0 = SHA1(salt + password)
+}
+
+func (k *cryptoAPIKeys) setPassword(password []rune) {
+	h := sha1.New()
+	h.Write(k.salt)
+	h.Write(utf16le(password))
+	k.h0 = h.Sum(nil)
+}
+
+func (k *cryptoAPIKeys) blockKey(block uint32) []byte {
+	var bb [4]byte
+	binary.LittleEndian.PutUint32(bb[:], block)
+	h := sha1.New()
+	h.Write(k.h0)
+	h.Write(bb[:])
+	hfinal := h.Sum(nil)
+
+	if k.keyBits == 40 {
+		// 40-bit keys are padded with zeros to 128 bits
+		key := make([]byte, 16)
+		copy(key, hfinal[:5])
+		return key
+	}
+	return hfinal[:k.keyBits/8]
+}
+
+func (k *cryptoAPIKeys) verifierHash(verifier []byte) []byte {
+	h := sha1.Sum(verifier)
+	return h[:]
+}
+
+// DecryptSummary decrypts the streams stored in the "encryption" stream of
+// an RC4 CryptoAPI encrypted file, per 2.3.5.4. When the document properties
+// are encrypted, the SummaryInformation and DocumentSummaryInformation
+// streams are moved there. The result maps each stream name to its data.
+//
+// The stream descriptor array is decrypted as one RC4 run keyed with block
+// 0, and each stream as one run keyed with its block number.
+func DecryptSummary(dec Decryptor, data []byte) (map[string][]byte, error) {
+	d, ok := dec.(*rc4Writer)
+	if !ok {
+		return nil, errors.New("xls: not an RC4 CryptoAPI decryptor")
+	}
+	k, ok := d.keys.(*cryptoAPIKeys)
+	if !ok {
+		return nil, errors.New("xls: not an RC4 CryptoAPI decryptor")
+	}
+	if d.Password == nil {
+		d.SetPassword([]byte(DefaultXLSPassword))
+	}
+
+	if len(data) < 8 {
+		return nil, errors.New("xls: invalid encryption stream")
+	}
+	offs := uint64(binary.LittleEndian.Uint32(data))
+	size := uint64(binary.LittleEndian.Uint32(data[4:]))
+	if offs+size > uint64(len(data)) || size < 4 {
+		return nil, errors.New("xls: invalid stream descriptor array")
+	}
+	arr := make([]byte, size)
+	c, _ := rc4.NewCipher(k.blockKey(0))
+	c.XORKeyStream(arr, data[offs:offs+size])
+
+	count := binary.LittleEndian.Uint32(arr)
+	arr = arr[4:]
+	if uint64(count) > size/20 {
+		return nil, errors.New("xls: invalid stream descriptor count")
+	}
+
+	res := make(map[string][]byte, count)
+	for i := uint32(0); i < count; i++ {
+		// 16 bytes, the stream name, then 4 reserved bytes
+		if len(arr) < 16 {
+			return nil, errors.New("xls: invalid stream descriptor")
+		}
+		soffs := uint64(binary.LittleEndian.Uint32(arr))
+		ssize := uint64(binary.LittleEndian.Uint32(arr[4:]))
+		block := binary.LittleEndian.Uint16(arr[8:])
+		nameSize := int(arr[10])
+		arr = arr[16:]
+		if len(arr) < nameSize*2+4 || soffs+ssize > uint64(len(data)) {
+			return nil, errors.New("xls: invalid stream descriptor")
+		}
+
+		name := make([]uint16, nameSize)
+		for j := range name {
+			name[j] = binary.LittleEndian.Uint16(arr[j*2:])
+		}
+		arr = arr[nameSize*2+4:]
+
+		sdata := make([]byte, ssize)
+		c, _ = rc4.NewCipher(k.blockKey(uint32(block)))
+		c.XORKeyStream(sdata, data[soffs:soffs+ssize])
+		res[string(utf16.Decode(name))] = sdata
+	}
+	return res, nil
+}
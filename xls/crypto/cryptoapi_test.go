@@ -0,0 +1,215 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+var testSalt = []byte{
+	0x3d, 0x1a, 0x5e, 0x0c, 0x72, 0x91, 0xaa, 0x04,
+	0xe8, 0x33, 0x6f, 0xb0, 0x19, 0xc4, 0x58, 0x27,
+}
+
+// testKey derives an RC4 CryptoAPI block key, per MS-OFFCRYPTO 2.3.5.2.
+func testKey(password string, keyBits int, block uint32) []byte {
+	h := sha1.New()
+	h.Write(testSalt)
+	for _, c := range utf16.Encode([]rune(password)) {
+		binary.Write(h, binary.LittleEndian, c)
+	}
+	h0 := h.Sum(nil)
+
+	h = sha1.New()
+	h.Write(h0)
+	binary.Write(h, binary.LittleEndian, block)
+	hfinal := h.Sum(nil)
+	if keyBits == 0 || keyBits == 40 {
+		return append(hfinal[:5], make([]byte, 11)...)
+	}
+	return hfinal[:keyBits/8]
+}
+
+// testEncrypt encrypts data in 1024-byte blocks.
+func testEncrypt(password string, keyBits int, data []byte) []byte {
+	res := make([]byte, len(data))
+	for i := 0; i < len(data); i += 1024 {
+		end := i + 1024
+		if end > len(data) {
+			end = len(data)
+		}
+		c, _ := rc4.NewCipher(testKey(password, keyBits, uint32(i/1024)))
+		c.XORKeyStream(res[i:end], data[i:end])
+	}
+	return res
+}
+
+// testCryptoAPIInfo builds the EncryptionInfo of a FilePass record.
+func testCryptoAPIInfo(password string, major uint16, keyBits int) []byte {
+	csp := utf16.Encode([]rune("Microsoft Enhanced Cryptographic Provider v1.0\x00"))
+
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.LittleEndian, major)
+	binary.Write(b, binary.LittleEndian, uint16(2))
+	binary.Write(b, binary.LittleEndian, uint32(0x04))
+	binary.Write(b, binary.LittleEndian, uint32(32+len(csp)*2))
+
+	binary.Write(b, binary.LittleEndian, encryptionHeader{
+		Flags:        0x04,
+		AlgID:        algRC4,
+		AlgIDHash:    algSHA1,
+		KeySize:      uint32(keyBits),
+		ProviderType: 1,
+	})
+	binary.Write(b, binary.LittleEndian, csp)
+
+	verifier := []byte("0123456789abcdef")
+	vhash := sha1.Sum(verifier)
+	enc := make([]byte, 36)
+	c, _ := rc4.NewCipher(testKey(password, keyBits, 0))
+	c.XORKeyStream(enc, append(verifier, vhash[:]...))
+
+	binary.Write(b, binary.LittleEndian, uint32(16))
+	b.Write(testSalt)
+	b.Write(enc[:16])
+	binary.Write(b, binary.LittleEndian, uint32(20))
+	b.Write(enc[16:])
+	return b.Bytes()
+}
+
+func TestCryptoAPIRC4(t *testing.T) {
+	plain := make([]byte, 3000)
+	for i := range plain {
+		plain[i] = byte(i * 7)
+	}
+
+	for _, major := range []uint16{2, 3, 4} {
+		for _, keyBits := range []int{0, 40, 56, 128} {
+			dec, err := NewCryptoAPIRC4(testCryptoAPIInfo(DefaultXLSPassword, major, keyBits))
+			if err != nil {
+				t.Fatalf("version %d, %d bits: %v", major, keyBits, err)
+			}
+			dec.Write(testEncrypt(DefaultXLSPassword, keyBits, plain))
+			dec.Flush()
+			if !bytes.Equal(dec.Bytes(), plain) {
+				t.Errorf("version %d, %d bits: decrypted data does not match", major, keyBits)
+			}
+		}
+	}
+}
+
+func TestCryptoAPIRC4Password(t *testing.T) {
+	info := testCryptoAPIInfo("secret", 4, 128)
+	dec, err := NewCryptoAPIRC4(info)
+	if err == nil {
+		t.Fatal("expected default password verification to fail")
+	}
+
+	dec.SetPassword([]byte("secret"))
+	plain := []byte("some encrypted workbook data")
+	dec.Write(testEncrypt("secret", 128, plain))
+	dec.Flush()
+	if !bytes.Equal(dec.Bytes(), plain) {
+		t.Error("decrypted data does not match")
+	}
+}
+
+func TestCryptoAPIRC4Invalid(t *testing.T) {
+	valid := testCryptoAPIInfo(DefaultXLSPassword, 4, 128)
+	tests := map[string]func([]byte){
+		"version":     func(b []byte) { b[0] = 5 },
+		"minor":       func(b []byte) { b[2] = 1 },
+		"header size": func(b []byte) { b[8] = 0x10 },
+		"algorithm":   func(b []byte) { b[20] = 0x10 },
+		"hash":        func(b []byte) { b[24] = 0x03 },
+		"key size":    func(b []byte) { b[28] = 0x30 },
+		"salt size":   func(b []byte) { b[len(b)-60] = 8 },
+		"verifier":    func(b []byte) { b[len(b)-1] ^= 0xFF },
+	}
+	for name, corrupt := range tests {
+		data := append([]byte{}, valid...)
+		corrupt(data)
+		if _, err := NewCryptoAPIRC4(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewCryptoAPIRC4(valid[:len(valid)-10]); err == nil {
+		t.Error("truncated: expected an error")
+	}
+}
+
+func TestDecryptSummary(t *testing.T) {
+	dec, err := NewCryptoAPIRC4(testCryptoAPIInfo(DefaultXLSPassword, 2, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams := []struct {
+		name  string
+		data  []byte
+		block uint16
+	}{
+		{"\x05SummaryInformation", []byte("summary property set"), 0},
+		{"\x05DocumentSummaryInformation", []byte("document summary property set"), 1},
+	}
+
+	// encrypted streams follow the 8 byte header, then the descriptor array
+	body := &bytes.Buffer{}
+	descs := &bytes.Buffer{}
+	binary.Write(descs, binary.LittleEndian, uint32(len(streams)))
+	for _, s := range streams {
+		c, _ := rc4.NewCipher(testKey(DefaultXLSPassword, 40, uint32(s.block)))
+		enc := make([]byte, len(s.data))
+		c.XORKeyStream(enc, s.data)
+
+		name := utf16.Encode([]rune(s.name))
+		binary.Write(descs, binary.LittleEndian, uint32(8+body.Len()))
+		binary.Write(descs, binary.LittleEndian, uint32(len(s.data)))
+		binary.Write(descs, binary.LittleEndian, s.block)
+		descs.WriteByte(byte(len(name)))
+		descs.WriteByte(1)
+		binary.Write(descs, binary.LittleEndian, uint32(0)) // Reserved1
+		binary.Write(descs, binary.LittleEndian, name)
+		binary.Write(descs, binary.LittleEndian, uint32(0)) // Reserved2
+		body.Write(enc)
+	}
+
+	encDescs := make([]byte, descs.Len())
+	c, _ := rc4.NewCipher(testKey(DefaultXLSPassword, 40, 0))
+	c.XORKeyStream(encDescs, descs.Bytes())
+
+	raw := make([]byte, 8, 8+body.Len()+len(encDescs))
+	binary.LittleEndian.PutUint32(raw, uint32(8+body.Len()))
+	binary.LittleEndian.PutUint32(raw[4:], uint32(len(encDescs)))
+	raw = append(raw, body.Bytes()...)
+	raw = append(raw, encDescs...)
+
+	res, err := DecryptSummary(dec, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(streams) {
+		t.Fatalf("got %d streams, expected %d", len(res), len(streams))
+	}
+	for _, s := range streams {
+		if !bytes.Equal(res[s.name], s.data) {
+			t.Errorf("%q: got %q, expected %q", s.name, res[s.name], s.data)
+		}
+	}
+
+	for i := 0; i < len(raw); i++ {
+		if _, err := DecryptSummary(dec, raw[:i]); err == nil {
+			t.Errorf("expected an error for a %d byte stream", i)
+		}
+	}
+
+	hdr := make([]byte, 52)
+	hdr[0], hdr[2] = 1, 1
+	basic, _ := NewBasicRC4(hdr)
+	if _, err := DecryptSummary(basic, raw); err == nil {
+		t.Error("expected an error for a basic RC4 decryptor")
+	}
+}
@@ -59,17 +59,28 @@ func (d *rc4Writer) Flush() {
 	d.block++
 }
 
-// SetPassword for the decryption.
+// SetPassword for the decryption. An empty password selects the default.
 func (d *rc4Writer) SetPassword(password []byte) {
+	if len(password) == 0 {
+		password = []byte(DefaultXLSPassword)
+	}
 	d.Password = make([]rune, len(password))
 	for i, p := range password {
 		d.Password[i] = rune(p)
 	}
+	d.keys.setPassword(d.Password)
+}
+
+// rc4Keys derives the RC4 keys used by each 1024-byte block.
+type rc4Keys interface {
+	// setPassword computes the base key from the password.
+	setPassword(password []rune)
 
-	/// compute the first part of the encryption key
-	result := generateStd97Key(d.Password, d.Salt)
-	d.encKey = make([]byte, len(result))
-	copy(d.encKey, result)
+	// blockKey returns the RC4 key for the block number.
+	blockKey(block uint32) []byte
+
+	// verifierHash returns the hash of a decrypted verifier.
+	verifierHash(verifier []byte) []byte
 }
 
 type rc4Writer struct {
@@ -85,10 +96,7 @@ type rc4Writer struct {
 	// decrypter for RC4 content streams
 	dec *rc4.Cipher
 
-	cipherKey []byte // H1 per 2.3.6.2
-	encKey    []byte // Hfinal per 2.3.6.2
-
-	Salt     []byte
+	keys     rc4Keys
 	Password []rune
 }
 
@@ -96,20 +104,18 @@ func (d *rc4Writer) Bytes() []byte {
 	return d.buf.Bytes()
 }
 
+// Verify the password using the encrypted verifier and its hash.
 func (d *rc4Writer) Verify(everifier, everifierHash []byte) error {
 	d.Reset()
 	d.startBlock()
 
-	var temp1 [16]byte
-	var temp2 [16]byte
-	d.dec.XORKeyStream(temp1[:], everifier)
-	d.dec.XORKeyStream(temp2[:], everifierHash)
+	temp1 := make([]byte, len(everifier))
+	temp2 := make([]byte, len(everifierHash))
+	d.dec.XORKeyStream(temp1, everifier)
+	d.dec.XORKeyStream(temp2, everifierHash)
 
-	newhash := md5.Sum(temp1[:])
-	for i, c := range newhash {
-		if temp2[i] != c {
-			return fmt.Errorf("verification failed")
-		}
+	if !bytes.Equal(d.keys.verifierHash(temp1), temp2) {
+		return fmt.Errorf("verification failed")
 	}
 	return nil
 }
@@ -117,27 +123,46 @@ func (d *rc4Writer) Verify(everifier, everifierHash []byte) error {
 /////////////////////
 
 func (d *rc4Writer) startBlock() {
-	if d.encKey == nil {
+	if d.Password == nil {
 		d.SetPassword([]byte(DefaultXLSPassword))
 	}
+	d.dec, _ = rc4.NewCipher(d.keys.blockKey(d.block))
+}
 
-	d.cipherKey = make([]byte, 16)
-	copy(d.cipherKey, d.encKey[:5])
-	binary.LittleEndian.PutUint32(d.cipherKey[5:], d.block)
-	mhash := md5.Sum(d.cipherKey[:9])
-	d.dec, _ = rc4.NewCipher(mhash[:])
+// std97Keys derives keys for standard RC4 encryption, per 2.3.6.2.
+type std97Keys struct {
+	salt   []byte
+	encKey []byte // H1 per 2.3.6.2
 }
 
-func generateStd97Key(passData []rune, salt []byte) []byte {
-	if len(passData) == 0 || len(salt) != 16 {
-		panic("invalid keygen material")
-	}
+func (k *std97Keys) setPassword(password []rune) {
+	k.encKey = generateStd97Key(password, k.salt)
+}
 
-	passBytes := make([]byte, len(passData)*2)
+func (k *std97Keys) blockKey(block uint32) []byte {
+	cipherKey := make([]byte, 9)
+	copy(cipherKey, k.encKey[:5])
+	binary.LittleEndian.PutUint32(cipherKey[5:], block)
+	mhash := md5.Sum(cipherKey)
+	return mhash[:]
+}
+
+func (k *std97Keys) verifierHash(verifier []byte) []byte {
+	h := md5.Sum(verifier)
+	return h[:]
+}
 
+// utf16le encodes the password as UTF-16LE bytes.
+func utf16le(passData []rune) []byte {
+	passBytes := make([]byte, len(passData)*2)
 	for i, c := range passData {
 		binary.LittleEndian.PutUint16(passBytes[2*i:], uint16(c))
 	}
+	return passBytes
+}
+
+func generateStd97Key(passData []rune, salt []byte) []byte {
+	passBytes := utf16le(passData)
 
 	// digest the IV then copy back into pKeyData
 	h0 := md5.Sum(passBytes)
@@ -0,0 +1,161 @@
+package xls
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/xls/cfb"
+	"github.com/pbnjay/grate/xls/crypto"
+)
+
+var testSalt = []byte("0123456789abcdef")
+
+// cryptoAPIKey derives an RC4 CryptoAPI block key for the default
+// password, per MS-OFFCRYPTO 2.3.5.2.
+func cryptoAPIKey(keyBits uint32, block uint32) []byte {
+	h := sha1.New()
+	h.Write(testSalt)
+	for _, c := range utf16.Encode([]rune(crypto.DefaultXLSPassword)) {
+		binary.Write(h, binary.LittleEndian, c)
+	}
+	h0 := h.Sum(nil)
+
+	h = sha1.New()
+	h.Write(h0)
+	binary.Write(h, binary.LittleEndian, block)
+	hfinal := h.Sum(nil)
+	if keyBits == 0 || keyBits == 40 {
+		return append(hfinal[:5], make([]byte, 11)...)
+	}
+	return hfinal[:keyBits/8]
+}
+
+// cryptoAPIInfo builds the EncryptionInfo of an RC4 CryptoAPI FilePass
+// record for the default password.
+func cryptoAPIInfo(keyBits uint32) []byte {
+	csp := utf16.Encode([]rune("Microsoft Enhanced Cryptographic Provider v1.0\x00"))
+	verifier := []byte("fedcba9876543210")
+	vhash := sha1.Sum(verifier)
+	enc := make([]byte, 36)
+	c, _ := rc4.NewCipher(cryptoAPIKey(keyBits, 0))
+	c.XORKeyStream(enc, append(verifier, vhash[:]...))
+
+	b := &bytes.Buffer{}
+	for _, x := range []interface{}{
+		uint16(4), uint16(2), uint32(0x04), uint32(32 + len(csp)*2),
+		// Flags, SizeExtra, AlgID, AlgIDHash, KeySize, ProviderType, Reserved
+		uint32(0x04), uint32(0), uint32(0x6801), uint32(0x8004), keyBits, uint32(1), uint64(0),
+		csp, uint32(16), testSalt, enc[:16], uint32(20), enc[16:],
+	} {
+		binary.Write(b, binary.LittleEndian, x)
+	}
+	return b.Bytes()
+}
+
+// encryptStream inserts a FilePass record after the first BOF record of a
+// workbook stream, and encrypts the records as described in MS-XLS 2.2.10.
+func encryptStream(raw []byte, keyBits uint32) []byte {
+	filePass := append([]byte{1, 0}, cryptoAPIInfo(keyBits)...)
+	shift := uint32(4 + len(filePass))
+
+	plain := &bytes.Buffer{}
+	type span struct{ pos, n int }
+	var clear []span
+	for pos := 0; pos+4 <= len(raw); {
+		typ := recordType(binary.LittleEndian.Uint16(raw[pos:]))
+		n := int(binary.LittleEndian.Uint16(raw[pos+2:]))
+		data := append([]byte{}, raw[pos+4:pos+4+n]...)
+
+		clear = append(clear, span{plain.Len(), 4})
+		switch typ {
+		case RecTypeBOF, RecTypeUsrExcl, RecTypeFileLock, RecTypeInterfaceHdr, RecTypeRRDInfo, RecTypeRRDHead:
+			clear = append(clear, span{plain.Len() + 4, n})
+		case RecTypeBoundSheet8:
+			binary.LittleEndian.PutUint32(data, binary.LittleEndian.Uint32(data)+shift)
+			clear = append(clear, span{plain.Len() + 4, 4})
+		}
+		plain.Write(raw[pos : pos+4])
+		plain.Write(data)
+
+		if pos == 0 {
+			clear = append(clear, span{plain.Len(), int(shift)})
+			binary.Write(plain, binary.LittleEndian, uint16(RecTypeFilePass))
+			binary.Write(plain, binary.LittleEndian, uint16(len(filePass)))
+			plain.Write(filePass)
+		}
+		pos += 4 + n
+	}
+
+	// RC4 in 1024 byte blocks, with the block number in each key
+	res := make([]byte, plain.Len())
+	for i := 0; i < len(res); i += 1024 {
+		end := i + 1024
+		if end > len(res) {
+			end = len(res)
+		}
+		c, _ := rc4.NewCipher(cryptoAPIKey(keyBits, uint32(i/1024)))
+		c.XORKeyStream(res[i:end], plain.Bytes()[i:end])
+	}
+	for _, s := range clear {
+		copy(res[s.pos:s.pos+s.n], plain.Bytes()[s.pos:])
+	}
+	return res
+}
+
+func loadTestStream(t *testing.T, raw []byte) map[string][][]string {
+	b := &WorkBook{
+		pos2substream: make(map[int64]int, 16),
+		opts:          grate.NewOptions(),
+	}
+	if err := b.loadFromStream(raw); err != nil {
+		t.Fatal(err)
+	}
+	names, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string][][]string, len(names))
+	for _, name := range names {
+		c, err := b.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for c.Next() {
+			res[name] = append(res[name], c.Strings())
+		}
+	}
+	return res
+}
+
+func TestCryptoAPIWorkbook(t *testing.T) {
+	doc, err := cfb.Open("../testdata/basic.xls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := doc.Open("Workbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := loadTestStream(t, raw)
+	if len(expected) == 0 {
+		t.Fatal("no sheets in test workbook")
+	}
+	for _, keyBits := range []uint32{0, 128} {
+		got := loadTestStream(t, encryptStream(raw, keyBits))
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%d bit key: decrypted workbook does not match", keyBits)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+var errUnsupportedFormula = errors.New("xls: unsupported formula token")
+
+// binary operator tokens (2.5.198.25 PtgAdd through PtgNe)
+var ptgOperators = map[byte]string{
+	0x03: "+", 0x04: "-", 0x05: "*", 0x06: "/", 0x07: "^", 0x08: "&",
+	0x09: "<", 0x0A: "<=", 0x0B: "=", 0x0C: ">=", 0x0D: ">", 0x0E: "<>",
+}
+
+// decodeFormula renders a simple parsed formula (2.5.198.104 Rgce) as text.
+// Only constants, cell references and operators are supported, which covers
+// the values typically found in data validation and conditional formatting.
+// Relative references (PtgRefN, PtgAreaN) are resolved against the base cell.
+func decodeFormula(rgce []byte, baseRow, baseCol int) (string, error) {
+	var stack []string
+	pop := func() (string, error) {
+		if len(stack) == 0 {
+			return "", errors.New("xls: invalid formula")
+		}
+		x := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return x, nil
+	}
+
+	for len(rgce) > 0 {
+		ptg := rgce[0]
+		rgce = rgce[1:]
+
+		if op, ok := ptgOperators[ptg]; ok {
+			b, err := pop()
+			if err != nil {
+				return "", err
+			}
+			a, err := pop()
+			if err != nil {
+				return "", err
+			}
+			stack = append(stack, a+op+b)
+			continue
+		}
+
+		switch ptg {
+		case 0x12, 0x13, 0x14: // PtgUplus, PtgUminus, PtgPercent
+			a, err := pop()
+			if err != nil {
+				return "", err
+			}
+			switch ptg {
+			case 0x12:
+				a = "+" + a
+			case 0x13:
+				a = "-" + a
+			case 0x14:
+				a += "%"
+			}
+			stack = append(stack, a)
+		case 0x15: // PtgParen
+			a, err := pop()
+			if err != nil {
+				return "", err
+			}
+			stack = append(stack, "("+a+")")
+
+		case 0x17: // PtgStr
+			if len(rgce) < 2 {
+				return "", errors.New("xls: invalid formula string")
+			}
+			n := int(rgce[0])
+			if (rgce[1] & 1) != 0 {
+				n *= 2
+			}
+			if len(rgce) < 2+n {
+				return "", errors.New("xls: invalid formula string")
+			}
+			str, _, err := decodeShortXLUnicodeString(rgce)
+			if err != nil {
+				return "", err
+			}
+			rgce = rgce[2+n:]
+			// explicit list values are separated by NULs
+			str = strings.ReplaceAll(str, "\x00", ",")
+			stack = append(stack, `"`+strings.ReplaceAll(str, `"`, `""`)+`"`)
+		case 0x1D: // PtgBool
+			if len(rgce) < 1 {
+				return "", errors.New("xls: invalid formula")
+			}
+			if rgce[0] != 0 {
+				stack = append(stack, "TRUE")
+			} else {
+				stack = append(stack, "FALSE")
+			}
+			rgce = rgce[1:]
+		case 0x1E: // PtgInt
+			if len(rgce) < 2 {
+				return "", errors.New("xls: invalid formula")
+			}
+			stack = append(stack, strconv.Itoa(int(binary.LittleEndian.Uint16(rgce))))
+			rgce = rgce[2:]
+		case 0x1F: // PtgNum
+			if len(rgce) < 8 {
+				return "", errors.New("xls: invalid formula")
+			}
+			f := math.Float64frombits(binary.LittleEndian.Uint64(rgce))
+			stack = append(stack, strconv.FormatFloat(f, 'g', -1, 64))
+			rgce = rgce[8:]
+
+		case 0x24, 0x44, 0x64: // PtgRef
+			if len(rgce) < 4 {
+				return "", errors.New("xls: invalid formula")
+			}
+			rw := binary.LittleEndian.Uint16(rgce)
+			col := binary.LittleEndian.Uint16(rgce[2:])
+			stack = append(stack, formulaRef(rw, col))
+			rgce = rgce[4:]
+		case 0x25, 0x45, 0x65: // PtgArea
+			if len(rgce) < 8 {
+				return "", errors.New("xls: invalid formula")
+			}
+			rw1 := binary.LittleEndian.Uint16(rgce)
+			rw2 := binary.LittleEndian.Uint16(rgce[2:])
+			col1 := binary.LittleEndian.Uint16(rgce[4:])
+			col2 := binary.LittleEndian.Uint16(rgce[6:])
+			stack = append(stack, formulaRef(rw1, col1)+":"+formulaRef(rw2, col2))
+			rgce = rgce[8:]
+
+		case 0x2C, 0x4C, 0x6C: // PtgRefN
+			if len(rgce) < 4 {
+				return "", errors.New("xls: invalid formula")
+			}
+			rw, col := relativeLoc(binary.LittleEndian.Uint16(rgce),
+				binary.LittleEndian.Uint16(rgce[2:]), baseRow, baseCol)
+			stack = append(stack, formulaRef(rw, col))
+			rgce = rgce[4:]
+		case 0x2D, 0x4D, 0x6D: // PtgAreaN
+			if len(rgce) < 8 {
+				return "", errors.New("xls: invalid formula")
+			}
+			rw1, col1 := relativeLoc(binary.LittleEndian.Uint16(rgce),
+				binary.LittleEndian.Uint16(rgce[4:]), baseRow, baseCol)
+			rw2, col2 := relativeLoc(binary.LittleEndian.Uint16(rgce[2:]),
+				binary.LittleEndian.Uint16(rgce[6:]), baseRow, baseCol)
+			stack = append(stack, formulaRef(rw1, col1)+":"+formulaRef(rw2, col2))
+			rgce = rgce[8:]
+
+		default:
+			return "", fmt.Errorf("%w 0x%02x", errUnsupportedFormula, ptg)
+		}
+	}
+	if len(stack) != 1 {
+		return "", errors.New("xls: invalid formula")
+	}
+	return stack[0], nil
+}
+
+// relativeLoc converts a RgceLocRel offset into an absolute location,
+// keeping the relative flags.
+func relativeLoc(rw, col uint16, baseRow, baseCol int) (uint16, uint16) {
+	if (col & 0x8000) != 0 {
+		rw = uint16(baseRow + int(int16(rw)))
+	}
+	if (col & 0x4000) != 0 {
+		c := uint16(baseCol+int(int8(col&0xFF))) & 0xFF
+		col = (col & 0xC000) | c
+	}
+	return rw, col
+}
+
+// formulaRef renders a RgceLoc / RgceLocRel cell reference in A1 notation.
+func formulaRef(rw, col uint16) string {
+	ref := ""
+	if (col & 0x4000) == 0 {
+		ref += "$"
+	}
+	ref += commonxl.ColumnName(int(col & 0x3FFF))
+	if (col & 0x8000) == 0 {
+		ref += "$"
+	}
+	return ref + strconv.Itoa(int(rw)+1)
+}
@@ -0,0 +1,64 @@
+//go:build go1.18
+// +build go1.18
+
+package xls
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/xls/cfb"
+)
+
+// FuzzWorkbook parses the Workbook stream of an xls file. The parser
+// recovers from panics, so any ParseError is a failure.
+func FuzzWorkbook(f *testing.F) {
+	files, _ := filepath.Glob("../testdata/*.xls")
+	for _, fn := range files {
+		doc, err := cfb.Open(fn)
+		if err != nil {
+			f.Fatal(err)
+		}
+		r, err := doc.Open("Workbook")
+		if err != nil {
+			f.Fatal(err)
+		}
+		raw, err := io.ReadAll(r)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		var perr grate.ParseError
+		b := &WorkBook{
+			pos2substream: make(map[int64]int, 16),
+			opts:          grate.NewOptions(),
+		}
+		err := b.loadFromStream(raw)
+		if errors.As(err, &perr) {
+			t.Fatal(err)
+		}
+		if err != nil {
+			return
+		}
+		names, _ := b.List()
+		for _, name := range names {
+			c, err := b.Get(name)
+			if errors.As(err, &perr) {
+				t.Fatal(err)
+			}
+			if err != nil || c == nil {
+				continue
+			}
+			for c.Next() {
+				c.Strings()
+				c.Types()
+			}
+		}
+	})
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"strings"
 	"unicode/utf16"
+
+	"github.com/pbnjay/grate/commonxl"
 )
 
-func decodeHyperlinks(raw []byte) (displayText, linkText string, err error) {
+// 2.5.104 HyperlinkObject, as specified in [MS-OSHARED] section 2.3.7.1
+func decodeHyperlink(raw []byte) (*commonxl.Hyperlink, error) {
+	h := &commonxl.Hyperlink{}
 	raw = raw[16:] // skip classid
 	slen := binary.LittleEndian.Uint32(raw[:4])
 	if slen != 2 {
-		return "", "", errors.New("xls: unknown hyperlink version")
+		return nil, errors.New("xls: unknown hyperlink version")
 	}
 
 	flags := binary.LittleEndian.Uint32(raw[4:8])
@@ -25,7 +29,7 @@ func decodeHyperlinks(raw []byte) (displayText, linkText string, err error) {
 			us[i] = binary.LittleEndian.Uint16(raw)
 			raw = raw[2:]
 		}
-		displayText = string(utf16.Decode(us))
+		h.Display = string(utf16.Decode(us))
 	}
 
 	if (flags & hlstmfHasFrameName) != 0 {
@@ -44,15 +48,18 @@ func decodeHyperlinks(raw []byte) (displayText, linkText string, err error) {
 				us[i] = binary.LittleEndian.Uint16(raw)
 				raw = raw[2:]
 			}
-			linkText = string(utf16.Decode(us))
+			h.URL = string(utf16.Decode(us))
 
 		} else {
-			n := 0
-			var err error
-			linkText, n, err = parseHyperlinkMoniker(raw)
-			raw = raw[n:]
+			link, isFile, n, err := parseHyperlinkMoniker(raw)
 			if err != nil {
-				return "", "", err
+				return nil, err
+			}
+			raw = raw[n:]
+			if isFile {
+				h.File = link
+			} else {
+				h.URL = link
 			}
 		}
 	}
@@ -65,15 +72,35 @@ func decodeHyperlinks(raw []byte) (displayText, linkText string, err error) {
 			us[i] = binary.LittleEndian.Uint16(raw)
 			raw = raw[2:]
 		}
-		linkText = string(utf16.Decode(us))
+		h.Location = string(utf16.Decode(us))
+	}
+
+	for _, x := range []*string{&h.URL, &h.File, &h.Location, &h.Display} {
+		*x = strings.Trim(*x, " \v\f\t\r\n\x00")
 	}
+	return h, nil
+}
 
-	linkText = strings.Trim(linkText, " \v\f\t\r\n\x00")
-	displayText = strings.Trim(displayText, " \v\f\t\r\n\x00")
-	return
+// 2.4.141 HLinkTooltip, returns the tooltip text.
+func decodeHyperlinkTooltip(raw []byte) string {
+	if len(raw) < 10 {
+		return ""
+	}
+	raw = raw[10:] // skip frtRefHeaderNoGrbit
+	us := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		u := binary.LittleEndian.Uint16(raw[i:])
+		if u == 0 {
+			break
+		}
+		us = append(us, u)
+	}
+	return string(utf16.Decode(us))
 }
 
-func parseHyperlinkMoniker(raw []byte) (string, int, error) {
+// parseHyperlinkMoniker returns the target of a URL or file moniker,
+// and true if it is a file moniker.
+func parseHyperlinkMoniker(raw []byte) (string, bool, int, error) {
 	classid := raw[:16]
 	no := 16
 
@@ -101,7 +128,7 @@ func parseHyperlinkMoniker(raw []byte) (string, int, error) {
 		if length > 12 && buf[length-13] == 0 {
 			buf = buf[:length-12]
 		}
-		return string(utf16.Decode(buf)), no, nil
+		return string(utf16.Decode(buf)), false, no, nil
 	}
 	if isFileMoniker {
 		//x := binary.LittleEndian.Uint16(raw[no:])        //cAnti
@@ -122,13 +149,13 @@ func parseHyperlinkMoniker(raw []byte) (string, int, error) {
 				buf2[i] = binary.LittleEndian.Uint16(raw[no:])
 				no += 2
 			}
-			return string(utf16.Decode(buf2)), no, nil
+			return string(utf16.Decode(buf2)), true, no, nil
 		}
 
-		return string(buf), no, nil
+		return string(buf), true, no, nil
 	}
 
-	return "", 0, fmt.Errorf("xls: unknown moniker classid")
+	return "", false, 0, fmt.Errorf("xls: unknown moniker classid")
 }
 
 // HLink flags
@@ -0,0 +1,45 @@
+package xls
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// hyperlinkString encodes a NUL-terminated HyperlinkString.
+func hyperlinkString(s string) []byte {
+	us := append(utf16.Encode([]rune(s)), 0)
+	res := make([]byte, 4+2*len(us))
+	binary.LittleEndian.PutUint32(res, uint32(len(us)))
+	for i, u := range us {
+		binary.LittleEndian.PutUint16(res[4+2*i:], u)
+	}
+	return res
+}
+
+func TestDecodeHyperlink(t *testing.T) {
+	data := make([]byte, 24)
+	binary.LittleEndian.PutUint32(data[16:], 2)
+	binary.LittleEndian.PutUint32(data[20:], hlstmfHasMoniker|hlstmfMonikerSavedAsStr|
+		hlstmfHasDisplayName|hlstmfHasLocationStr)
+	data = append(data, hyperlinkString("Example")...)
+	data = append(data, hyperlinkString("https://example.com/")...)
+	data = append(data, hyperlinkString("section")...)
+
+	h, err := decodeHyperlink(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Display != "Example" || h.URL != "https://example.com/" || h.Location != "section" {
+		t.Errorf("unexpected hyperlink %+v", h)
+	}
+	if h.Target() != "https://example.com/#section" {
+		t.Errorf("unexpected target %s", h.Target())
+	}
+
+	tip := make([]byte, 10)
+	tip = append(tip, 'H', 0, 'i', 0, 0, 0)
+	if s := decodeHyperlinkTooltip(tip); s != "Hi" {
+		t.Errorf("unexpected tooltip %q", s)
+	}
+}
@@ -0,0 +1,67 @@
+package xls
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// countryLocales maps the Windows country codes of the Country record
+// (2.4.63) to locales.
+var countryLocales = map[uint16]string{
+	1:   "en-US",
+	7:   "ru-RU",
+	31:  "nl-NL",
+	33:  "fr-FR",
+	34:  "es-ES",
+	39:  "it-IT",
+	44:  "en-GB",
+	49:  "de-DE",
+	55:  "pt-BR",
+	66:  "th-TH",
+	81:  "ja-JP",
+	82:  "ko-KR",
+	86:  "zh-CN",
+	886: "zh-TW",
+}
+
+// codepageLocales maps ANSI code pages of the CodePage record (2.4.52)
+// to locales, for the code pages which are specific to one locale.
+var codepageLocales = map[uint16]string{
+	874:  "th-TH",
+	932:  "ja-JP",
+	936:  "zh-CN",
+	949:  "ko-KR",
+	950:  "zh-TW",
+	1251: "ru-RU",
+}
+
+// setLocale selects the locale used to render values, from the
+// options or else the Country and CodePage records of the workbook.
+// Only an unknown locale in the options is an error.
+func (b *WorkBook) setLocale() error {
+	name := b.opts.Locale
+	if name == "" {
+		name = countryLocales[b.country]
+	}
+	if name == "" {
+		name = codepageLocales[b.codepage]
+	}
+	if name == "" {
+		return nil
+	}
+	loc, ok := commonxl.LookupLocale(name)
+	if !ok {
+		if b.opts.Locale != "" {
+			return grate.WrapErr(fmt.Errorf("xls: unknown locale %q", name), grate.ErrUnknownLocale)
+		}
+		if grate.Debug {
+			log.Println("xls: unknown locale", name)
+		}
+		return nil
+	}
+	b.nfmt.SetLocale(loc)
+	return nil
+}
@@ -0,0 +1,33 @@
+package xls
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+func TestDefaultLocale(t *testing.T) {
+	b := &WorkBook{opts: grate.NewOptions(), country: 49, codepage: 932}
+	b.setLocale()
+	if n := b.Metadata().Locale; n != "de-DE" {
+		t.Errorf("expected de-DE from country, got %s", n)
+	}
+
+	b = &WorkBook{opts: grate.NewOptions(), codepage: 932}
+	b.setLocale()
+	if n := b.Metadata().Locale; n != "ja-JP" {
+		t.Errorf("expected ja-JP from codepage, got %s", n)
+	}
+
+	b = &WorkBook{opts: grate.NewOptions(grate.WithLocale("fr-FR")), country: 49}
+	b.setLocale()
+	if n := b.Metadata().Locale; n != "fr-FR" {
+		t.Errorf("expected fr-FR from options, got %s", n)
+	}
+
+	b = &WorkBook{opts: grate.NewOptions(grate.WithLocale("xx-XX")), country: 49}
+	if err := b.setLocale(); !errors.Is(err, grate.ErrUnknownLocale) {
+		t.Errorf("expected ErrUnknownLocale, got %v", err)
+	}
+}
@@ -0,0 +1,361 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// maps the iiftab field values to ST_DataConsolidateFunction names.
+var pivotFunctions = []string{"sum", "count", "average", "max", "min",
+	"product", "countNums", "stdDev", "stdDevp", "var", "varp"}
+
+var errInvalidPivot = errors.New("xls: invalid pivot table record")
+
+// SXFDB flags
+const (
+	sxfdbAllAtoms    = 0x0001
+	sxfdbUnhashed    = 0x0002
+	sxfdbShortIitms  = 0x0200
+	sxfdbCalculated  = 0x8000
+	sxaxisRow        = 0x0001
+	sxDataFieldIndex = -2 // the "Values" pseudo-field in SxIvd
+)
+
+// PivotCaches lists the names of the pivot caches in the workbook.
+func (b *WorkBook) PivotCaches() ([]string, error) {
+	res := make([]string, len(b.pivotStreams))
+	for i := range b.pivotStreams {
+		res[i] = strconv.Itoa(i)
+	}
+	return res, nil
+}
+
+// GetPivotCache returns the named pivot cache, with the field names
+// as the first record.
+func (b *WorkBook) GetPivotCache(name string) (grate.Collection, error) {
+	pc, err := b.loadPivotCache(name)
+	if err != nil {
+		return nil, err
+	}
+	return pc.Sheet(&b.nfmt), nil
+}
+
+func (b *WorkBook) loadPivotCache(name string) (*commonxl.PivotCache, error) {
+	i, err := strconv.Atoi(name)
+	if err != nil || i < 0 || i >= len(b.pivotStreams) {
+		return nil, errors.New("xls: pivot cache not found")
+	}
+	rdr, err := b.doc.Open(fmt.Sprintf("_SX_DB_CUR/%04X", b.pivotStreams[i]))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(rdr)
+	if err != nil {
+		return nil, err
+	}
+	return parsePivotCache(raw)
+}
+
+// parsePivotCache decodes the records of a pivot cache stream.
+func parsePivotCache(raw []byte) (*commonxl.PivotCache, error) {
+	pc := &commonxl.PivotCache{}
+	var (
+		items     [][]interface{} // cache items for each field
+		indexed   []int           // fields referenced by index in SXDBB
+		postponed []int           // fields with values following SXDBB
+		short     []bool
+		remaining int // cache items left for the current field
+		rec       []interface{}
+		pcol      int
+	)
+
+	for len(raw) >= 4 {
+		rt := recordType(binary.LittleEndian.Uint16(raw))
+		n := int(binary.LittleEndian.Uint16(raw[2:]))
+		if len(raw) < 4+n {
+			return nil, errInvalidPivot
+		}
+		data := raw[4 : 4+n]
+		raw = raw[4+n:]
+
+		switch rt {
+		case RecTypeSXFDB:
+			if len(data) < 14 {
+				return nil, errInvalidPivot
+			}
+			flags := binary.LittleEndian.Uint16(data)
+			name, _, err := decodeXLUnicodeString(data[14:])
+			if err != nil {
+				return nil, err
+			}
+			ifdb := len(pc.Fields)
+			pc.Fields = append(pc.Fields, name)
+			items = append(items, nil)
+			remaining = int(binary.LittleEndian.Uint16(data[12:]))
+
+			switch {
+			case (flags & sxfdbCalculated) != 0:
+				// no values are stored for calculated fields
+			case (flags&sxfdbAllAtoms) != 0 && (flags&sxfdbUnhashed) == 0:
+				indexed = append(indexed, ifdb)
+				short = append(short, (flags&sxfdbShortIitms) != 0)
+			default:
+				postponed = append(postponed, ifdb)
+			}
+
+		case RecTypeSXDBB:
+			rec = make([]interface{}, len(pc.Fields))
+			pc.Records = append(pc.Records, rec)
+			pcol = 0
+			for i, ifdb := range indexed {
+				var iitm int
+				if short[i] {
+					if len(data) < 2 {
+						return nil, errInvalidPivot
+					}
+					iitm = int(binary.LittleEndian.Uint16(data))
+					data = data[2:]
+				} else {
+					if len(data) < 1 {
+						return nil, errInvalidPivot
+					}
+					iitm = int(data[0])
+					data = data[1:]
+				}
+				if iitm < len(items[ifdb]) {
+					rec[ifdb] = items[ifdb][iitm]
+				}
+			}
+
+		case RecTypeSXString, RecTypeSXNum, RecTypeSXInt, RecTypeSxBool,
+			RecTypeSxErr, RecTypeSXDtr, RecTypeSxNil:
+			v, err := decodeSxOper(rt, data)
+			if err != nil {
+				return nil, err
+			}
+			if remaining > 0 {
+				ifdb := len(items) - 1
+				items[ifdb] = append(items[ifdb], v)
+				remaining--
+				continue
+			}
+			if len(postponed) == 0 {
+				continue
+			}
+			if rec == nil || (len(indexed) == 0 && pcol == 0) {
+				// without indexed fields there are no SXDBB records
+				rec = make([]interface{}, len(pc.Fields))
+				pc.Records = append(pc.Records, rec)
+			}
+			if pcol < len(postponed) {
+				rec[postponed[pcol]] = v
+			}
+			pcol = (pcol + 1) % len(postponed)
+
+		case RecTypeEOF:
+			return pc, nil
+		}
+	}
+	return pc, nil
+}
+
+// 2.5.202 SXOPER, returns the value of a single cache item.
+func decodeSxOper(rt recordType, data []byte) (interface{}, error) {
+	switch rt {
+	case RecTypeSXString:
+		s, _, err := decodeXLUnicodeString(data)
+		return s, err
+	case RecTypeSXNum:
+		if len(data) < 8 {
+			return nil, errInvalidPivot
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+	case RecTypeSXInt:
+		if len(data) < 2 {
+			return nil, errInvalidPivot
+		}
+		return int64(int16(binary.LittleEndian.Uint16(data))), nil
+	case RecTypeSxBool:
+		if len(data) < 2 {
+			return nil, errInvalidPivot
+		}
+		return binary.LittleEndian.Uint16(data) != 0, nil
+	case RecTypeSxErr:
+		if len(data) < 2 {
+			return nil, errInvalidPivot
+		}
+		return grate.NewCellError(data[0]), nil
+	case RecTypeSXDtr:
+		if len(data) < 8 {
+			return nil, errInvalidPivot
+		}
+		return time.Date(int(binary.LittleEndian.Uint16(data)),
+			time.Month(binary.LittleEndian.Uint16(data[2:])), int(data[4]),
+			int(data[5]), int(data[6]), int(data[7]), 0, time.UTC), nil
+	}
+	return nil, nil
+}
+
+// sxView collects the records describing a single pivot table.
+type sxView struct {
+	pt commonxl.PivotTable
+
+	// view field names and axes, in cache field order
+	names []string
+	axes  []uint16
+
+	nIvd   int
+	rows   []int
+	cols   []int
+	pages  []int
+	data   []int
+	dataFn []string
+	dataNm []string
+}
+
+// 2.4.313 SxView
+func parseSxView(data []byte) (*sxView, error) {
+	if len(data) < 44 {
+		return nil, errInvalidPivot
+	}
+	v := &sxView{}
+	v.pt.Location = commonxl.Range{
+		FirstRow: int(binary.LittleEndian.Uint16(data)),
+		LastRow:  int(binary.LittleEndian.Uint16(data[2:])),
+		FirstCol: int(binary.LittleEndian.Uint16(data[4:])),
+		LastCol:  int(binary.LittleEndian.Uint16(data[6:])),
+	}
+	v.pt.Cache = strconv.Itoa(int(int16(binary.LittleEndian.Uint16(data[14:]))))
+	cch := binary.LittleEndian.Uint16(data[40:])
+	name, _, err := decodeXLUnicodeString(append([]byte{byte(cch), byte(cch >> 8)}, data[44:]...))
+	if err != nil {
+		return nil, err
+	}
+	v.pt.Name = name
+	return v, nil
+}
+
+// add the details of a record following the SxView.
+func (v *sxView) add(rt recordType, data []byte) error {
+	switch rt {
+	case RecTypeSxvd:
+		// 2.4.309 Sxvd
+		if len(data) < 10 {
+			return errInvalidPivot
+		}
+		v.axes = append(v.axes, binary.LittleEndian.Uint16(data))
+		var name string
+		if cch := binary.LittleEndian.Uint16(data[8:]); cch != 0xFFFF {
+			var err error
+			name, _, err = decodeXLUnicodeString(data[8:])
+			if err != nil {
+				return err
+			}
+		}
+		v.names = append(v.names, name)
+
+	case RecTypeSxIvd:
+		// 2.4.292 SxIvd, the row fields are listed before the column fields
+		var ivd []int
+		for i := 0; i+1 < len(data); i += 2 {
+			ivd = append(ivd, int(int16(binary.LittleEndian.Uint16(data[i:]))))
+		}
+		if v.nIvd == 0 && v.hasAxis(sxaxisRow) {
+			v.rows = ivd
+		} else {
+			v.cols = ivd
+		}
+		v.nIvd++
+
+	case RecTypeSXPI:
+		// 2.4.298 SXPI
+		for i := 0; i+5 < len(data); i += 6 {
+			v.pages = append(v.pages, int(int16(binary.LittleEndian.Uint16(data[i:]))))
+		}
+
+	case RecTypeSXDI:
+		// 2.4.278 SXDI
+		if len(data) < 14 {
+			return errInvalidPivot
+		}
+		var name string
+		if cch := binary.LittleEndian.Uint16(data[12:]); cch != 0xFFFF {
+			var err error
+			name, _, err = decodeXLUnicodeString(data[12:])
+			if err != nil {
+				return err
+			}
+		}
+		v.data = append(v.data, int(int16(binary.LittleEndian.Uint16(data))))
+		v.dataFn = append(v.dataFn, lookupName(pivotFunctions, int(binary.LittleEndian.Uint16(data[2:]))))
+		v.dataNm = append(v.dataNm, name)
+	}
+	return nil
+}
+
+func (v *sxView) hasAxis(axis uint16) bool {
+	for _, a := range v.axes {
+		if (a & axis) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// table returns the pivot table, naming fields from the given
+// cache fields when the view does not override them.
+func (v *sxView) table(fields []string) commonxl.PivotTable {
+	name := func(i int) string {
+		if i >= 0 && i < len(v.names) && v.names[i] != "" {
+			return v.names[i]
+		}
+		if i >= 0 && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	pt := v.pt
+	for _, i := range v.rows {
+		if i != sxDataFieldIndex {
+			pt.RowFields = append(pt.RowFields, name(i))
+		}
+	}
+	for _, i := range v.cols {
+		if i != sxDataFieldIndex {
+			pt.ColFields = append(pt.ColFields, name(i))
+		}
+	}
+	for _, i := range v.pages {
+		pt.PageFields = append(pt.PageFields, name(i))
+	}
+	for j, i := range v.data {
+		pt.DataFields = append(pt.DataFields, commonxl.PivotDataField{
+			Name:     v.dataNm[j],
+			Field:    name(i),
+			Function: v.dataFn[j],
+		})
+	}
+	return pt
+}
+
+// addPivotTable adds the pivot table to the sheet, using the field
+// names of its pivot cache.
+func (b *WorkBook) addPivotTable(res *commonxl.Sheet, v *sxView) {
+	var fields []string
+	pc, err := b.loadPivotCache(v.pt.Cache)
+	if err == nil {
+		fields = pc.Fields
+	} else if grate.Debug {
+		log.Println("xls: unable to load pivot cache:", err)
+	}
+	res.AddPivotTable(v.table(fields))
+}
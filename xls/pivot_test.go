@@ -0,0 +1,110 @@
+package xls
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+func pivotRec(rt recordType, data ...byte) []byte {
+	res := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint16(res, uint16(rt))
+	binary.LittleEndian.PutUint16(res[2:], uint16(len(data)))
+	return append(res, data...)
+}
+
+func pivotStr(s string) []byte {
+	return append([]byte{byte(len(s)), 0, 0}, s...)
+}
+
+func pivotNum(f float64) []byte {
+	res := make([]byte, 8)
+	binary.LittleEndian.PutUint64(res, math.Float64bits(f))
+	return res
+}
+
+func TestParsePivotCache(t *testing.T) {
+	var raw []byte
+	raw = append(raw, pivotRec(RecTypeSXDB, make([]byte, 20)...)...)
+
+	// Region has 2 shared items, Sales values follow each record
+	fdb := make([]byte, 14)
+	fdb[0] = sxfdbAllAtoms
+	fdb[12] = 2
+	raw = append(raw, pivotRec(RecTypeSXFDB, append(fdb, pivotStr("Region")...)...)...)
+	raw = append(raw, pivotRec(RecTypeSXString, pivotStr("East")...)...)
+	raw = append(raw, pivotRec(RecTypeSXString, pivotStr("West")...)...)
+	raw = append(raw, pivotRec(RecTypeSXFDB, append(make([]byte, 14), pivotStr("Sales")...)...)...)
+
+	raw = append(raw, pivotRec(RecTypeSXDBB, 1)...)
+	raw = append(raw, pivotRec(RecTypeSXNum, pivotNum(1.5)...)...)
+	raw = append(raw, pivotRec(RecTypeSXDBB, 0)...)
+	raw = append(raw, pivotRec(RecTypeSXInt, 3, 0)...)
+	raw = append(raw, pivotRec(RecTypeEOF)...)
+
+	pc, err := parsePivotCache(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pc.Fields) != 2 || pc.Fields[0] != "Region" || pc.Fields[1] != "Sales" {
+		t.Fatalf("unexpected fields %v", pc.Fields)
+	}
+	if len(pc.Records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(pc.Records))
+	}
+	if pc.Records[0][0] != "West" || pc.Records[0][1] != 1.5 ||
+		pc.Records[1][0] != "East" || pc.Records[1][1] != int64(3) {
+		t.Errorf("unexpected records %v", pc.Records)
+	}
+
+	sh := pc.Sheet(&commonxl.Formatter{})
+	sh.Next()
+	if s := sh.Strings(); s[0] != "Region" || s[1] != "Sales" {
+		t.Errorf("unexpected header %v", s)
+	}
+}
+
+func TestParseSxView(t *testing.T) {
+	data := make([]byte, 44)
+	binary.LittleEndian.PutUint16(data[0:], 2)  // rwFirst
+	binary.LittleEndian.PutUint16(data[2:], 10) // rwLast
+	binary.LittleEndian.PutUint16(data[6:], 3)  // colLast
+	data[40] = 6
+	data = append(data, 0)
+	data = append(data, "Pivot1"...)
+
+	v, err := parseSxView(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.pt.Name != "Pivot1" || v.pt.Cache != "0" || v.pt.Location.String() != "A3:D11" {
+		t.Errorf("unexpected view %+v", v.pt)
+	}
+
+	vd := make([]byte, 10)
+	vd[0] = sxaxisRow
+	v.add(RecTypeSxvd, vd)
+	vd = append(make([]byte, 8), 0xFF, 0xFF)
+	vd[0] = 0x08 // data axis
+	v.add(RecTypeSxvd, vd)
+	v.add(RecTypeSxIvd, []byte{0, 0})
+
+	di := make([]byte, 14)
+	di[0] = 1
+	di[2] = 2 // average
+	di[12] = 4
+	if err = v.add(RecTypeSXDI, append(di, 0, 'M', 'e', 'a', 'n')); err != nil {
+		t.Fatal(err)
+	}
+
+	pt := v.table([]string{"Region", "Sales"})
+	if len(pt.RowFields) != 1 || pt.RowFields[0] != "Region" || len(pt.ColFields) != 0 {
+		t.Errorf("unexpected axis fields %v %v", pt.RowFields, pt.ColFields)
+	}
+	if len(pt.DataFields) != 1 || pt.DataFields[0].Field != "Sales" ||
+		pt.DataFields[0].Function != "average" || pt.DataFields[0].Name != "Mean" {
+		t.Errorf("unexpected data fields %+v", pt.DataFields)
+	}
+}
@@ -0,0 +1,147 @@
+package xls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// recordReader reads little-endian values from the data of a record,
+// without reading past its end. Reads past the end return zero values
+// and set the error returned by Err.
+type recordReader struct {
+	data []byte
+	err  error
+}
+
+// next returns the next n bytes, or nil if fewer are left.
+func (r *recordReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// Len returns the number of bytes left to read.
+func (r *recordReader) Len() int {
+	return len(r.data)
+}
+
+// Err returns io.ErrUnexpectedEOF if a read went past the end of the data.
+func (r *recordReader) Err() error {
+	return r.err
+}
+
+func (r *recordReader) Uint8() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *recordReader) Uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *recordReader) Uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *recordReader) Uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// Bytes returns the next n bytes, or nil if fewer are left.
+func (r *recordReader) Bytes(n int) []byte {
+	return r.next(n)
+}
+
+// chars reads up to max characters of a string, as many as are left in
+// the record. If bit 0 of flags is set the characters are 16-bit,
+// otherwise they hold only the bottom 8 bits. The number of characters
+// read is also returned, as strings may continue in the next record.
+func (r *recordReader) chars(flags byte, max int) (string, int) {
+	if r.err != nil {
+		return "", 0
+	}
+	if (flags & 0x1) == 0 {
+		if max > len(r.data) {
+			max = len(r.data)
+		}
+		content := make([]uint16, max)
+		for i, x := range r.next(max) {
+			content[i] = uint16(x)
+		}
+		return string(utf16.Decode(content)), max
+	}
+
+	if max > len(r.data)/2 {
+		max = len(r.data) / 2
+	}
+	raw := r.next(max * 2)
+	content := make([]uint16, max)
+	for i := range content {
+		content[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(content)), max
+}
+
+// minRecordSize is the number of bytes of fixed size fields read from each
+// record type. Shorter records are corrupt, and are skipped.
+var minRecordSize = map[recordType]int{
+	RecTypeFormula:     20,
+	RecTypeDate1904:    2,
+	RecTypeFilePass:    2,
+	RecTypeCodePage:    2,
+	RecTypeWsBool:      2,
+	RecTypeBoundSheet8: 6,
+	RecTypeCountry:     2,
+	RecTypeMulRk:       6,
+	RecTypeSXStreamID:  2,
+	RecTypeXF:          4,
+	RecTypeMergeCells:  2,
+	RecTypeSST:         8,
+	RecTypeLabelSst:    10,
+	RecTypeHLink:       8,
+	RecTypeDimensions:  12,
+	RecTypeNumber:      14,
+	RecTypeBoolErr:     8,
+	RecTypeString:      3,
+	RecTypeRK:          10,
+	RecTypeFormat:      2,
+}
+
+// truncated returns true if the record is too short to hold its fields.
+func (r *rec) truncated() bool {
+	return len(r.Data) < minRecordSize[r.RecType]
+}
+
+// recPos is the position of a record in the workbook stream, used to
+// describe where a corrupt file failed to parse.
+type recPos struct {
+	substream int
+	index     int   // index in the substream, or -1 if not yet known
+	offset    int64 // offset in the stream, if index is -1
+	typ       recordType
+}
+
+func (p recPos) String() string {
+	if p.index < 0 {
+		return fmt.Sprintf("%s record at offset %d", p.typ, p.offset)
+	}
+	return fmt.Sprintf("%s record %d of substream %d", p.typ, p.index, p.substream)
+}
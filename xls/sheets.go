@@ -3,9 +3,9 @@ package xls
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"log"
 	"math"
-	"unicode/utf16"
 
 	"github.com/pbnjay/grate"
 	"github.com/pbnjay/grate/commonxl"
@@ -38,30 +38,47 @@ func (b *WorkBook) Get(sheetName string) (grate.Collection, error) {
 	for _, s := range b.sheets {
 		if s.Name == sheetName {
 			ss := b.pos2substream[int64(s.Position)]
-			return b.parseSheet(s, ss)
+			res, err := b.parseSheet(s, ss)
+			if res == nil {
+				// avoid returning a typed nil for dialog sheets
+				return nil, err
+			}
+			return res, err
 		}
 	}
 	return nil, errors.New("xls: sheet not found")
 }
 
-func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
+func (b *WorkBook) parseSheet(s *boundSheet, ss int) (_ *commonxl.Sheet, err error) {
+	pos := recPos{substream: ss}
+	defer grate.Recover(&err, "xls", func() string {
+		return fmt.Sprintf("sheet %q, %s", s.Name, pos)
+	})
+
 	res := &commonxl.Sheet{
-		Formatter: &b.nfmt,
+		Formatter:        &b.nfmt,
+		XFStyles:         b.styles,
+		SkipHidden:       b.opts.SkipHidden,
+		Hyperlinks:       b.opts.Hyperlinks,
+		Values:           b.opts.Values,
+		NumberCategories: b.opts.NumberCategories,
+		MaxCells:         b.opts.Limits.MaxCells,
 	}
 	var minRow, maxRow uint32
 	var minCol, maxCol uint16
 
-	// temporary string buffer
-	us := make([]uint16, 8224)
-
 	inSubstream := 0
 	for idx, r := range b.substreams[ss] {
+		pos.index, pos.typ = idx, r.RecType
 		if inSubstream > 0 {
 			if r.RecType == RecTypeEOF {
 				inSubstream--
 			}
 			continue
 		}
+		if r.truncated() {
+			continue
+		}
 		switch r.RecType {
 		case RecTypeBOF:
 			// a BOF inside a sheet usually means embedded content like a chart
@@ -76,6 +93,22 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				// it's a dialog
 				return nil, nil
 			}
+			res.Outline.SummaryAbove = (r.Data[0] & 0x40) == 0 // fRowSumsBelow
+			res.Outline.SummaryLeft = (r.Data[0] & 0x80) == 0  // fColSumsRight
+
+		case RecTypeGuts:
+			if len(r.Data) < 8 {
+				continue
+			}
+			// levels are stored as 1 + the deepest outline level, or 0 if none
+			rowLevels := int(binary.LittleEndian.Uint16(r.Data[4:6]))
+			colLevels := int(binary.LittleEndian.Uint16(r.Data[6:8]))
+			if rowLevels > 0 {
+				res.Outline.MaxRowLevel = rowLevels - 1
+			}
+			if colLevels > 0 {
+				res.Outline.MaxColLevel = colLevels - 1
+			}
 
 		case RecTypeDimensions:
 			// max = 0-based index of the row AFTER the last valid index
@@ -101,7 +134,14 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 	inSubstream = 0
 
 	var formulaRow, formulaCol uint16
+	var cfRanges []commonxl.Range
+	cfPriority := 0
+	frozen := false
+	activePane := byte(3) // top-left, unless there are split panes
+	var pivot *sxView
+	var lastLink *commonxl.Hyperlink
 	for ridx, r := range b.substreams[ss] {
+		pos.index, pos.typ = ridx, r.RecType
 		if inSubstream > 0 {
 			if r.RecType == RecTypeEOF {
 				inSubstream--
@@ -110,6 +150,12 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			}
 			continue
 		}
+		if r.truncated() {
+			if grate.Debug {
+				log.Println("      Truncated sheet record:", r.RecType, ridx)
+			}
+			continue
+		}
 
 		// sec 2.1.7.20.6 Common Productions ABNF:
 		/*
@@ -129,6 +175,45 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				continue
 			}
 
+		case RecTypeRow:
+			if len(r.Data) < 16 {
+				continue
+			}
+			row := shRow{
+				RowIndex: binary.LittleEndian.Uint16(r.Data[:2]),
+				FirstCol: binary.LittleEndian.Uint16(r.Data[2:4]),
+				LastCol:  binary.LittleEndian.Uint16(r.Data[4:6]),
+				Height:   binary.LittleEndian.Uint16(r.Data[6:8]),
+				Reserved: binary.LittleEndian.Uint32(r.Data[8:12]),
+				Flags:    binary.LittleEndian.Uint32(r.Data[12:16]),
+			}
+			res.SetRowLayout(int(row.RowIndex), commonxl.RowLayout{
+				Hidden:       (row.Flags & rowFlagHidden) != 0,
+				Height:       float64(row.Height&0x7FFF) / 20.0, // twips to points
+				CustomHeight: (row.Flags & rowFlagUnsynced) != 0,
+				OutlineLevel: int(row.Flags & rowFlagOutlineLevel),
+				Collapsed:    (row.Flags & rowFlagCollapsed) != 0,
+			})
+
+		case RecTypeColInfo:
+			if len(r.Data) < 10 {
+				continue
+			}
+			ci := shColInfo{
+				FirstCol: binary.LittleEndian.Uint16(r.Data[:2]),
+				LastCol:  binary.LittleEndian.Uint16(r.Data[2:4]),
+				Width:    binary.LittleEndian.Uint16(r.Data[4:6]),
+				IXFE:     binary.LittleEndian.Uint16(r.Data[6:8]),
+				Flags:    binary.LittleEndian.Uint16(r.Data[8:10]),
+			}
+			res.SetColLayout(int(ci.FirstCol), int(ci.LastCol), commonxl.ColLayout{
+				Hidden: (ci.Flags & colFlagHidden) != 0,
+				Width:  float64(ci.Width) / 256.0,
+
+				OutlineLevel: int(ci.Flags&colFlagOutlineLevel) >> 8,
+				Collapsed:    (ci.Flags & colFlagCollapsed) != 0,
+			})
+
 		case RecTypeBoolErr:
 			rowIndex := int(binary.LittleEndian.Uint16(r.Data[:2]))
 			colIndex := int(binary.LittleEndian.Uint16(r.Data[2:4]))
@@ -139,19 +224,11 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				if r.Data[6] == 1 {
 					bv = true
 				}
-				var fno uint16
-				if ixfe < len(b.xfs) {
-					fno = b.xfs[ixfe]
-				}
-				res.Put(rowIndex, colIndex, bv, fno)
+				b.put(res, rowIndex, colIndex, bv, ixfe)
 				//log.Printf("bool/error spec: %d %d %+v", rowIndex, colIndex, bv)
 			} else {
-				// it's an error, load the label
-				be, ok := berrLookup[r.Data[6]]
-				if !ok {
-					be = "<unknown error>"
-				}
-				res.Put(rowIndex, colIndex, be, 0)
+				// it's an error
+				b.put(res, rowIndex, colIndex, grate.NewCellError(r.Data[6]), ixfe)
 				//log.Printf("bool/error spec: %d %d %s", rowIndex, colIndex, be)
 			}
 
@@ -164,18 +241,7 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				off := 4 + i*6
 				ixfe := int(binary.LittleEndian.Uint16(r.Data[off:]))
 				value := RKNumber(binary.LittleEndian.Uint32(r.Data[off+2:]))
-
-				var rval interface{}
-				if value.IsInteger() {
-					rval = value.Int()
-				} else {
-					rval = value.Float64()
-				}
-				var fno uint16
-				if ixfe < len(b.xfs) {
-					fno = b.xfs[ixfe]
-				}
-				res.Put(rowIndex, colIndex+i, rval, fno)
+				b.putRK(res, rowIndex, colIndex+i, value, ixfe)
 			}
 			//log.Printf("mulrow spec: %+v", *mr)
 
@@ -186,11 +252,7 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			xnum := binary.LittleEndian.Uint64(r.Data[6:])
 
 			value := math.Float64frombits(xnum)
-			var fno uint16
-			if ixfe < len(b.xfs) {
-				fno = b.xfs[ixfe]
-			}
-			res.Put(rowIndex, colIndex, value, fno)
+			b.putFloat(res, rowIndex, colIndex, value, ixfe)
 			//log.Printf("Number spec: %d %d = %f", rowIndex, colIndex, value)
 
 		case RecTypeRK:
@@ -198,19 +260,8 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			colIndex := int(binary.LittleEndian.Uint16(r.Data[2:4]))
 			ixfe := int(binary.LittleEndian.Uint16(r.Data[4:]))
 			value := RKNumber(binary.LittleEndian.Uint32(r.Data[6:]))
-
-			var rval interface{}
-			if value.IsInteger() {
-				rval = value.Int()
-			} else {
-				rval = value.Float64()
-			}
-			var fno uint16
-			if ixfe < len(b.xfs) {
-				fno = b.xfs[ixfe]
-			}
-			res.Put(rowIndex, colIndex, rval, fno)
-			//log.Printf("RK spec: %d %d = %+v", rowIndex, colIndex, rval)
+			b.putRK(res, rowIndex, colIndex, value, ixfe)
+			//log.Printf("RK spec: %d %d = %+v", rowIndex, colIndex, value)
 
 		case RecTypeFormula:
 			formulaRow = binary.LittleEndian.Uint16(r.Data[:2])
@@ -236,11 +287,7 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 					res.Put(int(formulaRow), int(formulaCol), bv, fno)
 				case 2:
 					// error value
-					be, ok := berrLookup[fdata[2]]
-					if !ok {
-						be = "<unknown error>"
-					}
-					res.Put(int(formulaRow), int(formulaCol), be, 0)
+					res.Put(int(formulaRow), int(formulaCol), grate.NewCellError(fdata[2]), 0)
 				case 3:
 					// blank string
 				default:
@@ -251,6 +298,7 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				value := math.Float64frombits(xnum)
 				res.Put(int(formulaRow), int(formulaCol), value, fno)
 			}
+			res.SetXF(int(formulaRow), int(formulaCol), uint16(ixfe))
 			//log.Printf("formula spec: %d %d ~~ %+v", formulaRow, formulaCol, r.Data)
 
 		case RecTypeString:
@@ -260,46 +308,18 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			// at each gap indicates if the encoding switches
 			// to/from 8/16-bit characters.
 
-			charCount := binary.LittleEndian.Uint16(r.Data[:2])
-			flags := r.Data[2]
-			fstr := ""
-			if (flags & 1) == 0 {
-				fstr = string(r.Data[3:])
-			} else {
-				raw := r.Data[3:]
-				if int(charCount) > cap(us) {
-					us = make([]uint16, charCount)
-				}
-				us = us[:charCount]
-				for i := 0; i < int(charCount); i++ {
-					us[i] = binary.LittleEndian.Uint16(raw)
-					raw = raw[2:]
-				}
-				fstr = string(utf16.Decode(us))
-			}
-
-			if (ridx + 1) < len(b.substreams[ss]) {
-				ridx2 := ridx + 1
-				nrecs := len(b.substreams[ss])
-				for ridx2 < nrecs {
-					r2 := b.substreams[ss][ridx2]
-					if r2.RecType != RecTypeContinue {
-						break
-					}
-					if (r2.Data[0] & 1) == 0 {
-						fstr += string(r2.Data[1:])
-					} else {
-						raw := r2.Data[1:]
-						slen := len(raw) / 2
-						us = us[:slen]
-						for i := 0; i < slen; i++ {
-							us[i] = binary.LittleEndian.Uint16(raw)
-							raw = raw[2:]
-						}
-						fstr += string(utf16.Decode(us))
-					}
-					ridx2++
+			rr := &recordReader{data: r.Data}
+			charCount := int(rr.Uint16())
+			fstr, n := rr.chars(rr.Uint8(), charCount)
+			for ridx2 := ridx + 1; n < charCount && ridx2 < len(b.substreams[ss]); ridx2++ {
+				r2 := b.substreams[ss][ridx2]
+				if r2.RecType != RecTypeContinue || len(r2.Data) == 0 {
+					break
 				}
+				rr = &recordReader{data: r2.Data}
+				more, n2 := rr.chars(rr.Uint8(), charCount-n)
+				fstr += more
+				n += n2
 			}
 			res.Set(int(formulaRow), int(formulaCol), fstr)
 			//log.Printf("String direct: %d %d '%s'", int(formulaRow), int(formulaCol), fstr)
@@ -309,15 +329,11 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			colIndex := int(binary.LittleEndian.Uint16(r.Data[2:4]))
 			ixfe := int(binary.LittleEndian.Uint16(r.Data[4:6]))
 			sstIndex := int(binary.LittleEndian.Uint32(r.Data[6:]))
-			if sstIndex > len(b.strings) {
+			if sstIndex >= len(b.strings) {
 				return nil, errors.New("xls: invalid sst index")
 			}
-			var fno uint16
-			if ixfe < len(b.xfs) {
-				fno = b.xfs[ixfe]
-			}
 			if b.strings[sstIndex] != "" {
-				res.Put(rowIndex, colIndex, b.strings[sstIndex], fno)
+				b.putString(res, rowIndex, colIndex, b.strings[sstIndex], ixfe)
 			}
 			//log.Printf("SST spec: %d %d = [%d] '%s' %d", rowIndex, colIndex, sstIndex, b.strings[sstIndex], fno)
 
@@ -343,18 +359,26 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 
 			// decode the hyperlink datastructure and try to find the
 			// display text and separate the URL itself.
-			displayText, linkText, err := decodeHyperlinks(r.Data[8:])
+			link, err := decodeHyperlink(r.Data[8:])
 			if err != nil {
 				log.Println(err)
 				continue
 			}
+			lastLink = link
 
 			// apply merge cell rules (see RecTypeMergeCells below)
 			for rn := int(firstRow); rn <= int(lastRow); rn++ {
 				for cn := int(firstCol); cn <= int(lastCol); cn++ {
 					if rn == int(firstRow) && cn == int(firstCol) {
-						// TODO: provide custom hooks for how to handle links in output
-						res.Put(rn, cn, displayText+" <"+linkText+">", 0)
+						if b.opts.Hyperlinks == grate.HyperlinkDefault {
+							// earlier releases flattened the link into the text
+							target := link.Location
+							if target == "" {
+								target = link.URL + link.File
+							}
+							res.Put(rn, cn, link.Display+" <"+target+">", 0)
+						}
+						res.SetHyperlink(rn, cn, link)
 					} else if cn == int(firstCol) {
 						// first and last column MAY be the same
 						if rn == int(lastRow) {
@@ -371,6 +395,122 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				}
 			}
 
+		case RecTypeHLinkTooltip:
+			// always follows the HLink record it applies to
+			if lastLink != nil {
+				lastLink.Tooltip = decodeHyperlinkTooltip(r.Data)
+			}
+
+		case RecTypeDv:
+			// NB the preceding DVal record only holds the number of Dv records
+			v, err := parseDv(r.Data)
+			if err != nil {
+				if grate.Debug {
+					log.Println(err)
+				}
+				continue
+			}
+			res.AddValidation(v)
+
+		case RecTypeCondFmt:
+			var err error
+			cfRanges, err = parseCondFmt(r.Data)
+			if err != nil && grate.Debug {
+				log.Println(err)
+			}
+
+		case RecTypeCF:
+			cf, err := parseCF(r.Data, cfRanges)
+			if err != nil {
+				if grate.Debug {
+					log.Println(err)
+				}
+				continue
+			}
+			// rules are evaluated in the order they are stored
+			cfPriority++
+			cf.Priority = cfPriority
+			if cf.Format != nil {
+				ct := &commonxl.ColorTable{Indexed: b.palette}
+				ct.ResolveStyle(cf.Format)
+			}
+			res.AddConditionalFormat(cf)
+
+		case RecTypeWindow2:
+			if len(r.Data) >= 2 {
+				frozen = (r.Data[0] & 0x08) != 0 // fFrozenRt
+			}
+
+		case RecTypePane:
+			if len(r.Data) < 9 {
+				continue
+			}
+			if frozen {
+				res.View.FrozenCols = int(binary.LittleEndian.Uint16(r.Data[:2]))
+				res.View.FrozenRows = int(binary.LittleEndian.Uint16(r.Data[2:4]))
+			}
+			activePane = r.Data[8]
+
+		case RecTypeSelection:
+			if len(r.Data) < 9 || r.Data[0] != activePane {
+				continue
+			}
+			res.View.ActiveRow = int(binary.LittleEndian.Uint16(r.Data[1:3]))
+			res.View.ActiveCol = int(binary.LittleEndian.Uint16(r.Data[3:5]))
+			cref := int(binary.LittleEndian.Uint16(r.Data[7:9]))
+			res.View.Selection = nil
+			for i, raw := 0, r.Data[9:]; i < cref && len(raw) >= 6; i, raw = i+1, raw[6:] {
+				res.View.Selection = append(res.View.Selection, commonxl.Range{
+					FirstRow: int(binary.LittleEndian.Uint16(raw[:2])),
+					LastRow:  int(binary.LittleEndian.Uint16(raw[2:4])),
+					FirstCol: int(raw[4]),
+					LastCol:  int(raw[5]),
+				})
+			}
+
+		case RecTypeAutoFilterInfo:
+			// the filtered range is stored in the workbook's _FilterDatabase name
+			for i, bs := range b.sheets {
+				if bs != s {
+					continue
+				}
+				if rng, ok := b.filterRanges[i+1]; ok {
+					res.AutoFilter = &commonxl.AutoFilter{Range: rng}
+				}
+			}
+
+		case RecTypeAutoFilter:
+			if res.AutoFilter == nil {
+				continue
+			}
+			col, fc, err := parseAutoFilter(r.Data)
+			if err != nil {
+				if grate.Debug {
+					log.Println(err)
+				}
+				continue
+			}
+			fc.Col = res.AutoFilter.Range.FirstCol + col
+			res.AutoFilter.Columns = append(res.AutoFilter.Columns, fc)
+
+		case RecTypeSxView:
+			if pivot != nil {
+				b.addPivotTable(res, pivot)
+			}
+			var err error
+			pivot, err = parseSxView(r.Data)
+			if err != nil && grate.Debug {
+				log.Println(err)
+			}
+
+		case RecTypeSxvd, RecTypeSxIvd, RecTypeSXPI, RecTypeSXDI:
+			if pivot == nil {
+				continue
+			}
+			if err := pivot.add(r.RecType, r.Data); err != nil && grate.Debug {
+				log.Println(err)
+			}
+
 		case RecTypeMergeCells:
 			// To keep cells aligned, Merged cells are handled by placing
 			// special characters in each cell covered by the merge block.
@@ -383,14 +523,13 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			// column of the merge.
 			//
 
-			cmcs := binary.LittleEndian.Uint16(r.Data[:2])
-			raw := r.Data[2:]
-			for i := 0; i < int(cmcs); i++ {
-				firstRow := binary.LittleEndian.Uint16(raw[:2])
-				lastRow := binary.LittleEndian.Uint16(raw[2:4])
-				firstCol := binary.LittleEndian.Uint16(raw[4:6])
-				lastCol := binary.LittleEndian.Uint16(raw[6:])
-				raw = raw[8:]
+			rr := &recordReader{data: r.Data}
+			cmcs := int(rr.Uint16())
+			for i := 0; i < cmcs && rr.Len() >= 8; i++ {
+				firstRow := rr.Uint16()
+				lastRow := rr.Uint16()
+				firstCol := rr.Uint16()
+				lastCol := rr.Uint16()
 
 				if lastRow == 0xFFFF { // placeholder value indicate "last"
 					lastRow = uint16(maxRow) - 1
@@ -425,7 +564,7 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 				case RecTypeContinue:
 					// the only situation so far is when used in RecTypeString above
 
-				case RecTypeRow, RecTypeDimensions, RecTypeEOF, RecTypeWsBool:
+				case RecTypeDimensions, RecTypeEOF, RecTypeWsBool:
 					// handled in initial pass
 
 				default:
@@ -435,16 +574,48 @@ func (b *WorkBook) parseSheet(s *boundSheet, ss int) (*commonxl.Sheet, error) {
 			*/
 		}
 	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	if pivot != nil {
+		b.addPivotTable(res, pivot)
+	}
 	return res, nil
 }
 
-var berrLookup = map[byte]string{
-	0x00: "#NULL!",
-	0x07: "#DIV/0!",
-	0x0F: "#VALUE!",
-	0x17: "#REF!",
-	0x1D: "#NAME?",
-	0x24: "#NUM!",
-	0x2A: "#N/A",
-	0x2B: "#GETTING_DATA",
+// xfFormat returns the number format of the given XF record index.
+func (b *WorkBook) xfFormat(ixfe int) uint16 {
+	if ixfe < len(b.xfs) {
+		return b.xfs[ixfe]
+	}
+	return 0
+}
+
+// putFloat is put for float64 values, which avoids boxing each number.
+func (b *WorkBook) putFloat(res *commonxl.Sheet, row, col int, value float64, ixfe int) {
+	res.PutFloat(row, col, value, b.xfFormat(ixfe))
+	res.SetXF(row, col, uint16(ixfe))
+}
+
+// putRK is put for RK values, stored as integers where possible.
+func (b *WorkBook) putRK(res *commonxl.Sheet, row, col int, value RKNumber, ixfe int) {
+	if value.IsInteger() {
+		res.PutInt(row, col, int64(value.Int()), b.xfFormat(ixfe))
+	} else {
+		res.PutFloat(row, col, value.Float64(), b.xfFormat(ixfe))
+	}
+	res.SetXF(row, col, uint16(ixfe))
+}
+
+// putString is put for string values.
+func (b *WorkBook) putString(res *commonxl.Sheet, row, col int, value string, ixfe int) {
+	res.PutString(row, col, value, b.xfFormat(ixfe))
+	res.SetXF(row, col, uint16(ixfe))
+}
+
+// put the value at the cell location, using the number format
+// and style of the given XF record index.
+func (b *WorkBook) put(res *commonxl.Sheet, row, col int, value interface{}, ixfe int) {
+	res.Put(row, col, value, b.xfFormat(ixfe))
+	res.SetXF(row, col, uint16(ixfe))
 }
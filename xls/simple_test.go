@@ -71,12 +71,13 @@ func TestBasic(t *testing.T) {
 				firstLoad = false
 			}
 
-			for xrow, xdata := range xsheet.Rows {
-				for xcol, xval := range xdata {
-					//t.Logf("at %s (%d,%d) expect '%v'", fnames[0], xrow, xcol, trueData.Rows[xrow][xcol])
-					if !trueData.Rows[xrow][xcol].Equal(xval) {
+			for xrow := 0; xrow < xsheet.NumRows; xrow++ {
+				for xcol := 0; xcol < xsheet.NumCols; xcol++ {
+					xval := xsheet.Cell(xrow, xcol)
+					//t.Logf("at %s (%d,%d) expect '%v'", fnames[0], xrow, xcol, trueData.Cell(xrow, xcol))
+					if !trueData.Cell(xrow, xcol).Equal(xval) {
 						t.Logf("mismatch at %s (%d,%d): '%v' <> '%v' expected", fnames[0], xrow, xcol,
-							xval, trueData.Rows[xrow][xcol])
+							xval, trueData.Cell(xrow, xcol))
 						t.Fail()
 					}
 				}
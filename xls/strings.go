@@ -6,58 +6,36 @@ import (
 	"io"
 	"io/ioutil"
 	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
 )
 
 // 2.5.240
 func decodeShortXLUnicodeString(raw []byte) (string, int, error) {
 	// identical to decodeXLUnicodeString except for cch=8bits instead of 16
-	cch := int(raw[0])
-	flags := raw[1]
-	raw = raw[2:]
-
-	content := make([]uint16, cch)
-	if (flags & 0x1) == 0 {
-		// 16-bit characters but only the bottom 8bits
-		contentBytes := raw[:cch]
-		for i, x := range contentBytes {
-			content[i] = uint16(x)
-		}
-		cch += 2 // to return the offset
-	} else {
-		// 16-bit characters
-		for i := 0; i < cch; i++ {
-			content[i] = binary.LittleEndian.Uint16(raw[:2])
-			raw = raw[2:]
-		}
-		cch += cch + 2 // to return the offset
-	}
-	return string(utf16.Decode(content)), cch, nil
+	r := &recordReader{data: raw}
+	cch := int(r.Uint8())
+	str, err := r.xlString(cch)
+	return str, len(raw) - r.Len(), err
 }
 
 // 2.5.294
 func decodeXLUnicodeString(raw []byte) (string, int, error) {
 	// identical to decodeShortXLUnicodeString except for cch=16bits instead of 8
-	cch := int(binary.LittleEndian.Uint16(raw[:2]))
-	flags := raw[2]
-	raw = raw[3:]
+	r := &recordReader{data: raw}
+	cch := int(r.Uint16())
+	str, err := r.xlString(cch)
+	return str, len(raw) - r.Len(), err
+}
 
-	content := make([]uint16, cch)
-	if (flags & 0x1) == 0 {
-		// 16-bit characters but only the bottom 8bits
-		contentBytes := raw[:cch]
-		for i, x := range contentBytes {
-			content[i] = uint16(x)
-		}
-		cch += 3 // to return the offset
-	} else {
-		// 16-bit characters
-		for i := 0; i < cch; i++ {
-			content[i] = binary.LittleEndian.Uint16(raw[:2])
-			raw = raw[2:]
-		}
-		cch += cch + 3 // to return the offset
+// xlString reads the flags byte and cch characters of a string.
+func (r *recordReader) xlString(cch int) (string, error) {
+	flags := r.Uint8()
+	str, n := r.chars(flags, cch)
+	if r.err != nil || n < cch {
+		return "", errors.New("xls: invalid unicode string")
 	}
-	return string(utf16.Decode(content)), cch, nil
+	return str, nil
 }
 
 // 2.5.293
@@ -134,50 +112,65 @@ func decodeXLUnicodeRichExtendedString(r io.Reader) (string, error) {
 }
 
 // read in an array of XLUnicodeRichExtendedString s
-func parseSST(recs []*rec) ([]string, error) {
+// if phonetic is true, the phonetic reading stored in the ExtRst
+// data is returned in place of the base text where present.
+func parseSST(recs []*rec, phonetic bool, maxStrings int) ([]string, error) {
 	// The quirky thing about this code is that when strings cross a record
 	// boundary, there's an intervening flags byte that MAY change the string
 	// from an 8-bit encoding to 16-bit or vice versa.
 
 	//totalRefs := binary.LittleEndian.Uint32(recs[0].Data[0:4])
 	numStrings := binary.LittleEndian.Uint32(recs[0].Data[4:8])
+	if maxStrings > 0 && numStrings > uint32(maxStrings) {
+		return nil, grate.ErrLimitExceeded{Limit: "MaxStrings", Max: int64(maxStrings)}
+	}
 
+	// each string takes at least 3 bytes, so don't trust larger counts
+	size := 0
+	for _, r := range recs {
+		size += len(r.Data)
+	}
+	if int(numStrings) > size/3 {
+		numStrings = uint32(size / 3)
+	}
 	all := make([]string, 0, numStrings)
 	current := make([]uint16, 32*1024)
+	errInvalid := errors.New("xls: invalid SST record")
 
 	buf := recs[0].Data[8:]
-	for i := 0; i < len(recs); {
+	i := 0
+	// next moves to the following Continue record
+	next := func() bool {
+		i++
+		if i >= len(recs) {
+			return false
+		}
+		buf = recs[i].Data
+		return true
+	}
+	for i < len(recs) {
 		var cRunBytes int
 		var flags byte
 		var cbExtRs uint32
 
 		for len(buf) > 0 {
-			slen := binary.LittleEndian.Uint16(buf)
-			buf = buf[2:]
-			flags = buf[0]
-			buf = buf[1:]
+			r := &recordReader{data: buf}
+			slen := r.Uint16()
+			flags = r.Uint8()
 
 			if (flags & 0x8) != 0 {
 				// rich formating data is present
-				cRun := binary.LittleEndian.Uint16(buf)
+				cRun := r.Uint16()
 				cRunBytes = int(cRun) * 4
-				buf = buf[2:]
 			}
 			if (flags & 0x4) != 0 {
 				// phonetic string data is present
-				cbExtRs = binary.LittleEndian.Uint32(buf)
-				buf = buf[4:]
+				cbExtRs = r.Uint32()
 			}
-
-			///////
-			blx := len(buf)
-			bly := len(buf) - 5
-			if blx > 5 {
-				blx = 5
-			}
-			if bly < 0 {
-				bly = 0
+			if r.Err() != nil {
+				return nil, errInvalid
 			}
+			buf = r.data
 
 			// this block will read the string data, but transparently
 			// handle continuing across records
@@ -187,20 +180,28 @@ func parseSST(recs []*rec) ([]string, error) {
 				current = current[:slen]
 			}
 			for j := 0; j < int(slen); j++ {
-				if len(buf) == 0 {
-					i++
-					if (recs[i].Data[0] & 1) == 0 {
+				for len(buf) == 0 {
+					if !next() {
+						return nil, errInvalid
+					}
+					if len(buf) == 0 {
+						continue
+					}
+					if (buf[0] & 1) == 0 {
 						flags &= 0xFE
 					} else {
 						flags |= 1
 					}
-					buf = recs[i].Data[1:]
+					buf = buf[1:]
 				}
 
 				if (flags & 1) == 0 { //8-bit
 					current[j] = uint16(buf[0])
 					buf = buf[1:]
 				} else { //16-bit
+					if len(buf) < 2 {
+						return nil, errInvalid
+					}
 					current[j] = uint16(binary.LittleEndian.Uint16(buf[:2]))
 					buf = buf[2:]
 					if len(buf) == 1 {
@@ -210,7 +211,6 @@ func parseSST(recs []*rec) ([]string, error) {
 			}
 
 			s := string(utf16.Decode(current))
-			all = append(all, s)
 
 			///////
 
@@ -220,27 +220,101 @@ func parseSST(recs []*rec) ([]string, error) {
 					cRunBytes = 0
 				} else {
 					cRunBytes -= len(buf)
-					i++
-					buf = recs[i].Data
+					if !next() {
+						return nil, errInvalid
+					}
 				}
 			}
 
+			var ext []byte
 			for cbExtRs > 0 {
 				if len(buf) >= int(cbExtRs) {
+					if phonetic {
+						ext = append(ext, buf[:cbExtRs]...)
+					}
 					buf = buf[cbExtRs:]
 					cbExtRs = 0
 				} else {
+					if phonetic {
+						ext = append(ext, buf...)
+					}
 					cbExtRs -= uint32(len(buf))
-					i++
-					buf = recs[i].Data
+					if !next() {
+						return nil, errInvalid
+					}
 				}
 			}
+			if len(ext) > 0 {
+				s = applyExtRst(s, ext)
+			}
+			if maxStrings > 0 && len(all) >= maxStrings {
+				// the declared count may be wrong
+				return nil, grate.ErrLimitExceeded{Limit: "MaxStrings", Max: int64(maxStrings)}
+			}
+			all = append(all, s)
 		}
-		i++
-		if i < len(recs) {
-			buf = recs[i].Data
-		}
+		next()
 	}
 
 	return all, nil
 }
+
+// 2.5.85
+// applyExtRst replaces the characters of s with the phonetic runs found
+// in the ExtRst structure, returning the phonetic reading of the string.
+func applyExtRst(s string, ext []byte) string {
+	// reserved, cb, phs (4 bytes), crun, cch, st.cchCharacters
+	if len(ext) < 14 || binary.LittleEndian.Uint16(ext) != 1 {
+		return s
+	}
+	crun := int(binary.LittleEndian.Uint16(ext[8:]))
+	cch := int(binary.LittleEndian.Uint16(ext[12:]))
+	raw := ext[14:]
+	if cch == 0 || len(raw) < cch*2 {
+		return s
+	}
+	ph := make([]uint16, cch)
+	for i := range ph {
+		ph[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	raw = raw[cch*2:]
+
+	type phRun struct {
+		ichFirst, ichMom, cchMom int
+	}
+	runs := make([]phRun, 0, crun)
+	for i := 0; i < crun && len(raw) >= 6; i++ {
+		runs = append(runs, phRun{
+			ichFirst: int(binary.LittleEndian.Uint16(raw)),
+			ichMom:   int(binary.LittleEndian.Uint16(raw[2:])),
+			cchMom:   int(binary.LittleEndian.Uint16(raw[4:])),
+		})
+		raw = raw[6:]
+	}
+	if len(runs) == 0 {
+		// the reading applies to the whole string
+		return string(utf16.Decode(ph))
+	}
+
+	base := utf16.Encode([]rune(s))
+	res := make([]uint16, 0, len(base)+cch)
+	last := 0
+	for i, r := range runs {
+		// runs are stored in order, each run's text ends where the next begins
+		end := cch
+		if i+1 < len(runs) {
+			end = runs[i+1].ichFirst
+		}
+		if r.ichFirst > end || end > cch || r.ichMom < last || r.ichMom > len(base) {
+			continue
+		}
+		res = append(res, base[last:r.ichMom]...)
+		res = append(res, ph[r.ichFirst:end]...)
+		last = r.ichMom + r.cchMom
+		if last > len(base) {
+			last = len(base)
+		}
+	}
+	res = append(res, base[last:]...)
+	return string(utf16.Decode(res))
+}
@@ -0,0 +1,75 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pbnjay/grate"
+)
+
+func makeExtRst(reading string, runs [][3]uint16) []byte {
+	ph := utf16.Encode([]rune(reading))
+	ext := make([]byte, 14, 14+len(ph)*2+len(runs)*6)
+	binary.LittleEndian.PutUint16(ext[0:], 1)
+	binary.LittleEndian.PutUint16(ext[8:], uint16(len(runs)))
+	binary.LittleEndian.PutUint16(ext[10:], uint16(len(ph)))
+	binary.LittleEndian.PutUint16(ext[12:], uint16(len(ph)))
+	for _, c := range ph {
+		ext = append(ext, byte(c), byte(c>>8))
+	}
+	for _, r := range runs {
+		for _, x := range r {
+			ext = append(ext, byte(x), byte(x>>8))
+		}
+	}
+	binary.LittleEndian.PutUint16(ext[2:], uint16(len(ext)-4))
+	return ext
+}
+
+func TestApplyExtRst(t *testing.T) {
+	ext := makeExtRst("トウキョウト", [][3]uint16{{0, 0, 2}, {5, 2, 1}})
+	if got := applyExtRst("東京都庁", ext); got != "トウキョウト庁" {
+		t.Errorf("got %q, expected %q", got, "トウキョウト庁")
+	}
+
+	ext = makeExtRst("トウキョウ", nil)
+	if got := applyExtRst("東京", ext); got != "トウキョウ" {
+		t.Errorf("got %q, expected %q", got, "トウキョウ")
+	}
+
+	if got := applyExtRst("東京", ext[:10]); got != "東京" {
+		t.Errorf("truncated data should return base text, got %q", got)
+	}
+}
+
+func TestParseSSTLimit(t *testing.T) {
+	data := []byte{2, 0, 0, 0, 2, 0, 0, 0}
+	for _, str := range []string{"ab", "c"} {
+		data = append(data, byte(len(str)), 0, 0)
+		data = append(data, str...)
+	}
+	recs := []*rec{{RecType: RecTypeSST, RecSize: uint16(len(data)), Data: data}}
+
+	strs, err := parseSST(recs, false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(strs) != 2 || strs[0] != "ab" || strs[1] != "c" {
+		t.Errorf("unexpected strings %q", strs)
+	}
+
+	_, err = parseSST(recs, false, 1)
+	var lerr grate.ErrLimitExceeded
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxStrings" {
+		t.Errorf("expected MaxStrings ErrLimitExceeded, got %v", err)
+	}
+
+	// more strings than the declared count
+	data[4] = 1
+	_, err = parseSST(recs, false, 1)
+	if !errors.As(err, &lerr) || lerr.Limit != "MaxStrings" {
+		t.Errorf("expected MaxStrings ErrLimitExceeded, got %v", err)
+	}
+}
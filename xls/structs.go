@@ -37,6 +37,29 @@ type shRow struct {
 	Flags    uint32
 }
 
+// shRow.Flags bits
+const (
+	rowFlagOutlineLevel = uint32(0x07)
+	rowFlagCollapsed    = uint32(0x10)
+	rowFlagHidden       = uint32(0x20) // fDyZero
+	rowFlagUnsynced     = uint32(0x40) // height was manually set
+)
+
+type shColInfo struct {
+	FirstCol uint16 // 0-based
+	LastCol  uint16 // 0-based
+	Width    uint16 // in 1/256th of a character
+	IXFE     uint16
+	Flags    uint16
+}
+
+// shColInfo.Flags bits
+const (
+	colFlagHidden       = uint16(0x0001)
+	colFlagOutlineLevel = uint16(0x0700)
+	colFlagCollapsed    = uint16(0x1000)
+)
+
 type shRef8 struct {
 	FirstRow uint16 // 0-based
 	LastRow  uint16 // 0-based
@@ -0,0 +1,136 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// maps the alc field values to names.
+var horizontalAlignments = []string{"general", "left", "center", "right",
+	"fill", "justify", "centerContinuous", "distributed"}
+
+// maps the alcV field values to names.
+var verticalAlignments = []string{"top", "center", "bottom", "justify", "distributed"}
+
+// maps the dg field values to ST_BorderStyle names.
+var borderStyles = []string{"", "thin", "medium", "dashed", "dotted", "thick",
+	"double", "hair", "mediumDashed", "dashDot", "mediumDashDot", "dashDotDot",
+	"mediumDashDotDot", "slantDashDot"}
+
+// maps the fls field values to ST_PatternType names.
+var fillPatterns = []string{"none", "solid", "mediumGray", "darkGray",
+	"lightGray", "darkHorizontal", "darkVertical", "darkDown", "darkUp",
+	"darkGrid", "darkTrellis", "lightHorizontal", "lightVertical", "lightDown",
+	"lightUp", "lightGrid", "lightTrellis", "gray125", "gray0625"}
+
+func lookupName(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+// 2.5.161 Icv
+func icvColor(icv uint16) commonxl.Color {
+	if icv == 0x7FFF {
+		return commonxl.Color{Kind: commonxl.AutoColor}
+	}
+	return commonxl.Color{Kind: commonxl.IndexedColor, Index: int(icv)}
+}
+
+// 2.4.122
+func parseFont(data []byte) (commonxl.Font, error) {
+	if len(data) < 16 {
+		return commonxl.Font{}, errors.New("xls: invalid font record")
+	}
+	grbit := binary.LittleEndian.Uint16(data[2:])
+	f := commonxl.Font{
+		Size:      float64(binary.LittleEndian.Uint16(data)) / 20.0,
+		Italic:    (grbit & 0x02) != 0,
+		Strike:    (grbit & 0x08) != 0,
+		Color:     icvColor(binary.LittleEndian.Uint16(data[4:])),
+		Bold:      binary.LittleEndian.Uint16(data[6:]) >= 700,
+		Underline: data[10] != 0,
+	}
+	var err error
+	f.Name, _, err = decodeShortXLUnicodeString(data[14:])
+	return f, err
+}
+
+// 2.4.353 XF, with the 2.5.20 CellXF / 2.5.249 StyleXF fields that follow.
+func parseXF(data []byte, fonts []commonxl.Font) (*commonxl.Style, error) {
+	if len(data) < 20 {
+		return nil, errors.New("xls: invalid xf record")
+	}
+	st := &commonxl.Style{
+		NumFmt: binary.LittleEndian.Uint16(data[2:]),
+	}
+
+	// font index 4 is never written, so later indexes are shifted by one
+	ifnt := int(binary.LittleEndian.Uint16(data))
+	if ifnt > 4 {
+		ifnt--
+	}
+	if ifnt < len(fonts) {
+		st.Font = fonts[ifnt]
+	}
+
+	st.Alignment.Horizontal = lookupName(horizontalAlignments, int(data[6]&0x07))
+	st.Alignment.WrapText = (data[6] & 0x08) != 0
+	st.Alignment.Vertical = lookupName(verticalAlignments, int((data[6]>>4)&0x07))
+	st.Alignment.Rotation = int(data[7])
+	st.Alignment.Indent = int(data[8] & 0x0F)
+	st.Alignment.ShrinkToFit = (data[8] & 0x10) != 0
+
+	b1 := binary.LittleEndian.Uint32(data[10:])
+	b2 := binary.LittleEndian.Uint32(data[14:])
+	st.Border.Left = commonxl.BorderEdge{
+		Style: lookupName(borderStyles, int(b1&0x0F)),
+		Color: icvColor(uint16(b1>>16) & 0x7F),
+	}
+	st.Border.Right = commonxl.BorderEdge{
+		Style: lookupName(borderStyles, int((b1>>4)&0x0F)),
+		Color: icvColor(uint16(b1>>23) & 0x7F),
+	}
+	st.Border.Top = commonxl.BorderEdge{
+		Style: lookupName(borderStyles, int((b1>>8)&0x0F)),
+		Color: icvColor(uint16(b2) & 0x7F),
+	}
+	st.Border.Bottom = commonxl.BorderEdge{
+		Style: lookupName(borderStyles, int((b1>>12)&0x0F)),
+		Color: icvColor(uint16(b2>>7) & 0x7F),
+	}
+	for _, e := range []*commonxl.BorderEdge{&st.Border.Left, &st.Border.Right,
+		&st.Border.Top, &st.Border.Bottom} {
+		if e.Style == "" {
+			e.Color = commonxl.Color{}
+		}
+	}
+
+	fill := binary.LittleEndian.Uint16(data[18:])
+	st.Fill.Pattern = lookupName(fillPatterns, int((b2>>26)&0x3F))
+	if st.Fill.Pattern != "none" {
+		st.Fill.FgColor = icvColor(fill & 0x7F)
+		st.Fill.BgColor = icvColor((fill >> 7) & 0x7F)
+	}
+	return st, nil
+}
+
+// 2.4.188
+func parsePalette(data []byte) []uint32 {
+	// the palette replaces the colors starting at index 8
+	pal := make([]uint32, len(commonxl.DefaultPalette))
+	copy(pal, commonxl.DefaultPalette)
+	if len(data) < 2 {
+		return pal
+	}
+	ccv := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	for i := 0; i < ccv && len(data) >= 4 && 8+i < len(pal); i++ {
+		pal[8+i] = 0xFF000000 | uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+		data = data[4:]
+	}
+	return pal
+}
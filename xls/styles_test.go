@@ -0,0 +1,49 @@
+package xls
+
+import (
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+func TestStyles(t *testing.T) {
+	wb, err := Open("../testdata/basic.xls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+
+	sheets, err := wb.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := wb.Get(sheets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	xsheet := sheet.(*commonxl.Sheet)
+
+	// header row is bold with a gray fill and thin borders
+	xsheet.Next()
+	for i, st := range xsheet.Styles() {
+		if st == nil {
+			t.Fatalf("missing style for header column %d", i)
+		}
+		if !st.Font.Bold || st.Font.Name != "Helvetica Neue" {
+			t.Errorf("header column %d font = %+v, expected bold Helvetica Neue", i, st.Font)
+		}
+		if st.Fill.Pattern != "solid" || st.Fill.FgColor.String() != "#BDC0BF" {
+			t.Errorf("header column %d fill = %+v, expected solid #BDC0BF", i, st.Fill)
+		}
+		if st.Border.Bottom.Style != "thin" {
+			t.Errorf("header column %d bottom border = %+v, expected thin", i, st.Border.Bottom)
+		}
+	}
+
+	xsheet.Next()
+	for i, st := range xsheet.Styles() {
+		if st != nil && st.Font.Bold && i > 0 {
+			t.Errorf("data column %d should not be bold", i)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package xls
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pbnjay/grate/xls/crypto"
+)
+
+// SummaryStream returns the raw contents of a property set stream, such as
+// "\x05SummaryInformation" or "\x05DocumentSummaryInformation". When an RC4
+// CryptoAPI encrypted workbook also encrypts its document properties, the
+// streams are decrypted from the "encryption" stream.
+func (b *WorkBook) SummaryStream(name string) ([]byte, error) {
+	if b.doc == nil {
+		return nil, errors.New("xls: no document streams")
+	}
+
+	if b.dec != nil {
+		if rdr, err := b.doc.Open("encryption"); err == nil {
+			raw, err := io.ReadAll(rdr)
+			if err != nil {
+				return nil, err
+			}
+			streams, err := crypto.DecryptSummary(b.dec, raw)
+			if err != nil {
+				return nil, err
+			}
+			if data, ok := streams[name]; ok {
+				return data, nil
+			}
+		}
+	}
+
+	rdr, err := b.doc.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(rdr)
+}
@@ -0,0 +1,102 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// maps the valType field values to ST_DataValidationType names.
+var validationTypes = []string{"none", "whole", "decimal", "list", "date",
+	"time", "textLength", "custom"}
+
+// maps the typOperator field values to ST_DataValidationOperator names,
+// also used for conditional formatting (offset by one).
+var comparisonOperators = []string{"between", "notBetween", "equal", "notEqual",
+	"greaterThan", "lessThan", "greaterThanOrEqual", "lessThanOrEqual"}
+
+// maps the errStyle field values to ST_DataValidationErrorStyle names.
+var validationErrorStyles = []string{"stop", "warning", "information"}
+
+var errInvalidDv = errors.New("xls: invalid data validation record")
+
+// 2.4.95 Dv
+func parseDv(data []byte) (commonxl.Validation, error) {
+	var v commonxl.Validation
+	if len(data) < 4 {
+		return v, errInvalidDv
+	}
+	flags := binary.LittleEndian.Uint32(data)
+	v.Type = lookupName(validationTypes, int(flags&0x0F))
+	v.ErrorStyle = lookupName(validationErrorStyles, int((flags>>4)&0x07))
+	v.AllowBlank = (flags & 0x100) != 0
+	v.ShowDropDown = (flags & 0x200) == 0 // fSuppressCombo
+	v.ShowInputMessage = (flags & 0x40000) != 0
+	v.ShowErrorMessage = (flags & 0x80000) != 0
+	v.Operator = lookupName(comparisonOperators, int((flags>>20)&0x0F))
+	data = data[4:]
+
+	for _, dest := range []*string{&v.PromptTitle, &v.ErrorTitle, &v.Prompt, &v.Error} {
+		str, n, err := decodeXLUnicodeString(data)
+		if err != nil {
+			return v, err
+		}
+		// empty strings are written as a single NUL
+		*dest = strings.TrimRight(str, "\x00")
+		data = data[n:]
+	}
+
+	// 2.5.97 DVParsedFormula
+	var rgces [2][]byte
+	for i := range rgces {
+		if len(data) < 4 {
+			return v, errInvalidDv
+		}
+		cce := int(binary.LittleEndian.Uint16(data))
+		if len(data) < 4+cce {
+			return v, errInvalidDv
+		}
+		rgces[i] = data[4 : 4+cce]
+		data = data[4+cce:]
+	}
+
+	// 2.5.257 SqRefU
+	if len(data) < 2 {
+		return v, errInvalidDv
+	}
+	cref := int(binary.LittleEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < cref*8 {
+		return v, errInvalidDv
+	}
+	for i := 0; i < cref; i++ {
+		v.Ranges = append(v.Ranges, commonxl.Range{
+			FirstRow: int(binary.LittleEndian.Uint16(data)),
+			LastRow:  int(binary.LittleEndian.Uint16(data[2:])),
+			FirstCol: int(binary.LittleEndian.Uint16(data[4:])),
+			LastCol:  int(binary.LittleEndian.Uint16(data[6:])),
+		})
+		data = data[8:]
+	}
+
+	// relative references are based on the top-left cell of the first range
+	var baseRow, baseCol int
+	if len(v.Ranges) > 0 {
+		baseRow, baseCol = v.Ranges[0].FirstRow, v.Ranges[0].FirstCol
+	}
+	for i, dest := range []*string{&v.Formula1, &v.Formula2} {
+		if len(rgces[i]) == 0 {
+			continue
+		}
+		f, err := decodeFormula(rgces[i], baseRow, baseCol)
+		if err != nil && grate.Debug {
+			log.Println("xls: unable to decode validation formula:", err)
+		}
+		*dest = f
+	}
+	return v, nil
+}
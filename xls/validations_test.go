@@ -0,0 +1,78 @@
+package xls
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func appendXLString(b []byte, s string) []byte {
+	if s == "" {
+		s = "\x00"
+	}
+	b = append(b, byte(len(s)), byte(len(s)>>8), 0)
+	return append(b, s...)
+}
+
+func appendDVFormula(b []byte, rgce ...byte) []byte {
+	b = append(b, byte(len(rgce)), byte(len(rgce)>>8), 0, 0)
+	return append(b, rgce...)
+}
+
+func TestParseDv(t *testing.T) {
+	// list of explicit values, allow blank, show error, A2:A10
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, 0x03|0x80|0x100|0x80000)
+	data = appendXLString(data, "")
+	data = appendXLString(data, "Invalid")
+	data = appendXLString(data, "")
+	data = appendXLString(data, "Pick one")
+	data = appendDVFormula(data, 0x17, 6, 0, 'Y', 'e', 's', 0, 'N', 'o')
+	data = appendDVFormula(data)
+	data = append(data, 1, 0, 1, 0, 9, 0, 0, 0, 0, 0)
+
+	v, err := parseDv(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Type != "list" || v.Operator != "between" || v.ErrorStyle != "stop" ||
+		!v.AllowBlank || !v.ShowDropDown || !v.ShowErrorMessage || v.ShowInputMessage {
+		t.Errorf("unexpected flags %+v", v)
+	}
+	if v.ErrorTitle != "Invalid" || v.Error != "Pick one" || v.Prompt != "" {
+		t.Errorf("unexpected messages %+v", v)
+	}
+	if v.Formula1 != `"Yes,No"` || v.Formula2 != "" {
+		t.Errorf("unexpected formulas %q %q", v.Formula1, v.Formula2)
+	}
+	if len(v.Ranges) != 1 || v.Ranges[0].String() != "A2:A10" {
+		t.Errorf("unexpected ranges %v", v.Ranges)
+	}
+
+	if _, err = parseDv(data[:len(data)-4]); err == nil {
+		t.Error("expected error for truncated record")
+	}
+}
+
+func TestDecodeFormula(t *testing.T) {
+	cases := []struct {
+		rgce []byte
+		want string
+	}{
+		{[]byte{0x1E, 10, 0}, "10"},
+		{[]byte{0x1F, 0, 0, 0, 0, 0, 0, 0xF8, 0x3F}, "1.5"},
+		{[]byte{0x24, 4, 0, 1, 0xC0}, "B5"},
+		{[]byte{0x25, 0, 0, 4, 0, 0, 0, 0, 0}, "$A$1:$A$5"},
+		{[]byte{0x1E, 1, 0, 0x1E, 2, 0, 0x03, 0x15, 0x13}, "-(1+2)"},
+		{[]byte{0x2C, 0xFF, 0xFF, 1, 0x80}, "$B2"},
+		{[]byte{0x2C, 0, 0, 0xFF, 0xC0}, "A3"},
+	}
+	for _, c := range cases {
+		got, err := decodeFormula(c.rgce, 2, 1)
+		if err != nil || got != c.want {
+			t.Errorf("decodeFormula(% x) = %q, %v; expected %q", c.rgce, got, err, c.want)
+		}
+	}
+	if _, err := decodeFormula([]byte{0x21, 0, 0}, 0, 0); err == nil {
+		t.Error("expected error for unsupported function token")
+	}
+}
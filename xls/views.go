@@ -0,0 +1,123 @@
+package xls
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// maps the grbitSign field values to ST_FilterOperator names.
+var filterOperators = []string{"", "lessThan", "equal", "lessThanOrEqual",
+	"greaterThan", "notEqual", "greaterThanOrEqual"}
+
+var errInvalidAutoFilter = errors.New("xls: invalid autofilter record")
+
+// parseFilterDatabase decodes the range of a built-in _FilterDatabase
+// name (2.4.150 Lbl), which holds the autofilter range for a sheet.
+// The returned sheet index is 1-based.
+func parseFilterDatabase(data []byte) (int, commonxl.Range, bool) {
+	if len(data) < 16 {
+		return 0, commonxl.Range{}, false
+	}
+	flags := binary.LittleEndian.Uint16(data)
+	cch := int(data[3])
+	cce := int(binary.LittleEndian.Uint16(data[4:]))
+	itab := int(binary.LittleEndian.Uint16(data[8:]))
+	if (flags&0x20) == 0 || cch != 1 || data[15] != 0x0D {
+		// not the built-in _FilterDatabase name
+		return 0, commonxl.Range{}, false
+	}
+	// 1 byte for fHighByte, and a 1 or 2 byte name
+	off := 16
+	if (data[14] & 1) != 0 {
+		off++
+	}
+	if len(data) < off+cce || cce < 11 {
+		return 0, commonxl.Range{}, false
+	}
+	rgce := data[off : off+cce]
+	switch rgce[0] {
+	case 0x3B, 0x5B, 0x7B: // PtgArea3d
+		return itab, commonxl.Range{
+			FirstRow: int(binary.LittleEndian.Uint16(rgce[3:])),
+			LastRow:  int(binary.LittleEndian.Uint16(rgce[5:])),
+			FirstCol: int(binary.LittleEndian.Uint16(rgce[7:]) & 0x3FFF),
+			LastCol:  int(binary.LittleEndian.Uint16(rgce[9:]) & 0x3FFF),
+		}, true
+	}
+	return 0, commonxl.Range{}, false
+}
+
+// 2.4.6 AutoFilter, returns the column offset within the filter range.
+func parseAutoFilter(data []byte) (int, commonxl.FilterColumn, error) {
+	var fc commonxl.FilterColumn
+	if len(data) < 24 {
+		return 0, fc, errInvalidAutoFilter
+	}
+	iEntry := int(binary.LittleEndian.Uint16(data))
+	grbit := binary.LittleEndian.Uint16(data[2:])
+	if (grbit & 0x10) != 0 {
+		// fTopN
+		fc.Type = "top10"
+		fc.Top = (grbit & 0x20) != 0
+		fc.Percent = (grbit & 0x40) != 0
+		fc.Rank = float64(grbit >> 7)
+		return iEntry, fc, nil
+	}
+
+	fc.Type = "custom"
+	fc.And = (grbit & 0x03) == 0
+	strs := data[24:]
+	for _, doper := range [][]byte{data[4:14], data[14:24]} {
+		// 2.5.69 DOPER
+		c := commonxl.FilterCriterion{Operator: lookupName(filterOperators, int(doper[1]))}
+		switch doper[0] {
+		case 0x00:
+			// not used
+			continue
+		case 0x02:
+			c.Value = RKNumber(binary.LittleEndian.Uint32(doper[2:])).String()
+		case 0x04:
+			f := math.Float64frombits(binary.LittleEndian.Uint64(doper[2:]))
+			c.Value = strconv.FormatFloat(f, 'g', -1, 64)
+		case 0x06:
+			// the string follows both DOPERs
+			cch := int(doper[6])
+			if len(strs) < 1 {
+				return 0, fc, errInvalidAutoFilter
+			}
+			n := cch
+			if (strs[0] & 1) != 0 {
+				n *= 2
+			}
+			if len(strs) < 1+n {
+				return 0, fc, errInvalidAutoFilter
+			}
+			// reuse the XLUnicodeString decoder by prefixing the length
+			buf := append([]byte{byte(cch), byte(cch >> 8)}, strs[:1+n]...)
+			c.Value, _, _ = decodeXLUnicodeString(buf)
+			strs = strs[1+n:]
+		case 0x08:
+			// bBoolErr, then fError
+			if doper[3] != 0 {
+				c.Value = grate.NewCellError(doper[2]).Text
+			} else if doper[2] != 0 {
+				c.Value = "TRUE"
+			} else {
+				c.Value = "FALSE"
+			}
+		case 0x0C:
+			c.Operator, c.Value = "equal", ""
+		case 0x0E:
+			c.Operator, c.Value = "notEqual", ""
+		default:
+			return 0, fc, errInvalidAutoFilter
+		}
+		fc.Criteria = append(fc.Criteria, c)
+	}
+	return iEntry, fc, nil
+}
@@ -0,0 +1,85 @@
+package xls
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseFilterDatabase(t *testing.T) {
+	data := make([]byte, 16, 27)
+	data[0] = 0x21 // fHidden | fBuiltin
+	data[3] = 1
+	data[4] = 11
+	data[8] = 2
+	data[15] = 0x0D
+	data = append(data, 0x3B, 0, 0, 1, 0, 20, 0, 0, 0, 3, 0)
+	itab, rng, ok := parseFilterDatabase(data)
+	if !ok || itab != 2 || rng.String() != "A2:D21" {
+		t.Errorf("unexpected filter database %v %d %s", ok, itab, rng)
+	}
+
+	data[15] = 0x06 // _Print_Area
+	if _, _, ok = parseFilterDatabase(data); ok {
+		t.Error("expected other built-in names to be ignored")
+	}
+}
+
+func TestParseAutoFilter(t *testing.T) {
+	// greater than 5 and equal to "abc"
+	data := make([]byte, 24)
+	data[0] = 2
+	data[4], data[5] = 0x02, 4
+	binary.LittleEndian.PutUint32(data[6:], 5<<2|2)
+	data[14], data[15] = 0x06, 2
+	data[20] = 3
+	data = append(data, 0, 'a', 'b', 'c')
+
+	col, fc, err := parseAutoFilter(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if col != 2 || fc.Type != "custom" || !fc.And || len(fc.Criteria) != 2 {
+		t.Fatalf("unexpected filter %d %+v", col, fc)
+	}
+	if c := fc.Criteria[0]; c.Operator != "greaterThan" || c.Value != "5" {
+		t.Errorf("unexpected first criterion %+v", c)
+	}
+	if c := fc.Criteria[1]; c.Operator != "equal" || c.Value != "abc" {
+		t.Errorf("unexpected second criterion %+v", c)
+	}
+
+	// top 10 percent
+	binary.LittleEndian.PutUint16(data[2:], 0x10|0x20|0x40|10<<7)
+	_, fc, err = parseAutoFilter(data)
+	if err != nil || fc.Type != "top10" || !fc.Top || !fc.Percent || fc.Rank != 10 {
+		t.Errorf("unexpected top10 filter %+v %v", fc, err)
+	}
+}
+
+func TestParseAutoFilterBoolErr(t *testing.T) {
+	// equal to TRUE or equal to #DIV/0!
+	data := make([]byte, 24)
+	data[2] = 1
+	data[4], data[5], data[6], data[7] = 0x08, 2, 1, 0
+	data[14], data[15], data[16], data[17] = 0x08, 2, 0x07, 1
+
+	_, fc, err := parseAutoFilter(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.And || len(fc.Criteria) != 2 {
+		t.Fatalf("unexpected filter %+v", fc)
+	}
+	if c := fc.Criteria[0]; c.Operator != "equal" || c.Value != "TRUE" {
+		t.Errorf("unexpected boolean criterion %+v", c)
+	}
+	if c := fc.Criteria[1]; c.Operator != "equal" || c.Value != "#DIV/0!" {
+		t.Errorf("unexpected error criterion %+v", c)
+	}
+
+	data[6] = 0
+	_, fc, err = parseAutoFilter(data)
+	if err != nil || fc.Criteria[0].Value != "FALSE" {
+		t.Errorf("unexpected boolean criterion %+v %v", fc, err)
+	}
+}
@@ -32,25 +32,49 @@ type WorkBook struct {
 	h        *header
 	sheets   []*boundSheet
 	codepage uint16
+	country  uint16
 	dateMode uint16
 	strings  []string
 
 	password   string
 	substreams [][]*rec
 
+	// decryptor for encrypted workbooks
+	dec crypto.Decryptor
+
 	fpos          int64
 	pos2substream map[int64]int
 
 	nfmt commonxl.Formatter
 	xfs  []uint16
+	opts *grate.Options
+
+	fonts   []commonxl.Font
+	styles  []*commonxl.Style
+	palette []uint32
+
+	// autofilter ranges by 1-based sheet index
+	filterRanges map[int]commonxl.Range
+
+	// pivot cache stream IDs by cache index
+	pivotStreams []uint16
 }
 
 func (b *WorkBook) IsProtected() bool {
 	return b.prot
 }
 
-func Open(filename string) (grate.Source, error) {
-	doc, err := cfb.Open(filename)
+// Metadata returns the properties of the workbook.
+func (b *WorkBook) Metadata() grate.Metadata {
+	return grate.Metadata{
+		Date1904: b.dateMode == 1,
+		Locale:   b.nfmt.Locale().Name,
+	}
+}
+
+func Open(filename string, opts ...grate.Option) (grate.Source, error) {
+	o := grate.NewOptions(opts...)
+	doc, err := cfb.Open(filename, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +85,9 @@ func Open(filename string) (grate.Source, error) {
 
 		pos2substream: make(map[int64]int, 16),
 		xfs:           make([]uint16, 0, 128),
+		opts:          o,
 	}
+	b.nfmt.SetOverrides(b.opts)
 
 	rdr, err := doc.Open("Workbook")
 	if err != nil {
@@ -92,7 +118,7 @@ func (b *WorkBook) loadFromStreamWithDecryptor(raw []byte, dec crypto.Decryptor)
 	// "cleartext" contents in line with the decrypted content.
 
 	if grate.Debug {
-		log.Println("  Decrypting xls stream with standard RC4")
+		log.Println("  Decrypting xls stream with RC4")
 	}
 
 	pos := 0
@@ -114,6 +140,9 @@ func (b *WorkBook) loadFromStreamWithDecryptor(raw []byte, dec crypto.Decryptor)
 		o.RecType = recordType(binary.LittleEndian.Uint16(raw[pos : pos+2]))
 		o.DataBytes = binary.LittleEndian.Uint16(raw[pos+2 : pos+4])
 		pos += 4
+		if len(raw[pos:]) < int(o.DataBytes) {
+			return io.ErrUnexpectedEOF
+		}
 
 		// copy to output and decryption stream
 		binary.Write(dec, binary.LittleEndian, o.RecType)
@@ -129,6 +158,9 @@ func (b *WorkBook) loadFromStreamWithDecryptor(raw []byte, dec crypto.Decryptor)
 			tocopy = 0
 
 		case RecTypeBoundSheet8:
+			if o.DataBytes < 4 {
+				return errors.New("xls: invalid BoundSheet8 record")
+			}
 			// copy 32-bit position to output
 			o.Data = raw[pos : pos+4]
 			pos += 4
@@ -172,7 +204,10 @@ func (b *WorkBook) Close() error {
 	return nil
 }
 
-func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
+func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) (err error) {
+	var pos recPos
+	defer grate.Recover(&err, "xls", func() string { return pos.String() })
+
 	b.h = &header{}
 	substr := -1
 	nestedBOF := 0
@@ -191,6 +226,7 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 	rawfull := raw
 	nr, no, err := b.nextRecord(raw)
 	for err == nil {
+		pos = recPos{substream: substr, index: -1, offset: b.fpos, typ: nr.RecType}
 		raw = raw[no:]
 		switch nr.RecType {
 		case RecTypeEOF:
@@ -208,23 +244,34 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 
 		// if there's a FilePass record, the data is encrypted
 		if nr.RecType == RecTypeFilePass && !isDecrypted {
+			if nr.truncated() {
+				return errors.New("xls: invalid FilePass record")
+			}
 			etype := binary.LittleEndian.Uint16(nr.Data)
-			switch etype {
-			case 1:
-				dec, err := crypto.NewBasicRC4(nr.Data[2:])
-				if err != nil {
-					log.Println("xls: rc4 encryption failed to set up", err)
-					return err
-				}
-				return b.loadFromStreamWithDecryptor(rawfull, dec)
-			case 2, 3, 4:
-				log.Println("need Crypto API RC4 decryptor")
-				return errors.New("xls: unsupported Crypto API encryption method")
-			default:
+			if etype != 1 || len(nr.Data) < 4 {
 				return errors.New("xls: unsupported encryption method")
 			}
+			// RC4, the major version selects basic or CryptoAPI
+			var dec crypto.Decryptor
+			if binary.LittleEndian.Uint16(nr.Data[2:]) == 1 {
+				dec, err = crypto.NewBasicRC4(nr.Data[2:])
+			} else {
+				dec, err = crypto.NewCryptoAPIRC4(nr.Data[2:])
+			}
+			if err != nil {
+				log.Println("xls: rc4 encryption failed to set up", err)
+				return err
+			}
+			b.dec = dec
+			return b.loadFromStreamWithDecryptor(rawfull, dec)
 		}
 
+		if substr < 0 {
+			return errors.New("xls: missing BOF record")
+		}
+		if max := b.opts.Limits.MaxRecords; max > 0 && len(b.substreams[substr]) >= max {
+			return grate.ErrLimitExceeded{Limit: "MaxRecords", Max: int64(max)}
+		}
 		b.substreams[substr] = append(b.substreams[substr], nr)
 		nr, no, err = b.nextRecord(raw)
 	}
@@ -240,7 +287,8 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 			log.Printf("  Processing substream %d/%d (%d records)", ss, len(b.substreams), len(records))
 		}
 		for i, nr := range records {
-			if len(nr.Data) == 0 {
+			pos = recPos{substream: ss, index: i, typ: nr.RecType}
+			if len(nr.Data) == 0 || nr.truncated() {
 				continue
 			}
 
@@ -257,7 +305,7 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 					recSet = append(recSet, records[lastIndex])
 				}
 
-				b.strings, err = parseSST(recSet)
+				b.strings, err = parseSST(recSet, b.opts.Phonetic, b.opts.Limits.MaxStrings)
 				if err != nil {
 					return err
 				}
@@ -268,12 +316,13 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 				// done
 
 			case RecTypeBOF:
+				r := &recordReader{data: nr.Data}
 				b.h = &header{
-					Version:  binary.LittleEndian.Uint16(nr.Data[0:2]),
-					DocType:  binary.LittleEndian.Uint16(nr.Data[2:4]),
-					RupBuild: binary.LittleEndian.Uint16(nr.Data[4:6]),
-					RupYear:  binary.LittleEndian.Uint16(nr.Data[6:8]),
-					MiscBits: binary.LittleEndian.Uint64(nr.Data[8:16]),
+					Version:  r.Uint16(),
+					DocType:  r.Uint16(),
+					RupBuild: r.Uint16(),
+					RupYear:  r.Uint16(),
+					MiscBits: r.Uint64(),
 				}
 
 				if b.h.Version != 0x0600 {
@@ -291,11 +340,19 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 				*/
 
 			case RecTypeCodePage:
-				// BIFF8 is entirely UTF-16LE so this is actually ignored
+				// BIFF8 is entirely UTF-16LE so this only hints at the locale
 				b.codepage = binary.LittleEndian.Uint16(nr.Data)
 
+			case RecTypeCountry:
+				// prefer the system regional settings over the UI language
+				b.country = binary.LittleEndian.Uint16(nr.Data)
+				if len(nr.Data) >= 4 && binary.LittleEndian.Uint16(nr.Data[2:]) != 0 {
+					b.country = binary.LittleEndian.Uint16(nr.Data[2:])
+				}
+
 			case RecTypeDate1904:
 				b.dateMode = binary.LittleEndian.Uint16(nr.Data)
+				b.nfmt.Mode1904(b.dateMode == 1)
 
 			case RecTypeFormat:
 				// Format maps a format ID to a code string
@@ -309,10 +366,36 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 
 			case RecTypeXF:
 				// XF records merge multiple style and format directives to one ID
-				// ignore font id at nr.Data[0:2]
 				fmtNo := binary.LittleEndian.Uint16(nr.Data[2:])
 				b.xfs = append(b.xfs, fmtNo)
 
+				st, err := parseXF(nr.Data, b.fonts)
+				if err != nil {
+					st = &commonxl.Style{NumFmt: fmtNo}
+				}
+				b.styles = append(b.styles, st)
+
+			case RecTypeFont:
+				f, err := parseFont(nr.Data)
+				if err != nil {
+					return err
+				}
+				b.fonts = append(b.fonts, f)
+
+			case RecTypePalette:
+				b.palette = parsePalette(nr.Data)
+
+			case RecTypeLbl:
+				if itab, rng, ok := parseFilterDatabase(nr.Data); ok {
+					if b.filterRanges == nil {
+						b.filterRanges = make(map[int]commonxl.Range)
+					}
+					b.filterRanges[itab] = rng
+				}
+
+			case RecTypeSXStreamID:
+				b.pivotStreams = append(b.pivotStreams, binary.LittleEndian.Uint16(nr.Data))
+
 			case RecTypeBoundSheet8:
 				// Identifies the postition within the stream, visibility state,
 				// and name of a worksheet
@@ -334,6 +417,15 @@ func (b *WorkBook) loadFromStream2(raw []byte, isDecrypted bool) error {
 		}
 	}
 
+	// palette records follow the styles, so colors are resolved last
+	ct := &commonxl.ColorTable{Indexed: b.palette}
+	for _, st := range b.styles {
+		ct.ResolveStyle(st)
+	}
+	if lerr := b.setLocale(); lerr != nil {
+		return lerr
+	}
+
 	return err
 }
 
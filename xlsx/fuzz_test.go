@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+// FuzzDocument parses an xlsx file. The parser recovers from panics,
+// so any ParseError is a failure.
+func FuzzDocument(f *testing.F) {
+	files, _ := filepath.Glob("../testdata/*.xlsx")
+	for _, fn := range files {
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var perr grate.ParseError
+		z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		d := &Document{r: z, opts: grate.NewOptions(grate.WithLimits(grate.DefaultLimits))}
+		err = d.load()
+		if errors.As(err, &perr) {
+			t.Fatal(err)
+		}
+		if err != nil {
+			return
+		}
+		names, _ := d.List()
+		for _, name := range names {
+			c, err := d.Get(name)
+			if errors.As(err, &perr) {
+				t.Fatal(err)
+			}
+			if err != nil {
+				continue
+			}
+			for c.Next() {
+				c.Strings()
+				c.Types()
+			}
+		}
+	})
+}
+
+// FuzzSheet parses the XML of a single worksheet.
+func FuzzSheet(f *testing.F) {
+	f.Add(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:C2"/>
+<sheetData>
+  <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1"><v>1.5</v></c><c r="C1" t="b"><v>1</v></c></row>
+  <row r="2"><c r="A2" t="e"><v>#N/A</v></c><c r="B2" t="inlineStr"><is><t>x</t></is></c></row>
+</sheetData>
+<mergeCells count="1"><mergeCell ref="B2:C2"/></mergeCells>
+</worksheet>`)
+
+	f.Fuzz(func(t *testing.T, worksheet string) {
+		s := newTestSheet(t, worksheet, grate.WithLimits(grate.DefaultLimits))
+		s.d.strings = []string{"a"}
+		err := s.parseSheet()
+		var perr grate.ParseError
+		if errors.As(err, &perr) {
+			t.Fatal(err)
+		}
+	})
+}
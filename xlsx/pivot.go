@@ -0,0 +1,287 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+const (
+	relPivotCacheDefinition = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition"
+	relPivotCacheRecords    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords"
+	relPivotTable           = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+)
+
+// pivotCacheRef locates a pivot cache definition by its workbook cacheId.
+type pivotCacheRef struct {
+	id      string
+	docname string
+}
+
+// PivotCaches lists the names (cacheIds) of the pivot caches in the workbook.
+func (d *Document) PivotCaches() ([]string, error) {
+	res := make([]string, 0, len(d.pivotCaches))
+	for _, pc := range d.pivotCaches {
+		res = append(res, pc.id)
+	}
+	return res, nil
+}
+
+// GetPivotCache returns the named pivot cache, with the field names
+// as the first record.
+func (d *Document) GetPivotCache(name string) (grate.Collection, error) {
+	docname, fields, items, err := d.loadPivotCacheDefinition(name)
+	if err != nil {
+		return nil, err
+	}
+	pc := &commonxl.PivotCache{Fields: fields}
+	for _, fn := range d.relTargets(docname, relPivotCacheRecords) {
+		dec, c, err := d.openXML(fn)
+		if err != nil {
+			return nil, err
+		}
+		pc.Records, err = parsePivotCacheRecords(dec, items)
+		c.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pc.Sheet(&d.fmt), nil
+}
+
+// loadPivotCacheDefinition parses the fields and shared items of the
+// named pivot cache, and returns the filename of its definition.
+func (d *Document) loadPivotCacheDefinition(name string) (string, []string, [][]interface{}, error) {
+	for _, ref := range d.pivotCaches {
+		if ref.id != name {
+			continue
+		}
+		dec, c, err := d.openXML(ref.docname)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		defer c.Close()
+		fields, items, err := parsePivotCacheDefinition(dec)
+		return ref.docname, fields, items, err
+	}
+	return "", nil, nil, errors.New("xlsx: pivot cache not found")
+}
+
+// relTargets returns the targets of the given type from the
+// relationships of the named part.
+func (d *Document) relTargets(docname, relType string) []string {
+	base := filepath.Base(docname)
+	sub := strings.TrimSuffix(docname, base)
+	dec, c, err := d.openXML(filepath.Join(sub, "_rels", base+".rels"))
+	if err != nil {
+		return nil
+	}
+	defer c.Close()
+
+	var res []string
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		if v, ok := tok.(xml.StartElement); ok && v.Name.Local == "Relationship" {
+			ax := getAttrs(v.Attr, "Type", "Target")
+			if ax[0] == relType {
+				res = append(res, filepath.Join(sub, ax[1]))
+			}
+		}
+	}
+	return res
+}
+
+// parsePivotCacheDefinition returns the cache field names and their shared items.
+func parsePivotCacheDefinition(dec *xml.Decoder) ([]string, [][]interface{}, error) {
+	var fields []string
+	var items [][]interface{}
+	inShared := false
+
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "cacheField":
+				fields = append(fields, getAttrs(v.Attr, "name")[0])
+				items = append(items, nil)
+			case "sharedItems":
+				inShared = true
+			case "s", "n", "b", "e", "d", "m":
+				if inShared && len(items) > 0 {
+					i := len(items) - 1
+					items[i] = append(items[i], pivotValue(v.Name.Local, getAttrs(v.Attr, "v")[0]))
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == "sharedItems" {
+				inShared = false
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return fields, items, err
+}
+
+// parsePivotCacheRecords returns the cache records, resolving
+// indexes into the shared items of each field.
+func parsePivotCacheRecords(dec *xml.Decoder, items [][]interface{}) ([][]interface{}, error) {
+	var res [][]interface{}
+	var rec []interface{}
+	col := 0
+
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		v, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch v.Name.Local {
+		case "r":
+			rec = make([]interface{}, len(items))
+			res = append(res, rec)
+			col = 0
+		case "x":
+			if col < len(rec) {
+				x, _ := strconv.Atoi(getAttrs(v.Attr, "v")[0])
+				if x >= 0 && x < len(items[col]) {
+					rec[col] = items[col][x]
+				}
+			}
+			col++
+		case "s", "n", "b", "e", "d", "m":
+			if col < len(rec) {
+				rec[col] = pivotValue(v.Name.Local, getAttrs(v.Attr, "v")[0])
+			}
+			col++
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return res, err
+}
+
+// pivotValue converts a pivot cache item to a value.
+func pivotValue(tag, v string) interface{} {
+	switch tag {
+	case "s":
+		return v
+	case "e":
+		return grate.ParseCellError(v)
+	case "n":
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	case "b":
+		return v == "1" || v == "true"
+	case "d":
+		t, err := time.Parse("2006-01-02T15:04:05", v)
+		if err != nil {
+			return v
+		}
+		return t
+	}
+	return nil
+}
+
+// parsePivotTable reads a pivot table definition.
+func (d *Document) parsePivotTable(docname string) (commonxl.PivotTable, error) {
+	var pt commonxl.PivotTable
+	dec, c, err := d.openXML(docname)
+	if err != nil {
+		return pt, err
+	}
+	defer c.Close()
+
+	var names []string // from the pivotField elements
+	var rows, cols, pages []int
+	var dataFlds []int
+	axis := ""
+
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "pivotTableDefinition":
+				ax := getAttrs(v.Attr, "name", "cacheId")
+				pt.Name, pt.Cache = ax[0], ax[1]
+			case "location":
+				if rng := parseSqref(getAttrs(v.Attr, "ref")[0]); len(rng) > 0 {
+					pt.Location = rng[0]
+				}
+			case "pivotField":
+				names = append(names, getAttrs(v.Attr, "name")[0])
+			case "rowFields", "colFields":
+				axis = v.Name.Local
+			case "field":
+				x, err := strconv.Atoi(getAttrs(v.Attr, "x")[0])
+				if err != nil || x < 0 {
+					// -2 is the "Values" pseudo-field
+					continue
+				}
+				if axis == "rowFields" {
+					rows = append(rows, x)
+				} else if axis == "colFields" {
+					cols = append(cols, x)
+				}
+			case "pageField":
+				x, _ := strconv.Atoi(getAttrs(v.Attr, "fld")[0])
+				pages = append(pages, x)
+			case "dataField":
+				ax := getAttrs(v.Attr, "name", "fld", "subtotal")
+				x, _ := strconv.Atoi(ax[1])
+				if ax[2] == "" {
+					ax[2] = "sum"
+				}
+				dataFlds = append(dataFlds, x)
+				pt.DataFields = append(pt.DataFields, commonxl.PivotDataField{
+					Name: ax[0], Function: ax[2]})
+			}
+		case xml.EndElement:
+			if v.Name.Local == "rowFields" || v.Name.Local == "colFields" {
+				axis = ""
+			}
+		}
+	}
+	if err != io.EOF {
+		return pt, err
+	}
+
+	_, fields, _, err := d.loadPivotCacheDefinition(pt.Cache)
+	if err != nil && grate.Debug {
+		log.Println("xlsx: unable to load pivot cache:", err)
+	}
+	name := func(i int) string {
+		if i >= 0 && i < len(names) && names[i] != "" {
+			return names[i]
+		}
+		if i >= 0 && i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	for _, i := range rows {
+		pt.RowFields = append(pt.RowFields, name(i))
+	}
+	for _, i := range cols {
+		pt.ColFields = append(pt.ColFields, name(i))
+	}
+	for _, i := range pages {
+		pt.PageFields = append(pt.PageFields, name(i))
+	}
+	for j, i := range dataFlds {
+		pt.DataFields[j].Field = name(i)
+	}
+	return pt, nil
+}
@@ -0,0 +1,122 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pbnjay/grate"
+)
+
+var pivotTestFiles = map[string]string{
+	"xl/pivotCache/pivotCacheDefinition1.xml": `<pivotCacheDefinition r:id="rId1">
+<cacheFields count="3">
+  <cacheField name="Region"><sharedItems count="2"><s v="East"/><s v="West"/></sharedItems></cacheField>
+  <cacheField name="Date"><sharedItems containsDate="1"/></cacheField>
+  <cacheField name="Sales"><sharedItems containsNumber="1"/></cacheField>
+</cacheFields></pivotCacheDefinition>`,
+	"xl/pivotCache/_rels/pivotCacheDefinition1.xml.rels": `<Relationships>
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords" Target="pivotCacheRecords1.xml"/>
+</Relationships>`,
+	"xl/pivotCache/pivotCacheRecords1.xml": `<pivotCacheRecords count="2">
+<r><x v="1"/><d v="2021-03-04T00:00:00"/><n v="1.5"/></r>
+<r><x v="0"/><m/><n v="20"/></r>
+</pivotCacheRecords>`,
+	"xl/pivotTables/pivotTable1.xml": `<pivotTableDefinition name="PivotTable1" cacheId="4">
+<location ref="E3:F6" firstHeaderRow="1" firstDataRow="1" firstDataCol="1"/>
+<pivotFields count="3"><pivotField axis="axisRow"/><pivotField axis="axisPage"/><pivotField dataField="1"/></pivotFields>
+<rowFields count="1"><field x="0"/></rowFields>
+<colFields count="1"><field x="-2"/></colFields>
+<pageFields count="1"><pageField fld="1" hier="-1"/></pageFields>
+<dataFields count="2"><dataField name="Sum of Sales" fld="2"/><dataField name="Max of Sales" fld="2" subtotal="max"/></dataFields>
+</pivotTableDefinition>`,
+	"xl/worksheets/_rels/sheet1.xml.rels": `<Relationships>
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable" Target="../pivotTables/pivotTable1.xml"/>
+</Relationships>`,
+	"xl/worksheets/sheet1.xml": `<worksheet><dimension ref="A1"/><sheetData/></worksheet>`,
+}
+
+func openPivotTestDoc(t *testing.T) *Document {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range pivotTestFiles {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Document{
+		r:           zr,
+		opts:        grate.NewOptions(),
+		pivotCaches: []pivotCacheRef{{id: "4", docname: "xl/pivotCache/pivotCacheDefinition1.xml"}},
+	}
+}
+
+func TestPivotCache(t *testing.T) {
+	d := openPivotTestDoc(t)
+	names, _ := d.PivotCaches()
+	if len(names) != 1 || names[0] != "4" {
+		t.Fatalf("unexpected pivot caches %v", names)
+	}
+	c, err := d.GetPivotCache("4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{
+		{"Region", "Date", "Sales"},
+		{"West", "03-04-21", "1.5"},
+		{"East", "", "20"},
+	}
+	i := 0
+	for c.Next() {
+		row := c.Strings()
+		if i >= len(expect) {
+			t.Fatalf("unexpected extra row %v", row)
+		}
+		for j, x := range expect[i] {
+			if row[j] != x {
+				t.Errorf("row %d col %d: expected %q, got %q", i, j, x, row[j])
+			}
+		}
+		i++
+	}
+	if i != len(expect) {
+		t.Errorf("expected %d rows, got %d", len(expect), i)
+	}
+}
+
+func TestPivotTable(t *testing.T) {
+	d := openPivotTestDoc(t)
+	s := &Sheet{d: d, docname: "xl/worksheets/sheet1.xml"}
+	if err := s.parseSheet(); err != nil {
+		t.Fatal(err)
+	}
+	pts := s.wrapped.PivotTables()
+	if len(pts) != 1 {
+		t.Fatalf("expected 1 pivot table, got %d", len(pts))
+	}
+	pt := pts[0]
+	if pt.Name != "PivotTable1" || pt.Cache != "4" || pt.Location.String() != "E3:F6" {
+		t.Errorf("unexpected pivot table %+v", pt)
+	}
+	if len(pt.RowFields) != 1 || pt.RowFields[0] != "Region" || len(pt.ColFields) != 0 {
+		t.Errorf("unexpected axis fields %v %v", pt.RowFields, pt.ColFields)
+	}
+	if len(pt.PageFields) != 1 || pt.PageFields[0] != "Date" {
+		t.Errorf("unexpected page fields %v", pt.PageFields)
+	}
+	if len(pt.DataFields) != 2 || pt.DataFields[0].Function != "sum" ||
+		pt.DataFields[1].Function != "max" || pt.DataFields[1].Field != "Sales" {
+		t.Errorf("unexpected data fields %+v", pt.DataFields)
+	}
+}
@@ -26,11 +26,20 @@ type Sheet struct {
 
 var errNotLoaded = errors.New("xlsx: sheet not loaded")
 
-func (s *Sheet) parseSheet() error {
+func (s *Sheet) parseSheet() (err error) {
+	defer grate.Recover(&err, "xlsx", func() string { return s.docname })
+
 	s.wrapped = &commonxl.Sheet{
-		Formatter: &s.d.fmt,
+		Formatter:        &s.d.fmt,
+		XFStyles:         s.d.styles,
+		SkipHidden:       s.d.opts.SkipHidden,
+		Hyperlinks:       s.d.opts.Hyperlinks,
+		Values:           s.d.opts.Values,
+		NumberCategories: s.d.opts.NumberCategories,
+		MaxCells:         s.d.opts.Limits.MaxCells,
 	}
 	linkmap := make(map[string]string)
+	var pivotTables []string
 	base := filepath.Base(s.docname)
 	sub := strings.TrimSuffix(s.docname, base)
 	relsname := filepath.Join(sub, "_rels", base+".rels")
@@ -44,6 +53,9 @@ func (s *Sheet) parseSheet() error {
 				if ax[3] == "External" && ax[1] == "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" {
 					linkmap[ax[0]] = ax[2]
 				}
+				if ax[1] == relPivotTable {
+					pivotTables = append(pivotTables, filepath.Join(sub, ax[2]))
+				}
 			}
 		}
 		clo.Close()
@@ -57,20 +69,61 @@ func (s *Sheet) parseSheet() error {
 
 	currentCellType := BlankCellType
 	currentCell := ""
-	var fno uint16
+	var fno, xf uint16
 	var maxCol, maxRow int
 
+	// inline strings are accumulated until the end of the <is> element
+	var inline richText
+	inValue, inInline := false, false
+
+	// data validation and conditional formatting formulas are collected
+	// from element text into elemText
+	var elemText *string
+	var dv *commonxl.Validation
+	var dvSqref string
+	var cf *commonxl.ConditionalFormat
+	var cfRanges []commonxl.Range
+	var cfFormula string
+	extDepth := 0 // conditional formats in extensions duplicate the main rules
+
+	// only the first sheet view is used
+	numViews := 0
+	activePane := "topLeft"
+	var filterCol *commonxl.FilterColumn
+
 	tok, err := dec.RawToken()
 	for ; err == nil; tok, err = dec.RawToken() {
 		switch v := tok.(type) {
 		case xml.CharData:
-			if currentCell == "" {
+			if inInline {
+				inline.token(tok)
+				continue
+			}
+			if elemText != nil {
+				*elemText += string(v)
+				continue
+			}
+			if currentCell == "" || !inValue {
 				continue
 			}
 			c, r := refToIndexes(currentCell)
 			if c >= 0 && r >= 0 {
-				var val interface{} = string(v)
+				// numbers and shared strings are stored without boxing them
+				switch currentCellType {
+				case NumberCellType:
+					if fval, err := strconv.ParseFloat(string(v), 64); err == nil {
+						s.wrapped.PutFloat(r, c, fval, fno)
+						s.wrapped.SetXF(r, c, xf)
+						continue
+					}
+				case SharedStringCellType:
+					si, _ := strconv.ParseInt(string(v), 10, 64)
+					s.wrapped.PutString(r, c, s.d.strings[si], fno)
+					s.wrapped.SetXF(r, c, xf)
+					continue
+				}
 
+				var val interface{} = string(v)
 				switch currentCellType {
 				case BooleanCellType:
 					if v[0] == '1' {
@@ -79,31 +132,41 @@ func (s *Sheet) parseSheet() error {
 						val = false
 					}
 				case DateCellType:
-					log.Println("CELL DATE", val, fno)
-				case NumberCellType:
-					fval, err := strconv.ParseFloat(string(v), 64)
-					if err == nil {
-						val = fval
+					if t, ok := parseISODate(string(v)); ok {
+						val = t
+						if fno == 0 {
+							// unformatted dates need a date format to display
+							fno = 22
+							if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+								fno = 14
+							}
+						}
 					}
-					//log.Println("CELL NUMBER", val, numFormat)
-				case SharedStringCellType:
-					//log.Println("CELL SHSTR", val, currentCellType, numFormat)
-					si, _ := strconv.ParseInt(string(v), 10, 64)
-					val = s.d.strings[si]
+				case NumberCellType:
+					// not a valid number, keep the text
 				case BlankCellType:
 					//log.Println("CELL BLANK")
 					// don't place any values
 					continue
-				case ErrorCellType, FormulaStringCellType, InlineStringCellType:
-					//log.Println("CELL ERR/FORM/INLINE", val, currentCellType)
+				case FormulaStringCellType, InlineStringCellType:
+					val = decodeXString(string(v))
+				case ErrorCellType:
+					val = grate.ParseCellError(string(v))
 				default:
 					log.Println("CELL UNKNOWN", val, currentCellType, fno)
 				}
 				s.wrapped.Put(r, c, val, fno)
+				s.wrapped.SetXF(r, c, xf)
 			} else {
 				//log.Println("FAIL row/col: ", currentCell)
 			}
 		case xml.StartElement:
+			if inInline {
+				if !inline.token(tok) && grate.Debug {
+					log.Println("      Unhandled inline string xml tag", v.Name.Local, v.Attr)
+				}
+				continue
+			}
 			switch v.Name.Local {
 			case "dimension":
 				ax := getAttrs(v.Attr, "ref")
@@ -123,8 +186,45 @@ func (s *Sheet) parseSheet() error {
 				s.wrapped.Resize(maxRow, maxCol)
 				//log.Println("DIMENSION:", s.minRow, s.minCol, ">", s.maxRow, s.maxCol)
 			case "row":
-				//currentRow = ax["r"] // unsigned int row index
-				//log.Println("ROW", currentRow)
+				ax := getAttrs(v.Attr, "r", "hidden", "ht", "customHeight",
+					"outlineLevel", "collapsed")
+				rn, err := strconv.Atoi(ax[0]) // 1-based row index
+				if err != nil || rn < 1 {
+					continue
+				}
+				l := commonxl.RowLayout{
+					Hidden:       ax[1] == "1" || ax[1] == "true",
+					CustomHeight: ax[3] == "1" || ax[3] == "true",
+					Collapsed:    ax[5] == "1" || ax[5] == "true",
+				}
+				l.Height, _ = strconv.ParseFloat(ax[2], 64)
+				l.OutlineLevel, _ = strconv.Atoi(ax[4])
+				if l != (commonxl.RowLayout{}) {
+					s.wrapped.SetRowLayout(rn-1, l)
+				}
+			case "col":
+				ax := getAttrs(v.Attr, "min", "max", "width", "hidden",
+					"outlineLevel", "collapsed")
+				first, err1 := strconv.Atoi(ax[0]) // 1-based column indexes
+				last, err2 := strconv.Atoi(ax[1])
+				if err1 != nil || err2 != nil || first < 1 || last < first {
+					continue
+				}
+				l := commonxl.ColLayout{
+					Hidden:    ax[3] == "1" || ax[3] == "true",
+					Collapsed: ax[5] == "1" || ax[5] == "true",
+				}
+				l.Width, _ = strconv.ParseFloat(ax[2], 64)
+				l.OutlineLevel, _ = strconv.Atoi(ax[4])
+				s.wrapped.SetColLayout(first-1, last-1, l)
+			case "sheetFormatPr":
+				ax := getAttrs(v.Attr, "outlineLevelRow", "outlineLevelCol")
+				s.wrapped.Outline.MaxRowLevel, _ = strconv.Atoi(ax[0])
+				s.wrapped.Outline.MaxColLevel, _ = strconv.Atoi(ax[1])
+			case "outlinePr":
+				ax := getAttrs(v.Attr, "summaryBelow", "summaryRight")
+				s.wrapped.Outline.SummaryAbove = ax[0] == "0" || ax[0] == "false"
+				s.wrapped.Outline.SummaryLeft = ax[1] == "0" || ax[1] == "false"
 			case "c":
 				ax := getAttrs(v.Attr, "t", "r", "s")
 				currentCellType = CellType(ax[0])
@@ -136,12 +236,17 @@ func (s *Sheet) parseSheet() error {
 				sid, _ := strconv.ParseInt(style, 10, 64)
 				if len(s.d.xfs) > int(sid) {
 					fno = s.d.xfs[sid]
+					xf = uint16(sid)
 				} else {
 					fno = 0
+					xf = 0
 				}
 				//log.Println("CELL", currentCell, sid, numFormat, currentCellType)
 			case "v":
-				//log.Println("CELL VALUE", ax)
+				inValue = true
+			case "is":
+				inInline = true
+				inline.reset()
 
 			case "mergeCell":
 				ax := getAttrs(v.Attr, "ref")
@@ -178,15 +283,204 @@ func (s *Sheet) parseSheet() error {
 				}
 
 			case "hyperlink":
-				ax := getAttrs(v.Attr, "ref", "id")
-				col, row := refToIndexes(ax[0])
-				link := linkmap[ax[1]]
-				s.wrapped.Put(row, col, link, 0)
-				s.wrapped.SetURL(row, col, link)
+				ax := getAttrs(v.Attr, "ref", "id", "location", "tooltip", "display")
+				refs := parseSqref(ax[0])
+				if len(refs) == 0 {
+					continue
+				}
+				link := &commonxl.Hyperlink{
+					URL:      linkmap[ax[1]],
+					Location: ax[2],
+					Tooltip:  ax[3],
+					Display:  ax[4],
+				}
+				if strings.HasPrefix(link.URL, `\\`) || strings.HasPrefix(link.URL, "file:") {
+					link.File, link.URL = link.URL, ""
+				}
+				// links are only shown on the top-left cell of a range
+				row, col := refs[0].FirstRow, refs[0].FirstCol
+				if s.d.opts.Hyperlinks != grate.HyperlinkDefault {
+					s.wrapped.SetHyperlink(row, col, link)
+				} else if target := linkmap[ax[1]]; target != "" {
+					// earlier releases replaced the text with the external target
+					s.wrapped.Put(row, col, target, 0)
+					s.wrapped.SetHyperlink(row, col, link)
+				} else {
+					// or left it empty for internal links
+					s.wrapped.SetHyperlink(row, col, link)
+					s.wrapped.Put(row, col, "", 0)
+				}
+
+			case "dataValidation":
+				ax := getAttrs(v.Attr, "type", "operator", "allowBlank", "showDropDown",
+					"showInputMessage", "showErrorMessage", "errorStyle", "errorTitle",
+					"error", "promptTitle", "prompt", "sqref")
+				dv = &commonxl.Validation{
+					Type:             ax[0],
+					Operator:         ax[1],
+					AllowBlank:       ax[2] == "1" || ax[2] == "true",
+					ShowDropDown:     ax[3] != "1" && ax[3] != "true", // NB inverted
+					ShowInputMessage: ax[4] == "1" || ax[4] == "true",
+					ShowErrorMessage: ax[5] == "1" || ax[5] == "true",
+					ErrorStyle:       ax[6],
+					ErrorTitle:       ax[7],
+					Error:            ax[8],
+					PromptTitle:      ax[9],
+					Prompt:           ax[10],
+				}
+				if dv.Type == "" {
+					dv.Type = "none"
+				}
+				if dv.Operator == "" {
+					dv.Operator = "between"
+				}
+				if dv.ErrorStyle == "" {
+					dv.ErrorStyle = "stop"
+				}
+				dvSqref = ax[11]
+			case "formula1", "formula2", "sqref":
+				if dv == nil {
+					continue
+				}
+				switch v.Name.Local {
+				case "formula1":
+					elemText = &dv.Formula1
+				case "formula2":
+					elemText = &dv.Formula2
+				case "sqref":
+					// x14 extension rules list their ranges in an element
+					elemText = &dvSqref
+				}
+
+			case "conditionalFormatting":
+				if extDepth == 0 {
+					cfRanges = parseSqref(getAttrs(v.Attr, "sqref")[0])
+				}
+			case "cfRule":
+				if extDepth > 0 {
+					continue
+				}
+				ax := getAttrs(v.Attr, "type", "operator", "priority", "stopIfTrue",
+					"dxfId", "text", "rank", "percent", "bottom")
+				cf = &commonxl.ConditionalFormat{
+					Ranges:     cfRanges,
+					Type:       ax[0],
+					Operator:   ax[1],
+					StopIfTrue: ax[3] == "1" || ax[3] == "true",
+					Text:       ax[5],
+					Percent:    ax[7] == "1" || ax[7] == "true",
+					Bottom:     ax[8] == "1" || ax[8] == "true",
+				}
+				cf.Priority, _ = strconv.Atoi(ax[2])
+				cf.Rank, _ = strconv.Atoi(ax[6])
+				if ax[4] != "" {
+					dxfID, _ := strconv.Atoi(ax[4])
+					if dxfID >= 0 && dxfID < len(s.d.dxfs) {
+						cf.Format = s.d.dxfs[dxfID]
+					}
+				}
+			case "formula":
+				if cf != nil {
+					cfFormula = ""
+					elemText = &cfFormula
+				}
+			case "cfvo":
+				if cf != nil {
+					ax := getAttrs(v.Attr, "type", "val")
+					cf.Thresholds = append(cf.Thresholds, commonxl.Threshold{Type: ax[0], Value: ax[1]})
+				}
+			case "color":
+				if cf != nil {
+					ct := s.d.colors
+					if ct == nil {
+						ct = &commonxl.ColorTable{Theme: s.d.theme}
+					}
+					cf.Colors = append(cf.Colors, ct.Resolve(parseColor(v.Attr)))
+				}
+			case "colorScale", "dataBar", "iconSet":
+				// rule containers
+
+			case "sheetView":
+				numViews++
+			case "pane":
+				if numViews != 1 {
+					continue
+				}
+				ax := getAttrs(v.Attr, "xSplit", "ySplit", "activePane", "state")
+				if ax[3] == "frozen" || ax[3] == "frozenSplit" {
+					x, _ := strconv.ParseFloat(ax[0], 64)
+					y, _ := strconv.ParseFloat(ax[1], 64)
+					s.wrapped.View.FrozenCols, s.wrapped.View.FrozenRows = int(x), int(y)
+				}
+				if ax[2] != "" {
+					activePane = ax[2]
+				}
+			case "selection":
+				ax := getAttrs(v.Attr, "pane", "activeCell", "sqref")
+				if ax[0] == "" {
+					ax[0] = "topLeft"
+				}
+				if numViews != 1 || ax[0] != activePane {
+					continue
+				}
+				c, r := refToIndexes(ax[1])
+				if c >= 0 && r >= 0 {
+					s.wrapped.View.ActiveRow, s.wrapped.View.ActiveCol = r, c
+				}
+				s.wrapped.View.Selection = parseSqref(ax[2])
 
-			case "worksheet", "mergeCells", "hyperlinks":
+			case "autoFilter":
+				rng := parseSqref(getAttrs(v.Attr, "ref")[0])
+				if len(rng) == 1 {
+					s.wrapped.AutoFilter = &commonxl.AutoFilter{Range: rng[0]}
+				}
+			case "filterColumn":
+				if s.wrapped.AutoFilter == nil {
+					continue
+				}
+				colID, _ := strconv.Atoi(getAttrs(v.Attr, "colId")[0])
+				filterCol = &commonxl.FilterColumn{Col: s.wrapped.AutoFilter.Range.FirstCol + colID}
+			case "filters", "filter", "customFilters", "customFilter", "top10", "dynamicFilter":
+				if filterCol == nil {
+					continue
+				}
+				switch v.Name.Local {
+				case "filters":
+					filterCol.Type = "values"
+					ax := getAttrs(v.Attr, "blank")
+					filterCol.Blank = ax[0] == "1" || ax[0] == "true"
+				case "filter":
+					filterCol.Values = append(filterCol.Values, getAttrs(v.Attr, "val")[0])
+				case "customFilters":
+					filterCol.Type = "custom"
+					ax := getAttrs(v.Attr, "and")
+					filterCol.And = ax[0] == "1" || ax[0] == "true"
+				case "customFilter":
+					ax := getAttrs(v.Attr, "operator", "val")
+					if ax[0] == "" {
+						ax[0] = "equal"
+					}
+					filterCol.Criteria = append(filterCol.Criteria,
+						commonxl.FilterCriterion{Operator: ax[0], Value: ax[1]})
+				case "top10":
+					ax := getAttrs(v.Attr, "top", "percent", "val")
+					filterCol.Type = "top10"
+					filterCol.Top = ax[0] != "0" && ax[0] != "false"
+					filterCol.Percent = ax[1] == "1" || ax[1] == "true"
+					filterCol.Rank, _ = strconv.ParseFloat(ax[2], 64)
+				case "dynamicFilter":
+					filterCol.Type = "dynamic"
+					filterCol.Criteria = append(filterCol.Criteria,
+						commonxl.FilterCriterion{Operator: getAttrs(v.Attr, "type")[0]})
+				}
+
+			case "extLst":
+				extDepth++
+			case "worksheet", "mergeCells", "hyperlinks", "cols", "sheetPr",
+				"dataValidations", "ext", "sheetViews":
 				// containers
 			case "f":
+				// NB x14 validation formulas are wrapped in <xm:f>
 				//log.Println("start: ", v.Name.Local, v.Attr)
 			default:
 				if grate.Debug {
@@ -194,8 +488,50 @@ func (s *Sheet) parseSheet() error {
 				}
 			}
 		case xml.EndElement:
+			if inInline {
+				if v.Name.Local != "is" {
+					inline.token(tok)
+					continue
+				}
+				inInline = false
+				c, r := refToIndexes(currentCell)
+				if c >= 0 && r >= 0 {
+					s.wrapped.Put(r, c, inline.String(s.d.opts.Phonetic), fno)
+					s.wrapped.SetXF(r, c, xf)
+				}
+				continue
+			}
 
 			switch v.Name.Local {
+			case "formula1", "formula2", "sqref":
+				elemText = nil
+			case "formula":
+				if cf != nil {
+					cf.Formulas = append(cf.Formulas, cfFormula)
+				}
+				elemText = nil
+			case "cfRule":
+				if cf != nil {
+					s.wrapped.AddConditionalFormat(*cf)
+				}
+				cf = nil
+			case "conditionalFormatting":
+				cfRanges = nil
+			case "filterColumn":
+				if filterCol != nil {
+					s.wrapped.AutoFilter.Columns = append(s.wrapped.AutoFilter.Columns, *filterCol)
+				}
+				filterCol = nil
+			case "extLst":
+				extDepth--
+			case "dataValidation":
+				if dv != nil {
+					dv.Ranges = parseSqref(dvSqref)
+					s.wrapped.AddValidation(*dv)
+				}
+				dv, elemText = nil, nil
+			case "v":
+				inValue = false
 			case "c":
 				currentCell = ""
 			case "row":
@@ -208,7 +544,17 @@ func (s *Sheet) parseSheet() error {
 		}
 	}
 	if err == io.EOF {
-		err = nil
+		err = s.wrapped.Err()
+	}
+	for _, fn := range pivotTables {
+		pt, perr := s.d.parsePivotTable(fn)
+		if perr != nil {
+			if grate.Debug {
+				log.Println("xlsx: unable to parse pivot table:", perr)
+			}
+			continue
+		}
+		s.wrapped.AddPivotTable(pt)
 	}
 	return err
 }
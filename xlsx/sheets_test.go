@@ -0,0 +1,338 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// parseTestSheet parses the worksheet XML given from an in-memory document.
+func parseTestSheet(t *testing.T, worksheet string, opts ...grate.Option) *commonxl.Sheet {
+	t.Helper()
+	s := newTestSheet(t, worksheet, opts...)
+	if err := s.parseSheet(); err != nil {
+		t.Fatal(err)
+	}
+	return s.wrapped
+}
+
+// newTestSheet returns an unparsed Sheet for the worksheet XML given.
+func newTestSheet(t *testing.T, worksheet string, opts ...grate.Option) *Sheet {
+	t.Helper()
+	return newTestSheetRels(t, worksheet, "", opts...)
+}
+
+// newTestSheetRels is newTestSheet with the relationships of the sheet.
+func newTestSheetRels(t *testing.T, worksheet, rels string, opts ...grate.Option) *Sheet {
+	t.Helper()
+	const name = "xl/worksheets/sheet1.xml"
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	files := [][2]string{{name, worksheet}}
+	if rels != "" {
+		files = append(files, [2]string{"xl/worksheets/_rels/sheet1.xml.rels", rels})
+	}
+	for _, f := range files {
+		w, err := zw.Create(f[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, f[1])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Sheet{
+		d:       &Document{r: zr, opts: grate.NewOptions(opts...)},
+		docname: name,
+	}
+}
+
+func TestLimits(t *testing.T) {
+	const worksheet = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:B2"/>
+<sheetData>
+  <row r="1"><c r="A1"><v>1</v></c><c r="B1"><v>2</v></c></row>
+  <row r="2"><c r="A2"><v>3</v></c></row>
+</sheetData>
+</worksheet>`
+
+	sheet := parseTestSheet(t, worksheet, grate.WithLimits(grate.Limits{MaxCells: 3}))
+	if sheet.NumRows != 2 {
+		t.Errorf("expected 2 rows, got %d", sheet.NumRows)
+	}
+
+	for _, l := range []grate.Limits{{MaxCells: 2}, {MaxPartBytes: 100}} {
+		err := newTestSheet(t, worksheet, grate.WithLimits(l)).parseSheet()
+		var lerr grate.ErrLimitExceeded
+		if !errors.As(err, &lerr) {
+			t.Errorf("%+v: expected ErrLimitExceeded, got %v", l, err)
+		}
+	}
+}
+
+func TestValidations(t *testing.T) {
+	sheet := parseTestSheet(t, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+  xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"
+  xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">
+<dimension ref="A1:C10"/>
+<sheetData/>
+<dataValidations count="2">
+  <dataValidation type="list" allowBlank="1" showErrorMessage="1" error="Pick one" sqref="A2:A10">
+    <formula1>"Yes,No,""Maybe"""</formula1>
+  </dataValidation>
+  <dataValidation type="whole" operator="between" sqref="B2:B10 C2">
+    <formula1>1</formula1><formula2>100</formula2>
+  </dataValidation>
+</dataValidations>
+<extLst><ext uri="{CCE6A557-97BC-4b89-ADB6-D9C93CAAB3DF}">
+  <x14:dataValidations count="1">
+    <x14:dataValidation type="list" showDropDown="1">
+      <x14:formula1><xm:f>Lists!$A$1:$A$5</xm:f></x14:formula1>
+      <xm:sqref>C3:C10</xm:sqref>
+    </x14:dataValidation>
+  </x14:dataValidations>
+</ext></extLst>
+</worksheet>`)
+
+	dvs := sheet.Validations()
+	if len(dvs) != 3 {
+		t.Fatalf("expected 3 validations, got %d", len(dvs))
+	}
+	if dvs[0].Type != "list" || !dvs[0].AllowBlank || dvs[0].Error != "Pick one" ||
+		!dvs[0].ShowDropDown || len(dvs[0].Ranges) != 1 || dvs[0].Ranges[0].String() != "A2:A10" {
+		t.Errorf("unexpected list validation %+v", dvs[0])
+	}
+	if len(dvs[0].List) != 3 || dvs[0].List[2] != `"Maybe"` {
+		t.Errorf("unexpected list values %q", dvs[0].List)
+	}
+	if dvs[1].Type != "whole" || dvs[1].Operator != "between" ||
+		dvs[1].Formula1 != "1" || dvs[1].Formula2 != "100" || len(dvs[1].Ranges) != 2 {
+		t.Errorf("unexpected whole validation %+v", dvs[1])
+	}
+	if dvs[2].Formula1 != "Lists!$A$1:$A$5" || dvs[2].List != nil || dvs[2].ShowDropDown ||
+		len(dvs[2].Ranges) != 1 || dvs[2].Ranges[0].String() != "C3:C10" {
+		t.Errorf("unexpected x14 validation %+v", dvs[2])
+	}
+}
+
+func TestConditionalFormats(t *testing.T) {
+	sheet := parseTestSheet(t, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:C10"/>
+<sheetData/>
+<conditionalFormatting sqref="B2:B10">
+  <cfRule type="cellIs" dxfId="0" priority="2" operator="greaterThan"><formula>0.8</formula></cfRule>
+  <cfRule type="cellIs" priority="3" operator="between" stopIfTrue="1"><formula>0.5</formula><formula>0.8</formula></cfRule>
+</conditionalFormatting>
+<conditionalFormatting sqref="C2:C10">
+  <cfRule type="colorScale" priority="1">
+    <colorScale><cfvo type="min"/><cfvo type="percentile" val="50"/><cfvo type="max"/>
+    <color rgb="FFF8696B"/><color rgb="FFFFEB84"/><color rgb="FF63BE7B"/></colorScale>
+  </cfRule>
+  <cfRule type="top10" priority="4" rank="10" percent="1" bottom="1"/>
+</conditionalFormatting>
+</worksheet>`)
+
+	cfs := sheet.ConditionalFormats()
+	if len(cfs) != 4 {
+		t.Fatalf("expected 4 rules, got %d", len(cfs))
+	}
+	if cfs[0].Type != "cellIs" || cfs[0].Operator != "greaterThan" || cfs[0].Priority != 2 ||
+		len(cfs[0].Formulas) != 1 || cfs[0].Formulas[0] != "0.8" || cfs[0].Ranges[0].String() != "B2:B10" {
+		t.Errorf("unexpected cellIs rule %+v", cfs[0])
+	}
+	if len(cfs[1].Formulas) != 2 || cfs[1].Formulas[1] != "0.8" || !cfs[1].StopIfTrue {
+		t.Errorf("unexpected between rule %+v", cfs[1])
+	}
+	if cfs[2].Type != "colorScale" || len(cfs[2].Thresholds) != 3 || len(cfs[2].Colors) != 3 ||
+		cfs[2].Thresholds[1].Value != "50" || cfs[2].Colors[2].String() != "#63BE7B" ||
+		cfs[2].Ranges[0].String() != "C2:C10" {
+		t.Errorf("unexpected colorScale rule %+v", cfs[2])
+	}
+	if cfs[3].Type != "top10" || cfs[3].Rank != 10 || !cfs[3].Percent || !cfs[3].Bottom {
+		t.Errorf("unexpected top10 rule %+v", cfs[3])
+	}
+}
+
+func TestSheetView(t *testing.T) {
+	sheet := parseTestSheet(t, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:D20"/>
+<sheetViews><sheetView tabSelected="1" workbookViewId="0">
+  <pane xSplit="1" ySplit="2" topLeftCell="B3" activePane="bottomRight" state="frozen"/>
+  <selection pane="topRight" activeCell="B1" sqref="B1"/>
+  <selection pane="bottomRight" activeCell="C5" sqref="C5:D6"/>
+</sheetView></sheetViews>
+<sheetData/>
+<autoFilter ref="A2:D20">
+  <filterColumn colId="1"><filters blank="1"><filter val="East"/><filter val="West"/></filters></filterColumn>
+  <filterColumn colId="2"><customFilters and="1"><customFilter operator="greaterThan" val="5"/><customFilter operator="lessThan" val="10"/></customFilters></filterColumn>
+  <filterColumn colId="3"><top10 percent="1" val="10"/></filterColumn>
+</autoFilter>
+</worksheet>`)
+
+	v := sheet.View
+	if v.FrozenRows != 2 || v.FrozenCols != 1 || v.ActiveRow != 4 || v.ActiveCol != 2 ||
+		len(v.Selection) != 1 || v.Selection[0].String() != "C5:D6" {
+		t.Errorf("unexpected sheet view %+v", v)
+	}
+
+	af := sheet.AutoFilter
+	if af == nil || af.Range.String() != "A2:D20" || len(af.Columns) != 3 {
+		t.Fatalf("unexpected autofilter %+v", af)
+	}
+	if c := af.Columns[0]; c.Col != 1 || c.Type != "values" || !c.Blank || len(c.Values) != 2 {
+		t.Errorf("unexpected values filter %+v", c)
+	}
+	if c := af.Columns[1]; c.Col != 2 || c.Type != "custom" || !c.And || len(c.Criteria) != 2 ||
+		c.Criteria[0].Operator != "greaterThan" || c.Criteria[0].Value != "5" {
+		t.Errorf("unexpected custom filter %+v", c)
+	}
+	if c := af.Columns[2]; c.Type != "top10" || !c.Top || !c.Percent || c.Rank != 10 {
+		t.Errorf("unexpected top10 filter %+v", c)
+	}
+	if sheet.HeaderRows() != 2 {
+		t.Errorf("expected 2 header rows, got %d", sheet.HeaderRows())
+	}
+}
+
+func TestHyperlinks(t *testing.T) {
+	const ws = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:B2"/>
+<sheetData>
+  <row r="1"><c r="A1" t="inlineStr"><is><t>Summary</t></is></c><c r="B1"><v>42</v></c></row>
+</sheetData>
+<hyperlinks>
+  <hyperlink ref="A1" location="Sheet2!A1" tooltip="Jump" display="Summary"/>
+  <hyperlink ref="B1:B2" location="'Data Sheet'!C3"/>
+  <hyperlink ref="A2" location="Totals"/>
+</hyperlinks>
+</worksheet>`
+	sheet := parseTestSheet(t, ws, grate.WithHyperlinks(grate.HyperlinkText))
+	sheet.Next()
+	row := sheet.Raw()
+	h := row[0].Hyperlink()
+	if h == nil || h.Location != "Sheet2!A1" || h.Tooltip != "Jump" || h.URL != "" {
+		t.Fatalf("unexpected hyperlink %+v", h)
+	}
+	if types := sheet.Types(); types[0] != "hyperlink" || types[1] != "float" {
+		t.Errorf("unexpected types %v", types)
+	}
+	if s := sheet.Strings(); s[0] != "Summary" || s[1] != "42" {
+		t.Errorf("unexpected text %v", s)
+	}
+
+	sheet = parseTestSheet(t, ws, grate.WithHyperlinks(grate.HyperlinkBoth))
+	sheet.Next()
+	if s := sheet.Strings(); s[0] != "Summary <Sheet2!A1>" || s[1] != "42 <'Data Sheet'!C3>" {
+		t.Errorf("unexpected text %v", s)
+	}
+	sheet.Next()
+	if s := sheet.Strings(); s[0] != "Totals <Totals>" {
+		t.Errorf("unexpected text %v", s)
+	}
+
+	sheet = parseTestSheet(t, ws, grate.WithHyperlinks(grate.HyperlinkURL))
+	sheet.Next()
+	if s := sheet.Strings(); s[0] != "Sheet2!A1" {
+		t.Errorf("unexpected text %v", s)
+	}
+}
+
+func TestHyperlinksDefault(t *testing.T) {
+	const ws = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+  xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<dimension ref="A1:D2"/>
+<sheetData>
+  <row r="1"><c r="A1" t="inlineStr"><is><t>Home</t></is></c><c r="B1" t="inlineStr"><is><t>Jump</t></is></c></row>
+</sheetData>
+<hyperlinks>
+  <hyperlink ref="A1" r:id="rId1" display="Home"/>
+  <hyperlink ref="B1" location="Sheet2!A1"/>
+  <hyperlink ref="C1" r:id="rId1"/>
+</hyperlinks>
+</worksheet>`
+	const rels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/>
+</Relationships>`
+
+	// the text is replaced by the external target, as in earlier releases
+	s := newTestSheetRels(t, ws, rels)
+	if err := s.parseSheet(); err != nil {
+		t.Fatal(err)
+	}
+	sheet := s.wrapped
+	sheet.Next()
+	if got := sheet.Strings(); len(got) != 3 || got[0] != "https://example.com/" || got[1] != "" || got[2] != "https://example.com/" {
+		t.Errorf("unexpected default text %q", got)
+	}
+	if h := sheet.Raw()[1].Hyperlink(); h == nil || h.Location != "Sheet2!A1" {
+		t.Errorf("unexpected hyperlink %+v", h)
+	}
+
+	s = newTestSheetRels(t, ws, rels, grate.WithHyperlinks(grate.HyperlinkText))
+	if err := s.parseSheet(); err != nil {
+		t.Fatal(err)
+	}
+	sheet = s.wrapped
+	sheet.Next()
+	if got := sheet.Strings(); got[0] != "Home" || got[1] != "Jump" || got[2] != "https://example.com/" {
+		t.Errorf("unexpected text %q", got)
+	}
+}
+
+func TestErrorCells(t *testing.T) {
+	sheet := parseTestSheet(t, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:B1"/>
+<sheetData>
+  <row r="1"><c r="A1" t="e"><f>1/0</f><v>#DIV/0!</v></c><c r="B1" t="str"><v>#N/A</v></c></row>
+</sheetData>
+</worksheet>`)
+	sheet.Next()
+	if types := sheet.Types(); types[0] != "error" || types[1] != "string" {
+		t.Errorf("unexpected types %v", types)
+	}
+	if s := sheet.Strings(); s[0] != "#DIV/0!" || s[1] != "#N/A" {
+		t.Errorf("unexpected text %v", s)
+	}
+	if ce, ok := sheet.Raw()[0].Value().(grate.CellError); !ok || ce.Code != grate.ErrCodeDiv0 {
+		t.Errorf("unexpected value %#v", sheet.Raw()[0].Value())
+	}
+
+	var a, b string
+	err := sheet.Scan(&a, &b)
+	var ce grate.CellError
+	if !errors.As(err, &ce) || ce.Text != "#DIV/0!" {
+		t.Errorf("expected a CellError from Scan, got %v", err)
+	}
+}
+
+func TestISODateCells(t *testing.T) {
+	sheet := parseTestSheet(t, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:C1"/>
+<sheetData>
+  <row r="1"><c r="A1" t="d"><v>2021-03-04T10:30:00</v></c><c r="B1" t="d"><v>2021-03-04</v></c><c r="C1" t="d"><v>later</v></c></row>
+</sheetData>
+</worksheet>`)
+	sheet.Next()
+	if types := sheet.Types(); types[0] != "date" || types[1] != "date" || types[2] != "string" {
+		t.Errorf("unexpected types %v", types)
+	}
+	var a, b time.Time
+	var c string
+	if err := sheet.Scan(&a, &b, &c); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Equal(time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)) || !b.Equal(time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected dates %s %s", a, b)
+	}
+}
@@ -0,0 +1,155 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// richText accumulates the text of a rich string element (CT_Rst, section
+// 18.4.7) as used by shared strings (<si>) and inline strings (<is>).
+// Base text is kept separate from the phonetic runs (<rPh>) so that the
+// phonetic guide text is not glued onto the value.
+type richText struct {
+	inText    bool
+	inPh      bool
+	propDepth int
+
+	base strings.Builder
+	ph   []phoneticRun
+}
+
+// phoneticRun is the reading for the base characters in [start, end).
+type phoneticRun struct {
+	start, end int
+	text       strings.Builder
+}
+
+func (rt *richText) reset() {
+	rt.inText = false
+	rt.inPh = false
+	rt.propDepth = 0
+	rt.base.Reset()
+	rt.ph = rt.ph[:0]
+}
+
+// token processes an XML token within the string element, and
+// returns false if the token was not recognized.
+func (rt *richText) token(tok xml.Token) bool {
+	switch v := tok.(type) {
+	case xml.CharData:
+		if !rt.inText {
+			// whitespace between elements, or run properties
+			return true
+		}
+		if rt.inPh && len(rt.ph) > 0 {
+			rt.ph[len(rt.ph)-1].text.Write(v)
+		} else {
+			rt.base.Write(v)
+		}
+	case xml.StartElement:
+		if rt.propDepth > 0 {
+			rt.propDepth++
+			return true
+		}
+		switch v.Name.Local {
+		case "t":
+			rt.inText = true
+		case "r", "phoneticPr":
+			// containers, or phonetic settings we don't need
+		case "rPr":
+			// run properties (fonts, colors, etc) are ignored
+			rt.propDepth = 1
+		case "rPh":
+			rt.inPh = true
+			ax := getAttrs(v.Attr, "sb", "eb")
+			start, _ := strconv.Atoi(ax[0])
+			end, _ := strconv.Atoi(ax[1])
+			rt.ph = append(rt.ph, phoneticRun{start: start, end: end})
+		default:
+			return false
+		}
+	case xml.EndElement:
+		if rt.propDepth > 0 {
+			rt.propDepth--
+			return true
+		}
+		switch v.Name.Local {
+		case "t":
+			rt.inText = false
+		case "rPh":
+			rt.inPh = false
+		}
+	}
+	return true
+}
+
+// String returns the decoded text. If phonetic is true and phonetic runs
+// are present, the reading is returned with each run replacing the base
+// characters it annotates.
+func (rt *richText) String(phonetic bool) string {
+	base := decodeXString(rt.base.String())
+	if !phonetic || len(rt.ph) == 0 {
+		return base
+	}
+
+	runs := make([]phoneticRun, len(rt.ph))
+	copy(runs, rt.ph)
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].start < runs[j].start
+	})
+
+	br := []rune(base)
+	last := 0
+	var sb strings.Builder
+	for i := range runs {
+		start, end := runs[i].start, runs[i].end
+		if start < last || start > len(br) {
+			continue
+		}
+		if end > len(br) {
+			end = len(br)
+		}
+		sb.WriteString(string(br[last:start]))
+		sb.WriteString(decodeXString(runs[i].text.String()))
+		last = end
+	}
+	sb.WriteString(string(br[last:]))
+	return sb.String()
+}
+
+// decodeXString decodes the _xHHHH_ escape sequences used in ST_Xstring
+// values (section 22.9.2.19) for characters that cannot be represented in XML.
+// "_x005F_" escapes a literal underscore.
+func decodeXString(s string) string {
+	if !strings.Contains(s, "_x") {
+		return s
+	}
+
+	var sb strings.Builder
+	var units []uint16
+	flush := func() {
+		if len(units) > 0 {
+			sb.WriteString(string(utf16.Decode(units)))
+			units = units[:0]
+		}
+	}
+	for i := 0; i < len(s); {
+		if i+7 <= len(s) && s[i] == '_' && s[i+1] == 'x' && s[i+6] == '_' {
+			x, err := strconv.ParseUint(s[i+2:i+6], 16, 16)
+			if err == nil {
+				// collect UTF-16 code units so that surrogate pairs combine
+				units = append(units, uint16(x))
+				i += 7
+				continue
+			}
+		}
+		flush()
+		sb.WriteByte(s[i])
+		i++
+	}
+	flush()
+	return sb.String()
+}
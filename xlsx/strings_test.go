@@ -0,0 +1,55 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var xstrings = [][2]string{
+	{"plain text", "plain text"},
+	{"line_x000D__x000A_break", "line\r\nbreak"},
+	{"_x005F_x000D_", "_x000D_"},
+	{"tab_x0009_", "tab\t"},
+	{"_xD83D__xDE00_", "\U0001F600"},
+	{"not_xHHHH_escaped", "not_xHHHH_escaped"},
+	{"short_x00", "short_x00"},
+}
+
+func TestDecodeXString(t *testing.T) {
+	for _, c := range xstrings {
+		if got := decodeXString(c[0]); got != c[1] {
+			t.Errorf("decodeXString(%q) = %q, expected %q", c[0], got, c[1])
+		}
+	}
+}
+
+const phoneticSI = `<si>
+  <r><t>東京</t></r>
+  <r><rPr><b/><sz val="11"/></rPr><t xml:space="preserve">都 </t></r>
+  <rPh sb="0" eb="2"><t>トウキョウ</t></rPh>
+  <rPh sb="2" eb="3"><t>ト</t></rPh>
+  <phoneticPr fontId="1"/>
+</si>`
+
+func TestRichTextPhonetic(t *testing.T) {
+	var rt richText
+	dec := xml.NewDecoder(strings.NewReader(phoneticSI))
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "si" {
+			rt.reset()
+			continue
+		}
+		if !rt.token(tok) {
+			t.Fatalf("unexpected token %+v", tok)
+		}
+	}
+
+	if got := rt.String(false); got != "東京都 " {
+		t.Errorf("base text = %q, expected %q", got, "東京都 ")
+	}
+	if got := rt.String(true); got != "トウキョウト " {
+		t.Errorf("phonetic text = %q, expected %q", got, "トウキョウト ")
+	}
+}
@@ -0,0 +1,393 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/pbnjay/grate"
+	"github.com/pbnjay/grate/commonxl"
+)
+
+// xfRecord holds the raw style references of a <xf> element.
+type xfRecord struct {
+	numFmt uint16
+	font   int
+	fill   int
+	border int
+	align  *commonxl.Alignment
+}
+
+func (d *Document) parseStyles(dec *xml.Decoder) error {
+	baseNumFormats := []string{}
+	d.xfs = d.xfs[:0]
+
+	var (
+		fonts    []commonxl.Font
+		fills    []commonxl.Fill
+		borders  []commonxl.Border
+		baseXFs  []xfRecord
+		cellXFs  []xfRecord
+		palette  []uint32
+		curEdge  *commonxl.BorderEdge
+		curXF    *xfRecord
+		skip     int // depth of ignored content (gradients, extLst)
+		inFont   bool
+		inFill   bool
+		inBorder bool
+
+		// differential formats reuse the font, fill and border parsing,
+		// these mark where the current dxf's entries start
+		dxf                         *commonxl.Style
+		dxfFont, dxfFill, dxfBorder int
+	)
+	d.dxfs = d.dxfs[:0]
+
+	section := 0
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch v.Name.Local {
+			case "styleSheet", "fonts", "fills", "borders", "colors", "indexedColors", "dxfs":
+				// containers
+			case "extLst", "gradientFill", "mruColors":
+				// extensions are not cell styles
+				skip = 1
+				if v.Name.Local == "gradientFill" && len(fills) > 0 {
+					fills[len(fills)-1].Pattern = "gradient"
+				}
+			case "numFmt":
+				ax := getAttrs(v.Attr, "numFmtId", "formatCode")
+				fmtNo, _ := strconv.ParseInt(ax[0], 10, 16)
+				d.fmt.Add(uint16(fmtNo), ax[1])
+				if dxf != nil {
+					dxf.NumFmt = uint16(fmtNo)
+				}
+
+			case "dxf":
+				dxf = &commonxl.Style{}
+				dxfFont, dxfFill, dxfBorder = len(fonts), len(fills), len(borders)
+
+			case "font":
+				fonts = append(fonts, commonxl.Font{})
+				inFont = true
+			case "b", "i", "strike", "u", "sz", "name":
+				if !inFont {
+					continue
+				}
+				f := &fonts[len(fonts)-1]
+				val := getAttrs(v.Attr, "val")[0]
+				on := val != "0" && val != "false"
+				switch v.Name.Local {
+				case "b":
+					f.Bold = on
+				case "i":
+					f.Italic = on
+				case "strike":
+					f.Strike = on
+				case "u":
+					f.Underline = val != "none"
+				case "sz":
+					f.Size, _ = strconv.ParseFloat(val, 64)
+				case "name":
+					f.Name = val
+				}
+
+			case "fill":
+				fills = append(fills, commonxl.Fill{Pattern: "none"})
+				inFill = true
+			case "patternFill":
+				if pt := getAttrs(v.Attr, "patternType")[0]; pt != "" && len(fills) > 0 {
+					fills[len(fills)-1].Pattern = pt
+				}
+
+			case "border":
+				borders = append(borders, commonxl.Border{})
+				inBorder = true
+			case "left", "start", "right", "end", "top", "bottom":
+				if !inBorder {
+					continue
+				}
+				b := &borders[len(borders)-1]
+				switch v.Name.Local {
+				case "left", "start":
+					curEdge = &b.Left
+				case "right", "end":
+					curEdge = &b.Right
+				case "top":
+					curEdge = &b.Top
+				case "bottom":
+					curEdge = &b.Bottom
+				}
+				curEdge.Style = getAttrs(v.Attr, "style")[0]
+			case "diagonal", "vertical", "horizontal":
+				// not tracked, but may contain colors
+				curEdge = nil
+
+			case "color", "fgColor", "bgColor":
+				c := parseColor(v.Attr)
+				switch {
+				case inFont:
+					fonts[len(fonts)-1].Color = c
+				case inFill && v.Name.Local == "fgColor":
+					fills[len(fills)-1].FgColor = c
+				case inFill && v.Name.Local == "bgColor":
+					fills[len(fills)-1].BgColor = c
+				case inBorder && curEdge != nil:
+					curEdge.Color = c
+				}
+
+			case "rgbColor":
+				c := parseColor(v.Attr)
+				palette = append(palette, c.ARGB)
+
+			case "cellStyleXfs":
+				section = 1
+			case "cellXfs":
+				section = 2
+				ax := getAttrs(v.Attr, "count")
+				n, _ := strconv.ParseInt(ax[0], 10, 64)
+				d.xfs = make([]uint16, 0, n)
+
+			case "xf":
+				ax := getAttrs(v.Attr, "numFmtId", "applyNumberFormat", "xfId",
+					"fontId", "fillId", "borderId")
+				xr := xfRecord{}
+				xr.font, _ = strconv.Atoi(ax[3])
+				xr.fill, _ = strconv.Atoi(ax[4])
+				xr.border, _ = strconv.Atoi(ax[5])
+				if section == 1 {
+					// load base styles, but only save number format
+					if ax[1] == "0" {
+						baseNumFormats = append(baseNumFormats, "0")
+					} else {
+						baseNumFormats = append(baseNumFormats, ax[0])
+					}
+					baseXFs = append(baseXFs, xr)
+					curXF = &baseXFs[len(baseXFs)-1]
+				} else if section == 2 {
+					// actual referencable cell styles
+					// 1) get base style so we can inherit format properly
+					baseID, _ := strconv.ParseInt(ax[2], 10, 64)
+					numFmtID := "0"
+					if len(baseNumFormats) > int(baseID) {
+						numFmtID = baseNumFormats[baseID]
+					}
+
+					// 2) check if this XF overrides the base format
+					if ax[1] == "0" {
+						// remove the format (if it was inherited)
+						numFmtID = "0"
+					} else {
+						numFmtID = ax[0]
+					}
+
+					nfid, _ := strconv.ParseInt(numFmtID, 10, 16)
+					d.xfs = append(d.xfs, uint16(nfid))
+
+					// inherit the alignment from the base style, unless overridden below
+					xr.numFmt = uint16(nfid)
+					if int(baseID) < len(baseXFs) {
+						xr.align = baseXFs[baseID].align
+					}
+					cellXFs = append(cellXFs, xr)
+					curXF = &cellXFs[len(cellXFs)-1]
+				} else if grate.Debug {
+					log.Println("  xf outside of cellStyleXfs and cellXfs")
+				}
+
+			case "alignment":
+				if curXF == nil && dxf == nil {
+					continue
+				}
+				ax := getAttrs(v.Attr, "horizontal", "vertical", "wrapText",
+					"shrinkToFit", "indent", "textRotation")
+				al := &commonxl.Alignment{
+					Horizontal:  ax[0],
+					Vertical:    ax[1],
+					WrapText:    ax[2] == "1" || ax[2] == "true",
+					ShrinkToFit: ax[3] == "1" || ax[3] == "true",
+				}
+				if al.Horizontal == "" {
+					al.Horizontal = "general"
+				}
+				if al.Vertical == "" {
+					al.Vertical = "bottom"
+				}
+				al.Indent, _ = strconv.Atoi(ax[4])
+				al.Rotation, _ = strconv.Atoi(ax[5])
+				if dxf != nil {
+					dxf.Alignment = *al
+					continue
+				}
+				curXF.align = al
+
+			default:
+				if grate.Debug {
+					log.Println("  Unhandled style xml tag", v.Name.Local, v.Attr)
+				}
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch v.Name.Local {
+			case "cellStyleXfs":
+				section = 0
+			case "cellXfs":
+				section = 0
+			case "font":
+				inFont = false
+			case "fill":
+				inFill = false
+			case "border":
+				inBorder = false
+				curEdge = nil
+			case "xf":
+				curXF = nil
+			case "dxf":
+				if dxf == nil {
+					continue
+				}
+				if len(fonts) > dxfFont {
+					dxf.Font = fonts[dxfFont]
+				}
+				if len(fills) > dxfFill {
+					dxf.Fill = fills[dxfFill]
+					// solid differential fills use the background color
+					if dxf.Fill.FgColor.Kind == commonxl.NoColor {
+						dxf.Fill.FgColor = dxf.Fill.BgColor
+					}
+					if dxf.Fill.Pattern == "none" && dxf.Fill.FgColor.Kind != commonxl.NoColor {
+						dxf.Fill.Pattern = "solid"
+					}
+				}
+				if len(borders) > dxfBorder {
+					dxf.Border = borders[dxfBorder]
+				}
+				fonts, fills, borders = fonts[:dxfFont], fills[:dxfFill], borders[:dxfBorder]
+				d.dxfs = append(d.dxfs, dxf)
+				dxf = nil
+			}
+		default:
+			if grate.Debug {
+				log.Printf("      Unhandled style xml tokens %T %+v", tok, tok)
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	ct := &commonxl.ColorTable{Theme: d.theme}
+	if len(palette) > 0 {
+		ct.Indexed = palette
+	}
+	d.colors = ct
+	for _, st := range d.dxfs {
+		ct.ResolveStyle(st)
+	}
+	d.styles = make([]*commonxl.Style, len(cellXFs))
+	for i, xr := range cellXFs {
+		st := &commonxl.Style{NumFmt: xr.numFmt}
+		if xr.font >= 0 && xr.font < len(fonts) {
+			st.Font = fonts[xr.font]
+		}
+		if xr.fill >= 0 && xr.fill < len(fills) {
+			st.Fill = fills[xr.fill]
+		}
+		if xr.border >= 0 && xr.border < len(borders) {
+			st.Border = borders[xr.border]
+		}
+		if xr.align != nil {
+			st.Alignment = *xr.align
+		} else {
+			st.Alignment = commonxl.Alignment{Horizontal: "general", Vertical: "bottom"}
+		}
+		ct.ResolveStyle(st)
+		d.styles[i] = st
+	}
+	return err
+}
+
+// parseColor decodes the attributes of a CT_Color element.
+func parseColor(attrs []xml.Attr) commonxl.Color {
+	ax := getAttrs(attrs, "rgb", "theme", "indexed", "tint", "auto")
+	c := commonxl.Color{}
+	switch {
+	case ax[0] != "":
+		c.Kind = commonxl.RGBColor
+		c.ARGB = parseRGB(ax[0])
+	case ax[1] != "":
+		c.Kind = commonxl.ThemeColor
+		c.Index, _ = strconv.Atoi(ax[1])
+	case ax[2] != "":
+		c.Kind = commonxl.IndexedColor
+		c.Index, _ = strconv.Atoi(ax[2])
+	case ax[4] == "1" || ax[4] == "true":
+		c.Kind = commonxl.AutoColor
+	}
+	if ax[3] != "" {
+		c.Tint, _ = strconv.ParseFloat(ax[3], 64)
+	}
+	return c
+}
+
+// parseRGB decodes a hex RGB or ARGB color value, e.g. "FFFF0000" or "FF0000".
+func parseRGB(s string) uint32 {
+	x, _ := strconv.ParseUint(s, 16, 32)
+	if len(s) <= 6 {
+		x |= 0xFF000000
+	}
+	return uint32(x)
+}
+
+// theme color scheme element names, in SpreadsheetML theme index order.
+var themeColorNames = []string{"lt1", "dk1", "lt2", "dk2", "accent1", "accent2",
+	"accent3", "accent4", "accent5", "accent6", "hlink", "folHlink"}
+
+func (d *Document) parseTheme(dec *xml.Decoder) error {
+	d.theme = make([]uint32, len(themeColorNames))
+	current := -1
+	tok, err := dec.RawToken()
+	for ; err == nil; tok, err = dec.RawToken() {
+		switch v := tok.(type) {
+		case xml.StartElement:
+			switch v.Name.Local {
+			case "srgbClr":
+				if current >= 0 {
+					d.theme[current] = parseRGB(getAttrs(v.Attr, "val")[0])
+				}
+			case "sysClr":
+				if current >= 0 {
+					d.theme[current] = parseRGB(getAttrs(v.Attr, "lastClr")[0])
+				}
+			default:
+				for i, n := range themeColorNames {
+					if n == v.Name.Local {
+						current = i
+					}
+				}
+			}
+		case xml.EndElement:
+			if v.Name.Local == "clrScheme" {
+				// only the color scheme is needed
+				return nil
+			}
+			if current >= 0 && v.Name.Local == themeColorNames[current] {
+				current = -1
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
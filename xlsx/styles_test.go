@@ -0,0 +1,78 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/pbnjay/grate/commonxl"
+)
+
+func TestStyles(t *testing.T) {
+	wb, err := Open("../testdata/basic.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+
+	sheets, err := wb.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := wb.Get(sheets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	xsheet := sheet.(*commonxl.Sheet)
+
+	// header row is bold with a gray fill and thin borders
+	xsheet.Next()
+	for i, st := range xsheet.Styles() {
+		if st == nil {
+			t.Fatalf("missing style for header column %d", i)
+		}
+		if !st.Font.Bold || st.Font.Name != "Helvetica Neue" {
+			t.Errorf("header column %d font = %+v, expected bold Helvetica Neue", i, st.Font)
+		}
+		if st.Fill.Pattern != "solid" || st.Fill.FgColor.String() != "#BDC0BF" {
+			t.Errorf("header column %d fill = %+v, expected solid #BDC0BF", i, st.Fill)
+		}
+		if st.Border.Bottom.Style != "thin" {
+			t.Errorf("header column %d bottom border = %+v, expected thin", i, st.Border.Bottom)
+		}
+	}
+
+	xsheet.Next()
+	for i, st := range xsheet.Styles() {
+		if st != nil && st.Font.Bold && i > 0 {
+			t.Errorf("data column %d should not be bold", i)
+		}
+	}
+}
+
+func TestDifferentialStyles(t *testing.T) {
+	d := &Document{}
+	err := d.parseStyles(xml.NewDecoder(strings.NewReader(`<styleSheet>
+<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="1"><fill><patternFill patternType="none"/></fill></fills>
+<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellXfs>
+<dxfs count="1"><dxf>
+  <font><b/><color rgb="FF9C0006"/></font>
+  <fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill>
+</dxf></dxfs>
+</styleSheet>`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.styles) != 1 || d.styles[0].Font.Name != "Calibri" || d.styles[0].Font.Bold {
+		t.Errorf("unexpected cell styles %+v", d.styles)
+	}
+	if len(d.dxfs) != 1 {
+		t.Fatalf("expected 1 differential style, got %d", len(d.dxfs))
+	}
+	dxf := d.dxfs[0]
+	if !dxf.Font.Bold || dxf.Font.Color.String() != "#9C0006" ||
+		dxf.Fill.Pattern != "solid" || dxf.Fill.FgColor.String() != "#FFC7CE" {
+		t.Errorf("unexpected differential style %+v", dxf)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/xml"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/pbnjay/grate/commonxl"
 )
 
 type CellType string
@@ -79,6 +82,24 @@ func refToIndexes(r string) (column, row int) {
 	return int(cn), int(rn) - 1
 }
 
+// parseSqref decodes a space-separated list of A1 style ranges, e.g. "A1:B5 D7".
+func parseSqref(sqref string) []commonxl.Range {
+	var res []commonxl.Range
+	for _, ref := range strings.Fields(sqref) {
+		dims := strings.Split(ref, ":")
+		c1, r1 := refToIndexes(dims[0])
+		c2, r2 := c1, r1
+		if len(dims) > 1 {
+			c2, r2 = refToIndexes(dims[1])
+		}
+		if c1 < 0 || r1 < 0 || c2 < 0 || r2 < 0 {
+			continue
+		}
+		res = append(res, commonxl.Range{FirstRow: r1, FirstCol: c1, LastRow: r2, LastCol: c2})
+	}
+	return res
+}
+
 func getAttrs(attrs []xml.Attr, keys ...string) []string {
 	res := make([]string, len(keys))
 	for _, a := range attrs {
@@ -90,3 +111,22 @@ func getAttrs(attrs []xml.Attr, keys ...string) []string {
 	}
 	return res
 }
+
+// isoDateFormats are the ISO 8601 layouts accepted in t="d" cells.
+var isoDateFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseISODate decodes the value of a t="d" cell.
+func parseISODate(v string) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+	for _, layout := range isoDateFormats {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
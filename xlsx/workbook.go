@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/pbnjay/grate"
@@ -80,7 +79,17 @@ func (d *Document) parseWorkbook(dec *xml.Decoder) error {
 					err:     errNotLoaded,
 				}
 				d.sheets = append(d.sheets, s)
-			case "workbook", "sheets":
+			case "workbookPr":
+				v1904 := getAttrs(v.Attr, "date1904")[0]
+				d.date1904 = v1904 == "1" || v1904 == "true"
+				d.fmt.Mode1904(d.date1904)
+			case "pivotCache":
+				ax := getAttrs(v.Attr, "cacheId", "id")
+				d.pivotCaches = append(d.pivotCaches, pivotCacheRef{
+					id:      ax[0],
+					docname: d.rels[relPivotCacheDefinition][ax[1]],
+				})
+			case "workbook", "sheets", "pivotCaches":
 				// containers
 			default:
 				if grate.Debug {
@@ -101,111 +110,33 @@ func (d *Document) parseWorkbook(dec *xml.Decoder) error {
 	return err
 }
 
-func (d *Document) parseStyles(dec *xml.Decoder) error {
-	baseNumFormats := []string{}
-	d.xfs = d.xfs[:0]
-
-	section := 0
-	tok, err := dec.RawToken()
-	for ; err == nil; tok, err = dec.RawToken() {
-		switch v := tok.(type) {
-		case xml.StartElement:
-			switch v.Name.Local {
-			case "styleSheet":
-				// container
-			case "numFmt":
-				ax := getAttrs(v.Attr, "numFmtId", "formatCode")
-				fmtNo, _ := strconv.ParseInt(ax[0], 10, 16)
-				d.fmt.Add(uint16(fmtNo), ax[1])
-
-			case "cellStyleXfs":
-				section = 1
-			case "cellXfs":
-				section = 2
-				ax := getAttrs(v.Attr, "count")
-				n, _ := strconv.ParseInt(ax[0], 10, 64)
-				d.xfs = make([]uint16, 0, n)
-
-			case "xf":
-				ax := getAttrs(v.Attr, "numFmtId", "applyNumberFormat", "xfId")
-				if section == 1 {
-					// load base styles, but only save number format
-					if ax[1] == "0" {
-						baseNumFormats = append(baseNumFormats, "0")
-					} else {
-						baseNumFormats = append(baseNumFormats, ax[0])
-					}
-				} else if section == 2 {
-					// actual referencable cell styles
-					// 1) get base style so we can inherit format properly
-					baseID, _ := strconv.ParseInt(ax[2], 10, 64)
-					numFmtID := "0"
-					if len(baseNumFormats) > int(baseID) {
-						numFmtID = baseNumFormats[baseID]
-					}
-
-					// 2) check if this XF overrides the base format
-					if ax[1] == "0" {
-						// remove the format (if it was inherited)
-						numFmtID = "0"
-					} else {
-						numFmtID = ax[0]
-					}
-
-					nfid, _ := strconv.ParseInt(numFmtID, 10, 16)
-					d.xfs = append(d.xfs, uint16(nfid))
-				} else {
-					panic("wheres is this xf??")
-				}
-			default:
-				if grate.Debug {
-					log.Println("  Unhandled style xml tag", v.Name.Local, v.Attr)
-				}
-			}
-		case xml.EndElement:
-			switch v.Name.Local {
-			case "cellStyleXfs":
-				section = 0
-			case "cellXfs":
-				section = 0
-			}
-		default:
-			if grate.Debug {
-				log.Printf("      Unhandled style xml tokens %T %+v", tok, tok)
-			}
-		}
-	}
-	if err == io.EOF {
-		err = nil
-	}
-	return err
-}
-
 func (d *Document) parseSharedStrings(dec *xml.Decoder) error {
-	val := ""
+	var val richText
 	tok, err := dec.RawToken()
 	for ; err == nil; tok, err = dec.RawToken() {
 		switch v := tok.(type) {
-		case xml.CharData:
-			val += string(v)
 		case xml.StartElement:
 			switch v.Name.Local {
 			case "si":
-				val = ""
-			case "t":
-				// no attributes to parse, we only want the CharData ...
+				val.reset()
 			case "sst":
 				// main container
 			default:
-				if grate.Debug {
+				if !val.token(tok) && grate.Debug {
 					log.Println("  Unhandled SST xml tag", v.Name.Local, v.Attr)
 				}
 			}
 		case xml.EndElement:
 			if v.Name.Local == "si" {
-				d.strings = append(d.strings, val)
+				if max := d.opts.Limits.MaxStrings; max > 0 && len(d.strings) >= max {
+					return grate.ErrLimitExceeded{Limit: "MaxStrings", Max: int64(max)}
+				}
+				d.strings = append(d.strings, val.String(d.opts.Phonetic))
 				continue
 			}
+			val.token(tok)
+		case xml.CharData:
+			val.token(tok)
 		default:
 			if grate.Debug {
 				log.Printf("    Unhandled SST xml token %T %+v", tok, tok)
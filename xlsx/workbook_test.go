@@ -0,0 +1,53 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pbnjay/grate"
+)
+
+func TestDate1904(t *testing.T) {
+	for _, tc := range []struct {
+		pr     string
+		is1904 bool
+		date   time.Time
+	}{
+		{`<workbookPr date1904="1"/>`, true, time.Date(1908, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{`<workbookPr defaultThemeVersion="124226"/>`, false, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		d := &Document{rels: make(map[string]map[string]string)}
+		dec := xml.NewDecoder(strings.NewReader(`<workbook>` + tc.pr + `<sheets/></workbook>`))
+		if err := d.parseWorkbook(dec); err != nil {
+			t.Fatal(err)
+		}
+		if d.Metadata().Date1904 != tc.is1904 {
+			t.Errorf("%s: expected Date1904=%v", tc.pr, tc.is1904)
+		}
+		if dt := d.fmt.ConvertToDate(1462); !dt.Equal(tc.date) {
+			t.Errorf("%s: expected %s, got %s", tc.pr, tc.date, dt)
+		}
+	}
+}
+
+func TestUnknownLocale(t *testing.T) {
+	wb, err := Open("../testdata/basic.xlsx", grate.WithLocale("xx-XX"))
+	if !errors.Is(err, grate.ErrUnknownLocale) {
+		t.Errorf("expected ErrUnknownLocale, got %v", err)
+	}
+	if wb != nil {
+		wb.Close()
+	}
+
+	wb, err = Open("../testdata/basic.xlsx", grate.WithLocale("de_DE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wb.Close()
+	if n := wb.(*Document).Metadata().Locale; n != "de-DE" {
+		t.Errorf("expected de-DE, got %s", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -28,12 +29,22 @@ type Document struct {
 	sheets  []*Sheet
 	strings []string
 	xfs     []uint16
+	styles  []*commonxl.Style
+	dxfs    []*commonxl.Style
+	theme   []uint32
+	colors  *commonxl.ColorTable
 	fmt     commonxl.Formatter
+	opts    *grate.Options
+
+	pivotCaches []pivotCacheRef
+	date1904    bool
 }
 
 func (d *Document) Close() error {
 	d.xfs = d.xfs[:0]
 	d.xfs = nil
+	d.styles = nil
+	d.dxfs = nil
 	d.strings = d.strings[:0]
 	d.strings = nil
 	d.sheets = d.sheets[:0]
@@ -41,7 +52,7 @@ func (d *Document) Close() error {
 	return d.f.Close()
 }
 
-func Open(filename string) (grate.Source, error) {
+func Open(filename string, opts ...grate.Option) (grate.Source, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -58,97 +69,150 @@ func Open(filename string) (grate.Source, error) {
 		filename: filename,
 		f:        f,
 		r:        z,
+		opts:     grate.NewOptions(opts...),
+	}
+	if err = d.load(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// load parses the workbook structure, styles and shared strings.
+func (d *Document) load() (err error) {
+	var part string
+	defer grate.Recover(&err, "xlsx", func() string { return part })
+
+	d.fmt.SetOverrides(d.opts)
+	if d.opts.Locale != "" {
+		loc, ok := commonxl.LookupLocale(d.opts.Locale)
+		if !ok {
+			return grate.WrapErr(fmt.Errorf("xlsx: unknown locale %q", d.opts.Locale), grate.ErrUnknownLocale)
+		}
+		d.fmt.SetLocale(loc)
 	}
 
 	d.rels = make(map[string]map[string]string, 4)
 
 	// parse the primary relationships
-	dec, c, err := d.openXML("_rels/.rels")
+	part = "_rels/.rels"
+	dec, c, err := d.openXML(part)
 	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+		return grate.WrapErr(err, grate.ErrNotInFormat)
 	}
 	err = d.parseRels(dec, "")
 	c.Close()
 	if err != nil {
-		return nil, grate.WrapErr(err, grate.ErrNotInFormat)
+		return grate.WrapErr(err, grate.ErrNotInFormat)
 	}
 	if d.primaryDoc == "" {
-		return nil, errors.New("xlsx: invalid document")
+		return errors.New("xlsx: invalid document")
 	}
 
 	// parse the secondary relationships to primary doc
 	base := filepath.Base(d.primaryDoc)
 	sub := strings.TrimSuffix(d.primaryDoc, base)
 	relfn := filepath.Join(sub, "_rels", base+".rels")
-	dec, c, err = d.openXML(relfn)
+	part = relfn
+	dec, c, err = d.openXML(part)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	err = d.parseRels(dec, sub)
 	c.Close()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// parse the workbook structure
-	dec, c, err = d.openXML(d.primaryDoc)
+	part = d.primaryDoc
+	dec, c, err = d.openXML(part)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	err = d.parseWorkbook(dec)
 	c.Close()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	thn := d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"]
+	for _, th := range thn {
+		// parse the theme colors (referenced by styles)
+		part = th
+		dec, c, err = d.openXML(part)
+		if err != nil {
+			return err
+		}
+		err = d.parseTheme(dec)
+		c.Close()
+		if err != nil {
+			return err
+		}
 	}
 
 	styn := d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"]
 	for _, sst := range styn {
 		// parse the shared string table
-		dec, c, err = d.openXML(sst)
+		part = sst
+		dec, c, err = d.openXML(part)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		err = d.parseStyles(dec)
 		c.Close()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	ssn := d.rels["http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"]
 	for _, sst := range ssn {
 		// parse the shared string table
-		dec, c, err = d.openXML(sst)
+		part = sst
+		dec, c, err = d.openXML(part)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		err = d.parseSharedStrings(dec)
 		c.Close()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	return d, nil
+	return nil
 }
 
 func (d *Document) openXML(name string) (*xml.Decoder, io.Closer, error) {
 	if grate.Debug {
 		log.Println("    openXML", name)
 	}
+	max := d.opts.Limits.MaxPartBytes
 	for _, zf := range d.r.File {
 		if zf.Name == name {
+			if max > 0 && zf.UncompressedSize64 > uint64(max) {
+				return nil, nil, grate.ErrLimitExceeded{Limit: "MaxPartBytes", Max: max}
+			}
 			zfr, err := zf.Open()
 			if err != nil {
 				return nil, nil, err
 			}
-			dec := xml.NewDecoder(zfr)
+			// the declared size may be wrong, so limit the actual reads too
+			dec := xml.NewDecoder(grate.LimitReader(zfr, "MaxPartBytes", max))
 			return dec, zfr, nil
 		}
 	}
 	return nil, nil, io.EOF
 }
 
+// Metadata returns the properties of the workbook.
+func (d *Document) Metadata() grate.Metadata {
+	return grate.Metadata{
+		Date1904: d.date1904,
+		Locale:   d.fmt.Locale().Name,
+	}
+}
+
 func (d *Document) List() ([]string, error) {
 	res := make([]string, 0, len(d.sheets))
 	for _, s := range d.sheets {